@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/pkosiec/terminer/internal/recipecmd"
+	"github.com/spf13/cobra"
+)
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply [profile path]",
+	Short: "Converges the machine to a profile with a list of recipes",
+	Long: `Apply command installs recipes listed in a profile, which aren't installed yet,
+and upgrades installed recipes, which definitions or parameters have changed.
+With the prune flag, it also reverts installed recipes, which aren't listed in the profile.`,
+	Example: `	terminer apply ./profile.yaml
+	terminer apply ./profile.yaml --prune
+	terminer apply ./profile.yaml --dry-run
+`,
+	Args:                  recipecmd.ValidateApplyArgs,
+	RunE:                  recipecmd.Apply,
+	DisableFlagsInUseLine: true,
+}
+
+func init() {
+	recipecmd.SupportApplyFlags(applyCmd)
+	rootCmd.AddCommand(applyCmd)
+}
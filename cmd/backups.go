@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"github.com/pkosiec/terminer/internal/backupscmd"
+	"github.com/spf13/cobra"
+)
+
+// backupsCmd represents the backups command
+var backupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "Manages files backed up before recipe steps",
+	Long: `Backups command lists and restores files, which were backed up before executing recipe steps.
+Files are restored automatically on rollback. Use this command to recover them manually.`,
+}
+
+// backupsListCmd represents the backups list command
+var backupsListCmd = &cobra.Command{
+	Use:   "list [recipe name]",
+	Short: "Lists backed up files",
+	Example: `	terminer backups list
+	terminer backups list zsh-starter
+`,
+	Args: backupscmd.ValidateListArgs,
+	RunE: backupscmd.List,
+}
+
+// backupsRestoreCmd represents the backups restore command
+var backupsRestoreCmd = &cobra.Command{
+	Use:   "restore <recipe name> [file paths]",
+	Short: "Restores backed up files",
+	Long: `Restore command restores original files from the latest or a given backup run of a recipe.
+Without file paths, it restores all files of the run.`,
+	Example: `	terminer backups restore zsh-starter
+	terminer backups restore zsh-starter ~/.zshrc
+	terminer backups restore zsh-starter --run 20210701T100000Z
+`,
+	Args: backupscmd.ValidateRestoreArgs,
+	RunE: backupscmd.Restore,
+}
+
+func init() {
+	backupscmd.SupportRestoreFlags(backupsRestoreCmd)
+	backupsCmd.AddCommand(backupsListCmd, backupsRestoreCmd)
+	rootCmd.AddCommand(backupsCmd)
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/pkosiec/terminer/internal/changescmd"
+	"github.com/spf13/cobra"
+)
+
+// changesCmd represents the changes command
+var changesCmd = &cobra.Command{
+	Use:   "changes <recipe name>",
+	Short: "Shows files changed by steps of an installed recipe",
+	Long: `Changes command shows files created, modified and deleted by every step of an installed recipe.
+Changes are recorded only if the recipe was installed with the --track-changes flag.`,
+	Example: `	terminer changes zsh-starter
+	terminer changes zsh-starter --suggest-rollback
+`,
+	Args: changescmd.ValidateArgs,
+	RunE: changescmd.Run,
+}
+
+func init() {
+	changescmd.SupportFlags(changesCmd)
+	rootCmd.AddCommand(changesCmd)
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/pkosiec/terminer/internal/freezecmd"
+	"github.com/spf13/cobra"
+)
+
+// freezeCmd represents the freeze command
+var freezeCmd = &cobra.Command{
+	Use:   "freeze",
+	Short: "Exports installed recipes as a profile",
+	Long: `Freeze command prints a profile with sources, checksums and parameters of all installed recipes.
+Apply the profile on another machine to reproduce the same setup.`,
+	Example: `	terminer freeze
+	terminer freeze --export ./profile.yaml
+`,
+	Args: freezecmd.ValidateArgs,
+	RunE: freezecmd.Run,
+}
+
+func init() {
+	freezecmd.SupportFlags(freezeCmd)
+	rootCmd.AddCommand(freezeCmd)
+}
@@ -8,16 +8,18 @@ import (
 
 // installCmd represents the install command
 var installCmd = &cobra.Command{
-	Use:   "install [recipe name]",
+	Use:   "install [recipe names]",
 	Short: "Installs a recipe from official repository, given path or URL",
 	Long: `Install command installs a recipe from the official recipe repository.
-You can use additional flags to install a recipe from a local or remote file.`,
+You can use additional flags to install a recipe from a local or remote file.
+Multiple recipes are installed after recipes they depend on.`,
 	Example:
 `	terminer install zsh-starter
 	terminer install -f ./recipe.yaml
 	terminer install --file /Users/sample-user/recipe.yml
 	terminer install -u https://example.com/recipe.yaml
 	terminer install --url http://foo.bar/recipe.yml
+	terminer install homebrew zsh-starter -f ./recipe.yaml
 `,
 	Args:                  recipecmd.ValidateArgs,
 	RunE:                  recipecmd.Run(shared.OperationInstall),
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/pkosiec/terminer/internal/logscmd"
+	"github.com/spf13/cobra"
+)
+
+// logsCmd represents the logs command
+var logsCmd = &cobra.Command{
+	Use:   "logs [recipe name]",
+	Short: "Shows logs of recipe installations and rollbacks",
+	Long: `Logs command shows a complete log of the latest or a given run of a recipe.
+Without a recipe name, it lists all runs with available logs.`,
+	Example: `	terminer logs
+	terminer logs zsh-starter
+	terminer logs zsh-starter --list
+	terminer logs zsh-starter --run 20210701T100000Z
+	terminer logs zsh-starter --export ./install.log
+`,
+	Args: logscmd.ValidateArgs,
+	RunE: logscmd.Run,
+}
+
+func init() {
+	logscmd.SupportFlags(logsCmd)
+	rootCmd.AddCommand(logsCmd)
+}
@@ -8,16 +8,18 @@ import (
 
 // rollbackCmd represents the rollback command
 var rollbackCmd = &cobra.Command{
-	Use:   "rollback [recipe name]",
+	Use:   "rollback [recipe names]",
 	Short: "Rollbacks a recipe from official repository, given path or URL",
 	Long: `Rollback command uninstalls a recipe from the official recipe repository.
-You can use additional flags to rollback a recipe from a local or remote file.`,
+You can use additional flags to rollback a recipe from a local or remote file.
+Multiple recipes are reverted in reverse order of their dependencies.`,
 Example:
 `	terminer rollback zsh-starter
 	terminer rollback -f ./recipe.yaml
 	terminer rollback --file /Users/sample-user/recipe.yml
 	terminer rollback -u https://example.com/recipe.yaml
 	terminer rollback --url http://foo.bar/recipe.yml
+	terminer rollback homebrew zsh-starter -f ./recipe.yaml
 `,
 	Args:                  recipecmd.ValidateArgs,
 	RunE:                  recipecmd.Run(shared.OperationRollback),
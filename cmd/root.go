@@ -2,10 +2,19 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/pkosiec/terminer/internal/printer"
+	"github.com/pkosiec/terminer/internal/recipecmd"
+	"github.com/pkosiec/terminer/pkg/elevation"
+	"github.com/pkosiec/terminer/pkg/shell"
 	"github.com/spf13/cobra"
 	"os"
 )
 
+// colorMode is a variable which stores a color policy given by user
+var colorMode = printer.ColorAuto
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "terminer",
@@ -14,13 +23,50 @@ var rootCmd = &cobra.Command{
 For example, install Fish or Zsh shell packed with useful plugins and
 sleek prompts. Use one of starter recipes or make yours.
 `,
+	SilenceErrors:     true,
+	PersistentPreRunE: setupOutput,
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", printer.ColorAuto, "Colorize output. One of: auto, always, never")
+	rootCmd.PersistentFlags().BoolVarP(&recipecmd.Quiet, "quiet", "q", false, "Print command output only for failed steps")
+	rootCmd.PersistentFlags().BoolVarP(&recipecmd.Verbose, "verbose", "v", false, "Print full command lines of executed processes, including shell and elevation wrapper")
+	rootCmd.PersistentFlags().StringVar(&recipecmd.Elevation, "elevation", string(elevation.MethodAuto), "Method of running commands as root or another user. One of: auto, root, sudo, doas, pkexec, su")
+	rootCmd.PersistentFlags().IntVar(&recipecmd.MaxOutputLines, "max-output-lines", shell.DefaultOutputLimits.Lines, "Maximum number of printed and logged lines of output of a single command. First and last lines are kept. 0 disables the limit")
+	rootCmd.PersistentFlags().IntVar(&recipecmd.MaxOutputBytes, "max-output-bytes", shell.DefaultOutputLimits.Bytes, "Maximum size in bytes of printed and logged output of a single command. First and last lines are kept. 0 disables the limit")
+	rootCmd.PersistentFlags().StringVar(&recipecmd.AsUser, "as-user", "", "User, who runs commands, which define neither root nor user. By default, the user saved in the recipe state is used for rollback")
+}
+
+func setupOutput(_ *cobra.Command, _ []string) error {
+	if recipecmd.Quiet && recipecmd.Verbose {
+		return exitcode.New(exitcode.Validation, errors.New("Both quiet and verbose flags defined. Use only one of them"))
+	}
+
+	err := printer.SetColorMode(colorMode)
+	if err != nil {
+		return exitcode.New(exitcode.Validation, err)
+	}
+
+	if recipecmd.MaxOutputLines < 0 || recipecmd.MaxOutputBytes < 0 {
+		return exitcode.New(exitcode.Validation, errors.New("Output limits can't be negative"))
+	}
+
+	_, err = elevation.ParseMethod(recipecmd.Elevation)
+	if err != nil {
+		return exitcode.New(exitcode.Validation, err)
+	}
+
+	return nil
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		if !exitcode.IsSilent(err) {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		}
+
+		os.Exit(exitcode.FromError(err))
 	}
 }
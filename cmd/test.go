@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/pkosiec/terminer/internal/recipecmd"
+	"github.com/spf13/cobra"
+)
+
+// testCmd represents the test command
+var testCmd = &cobra.Command{
+	Use:   "test [recipe names]",
+	Short: "Verifies that rollback of recipes reverts all changes made by their installation",
+	Long: `Test command installs recipes with a throwaway home directory and state, and reverts them.
+It snapshots files before the installation, after it and after the rollback,
+and fails if the rollback left any changes.`,
+	Example: `	terminer test -f ./recipe.yaml
+	terminer test -f ./recipe.yaml --junit ./report.xml
+	terminer test -f ./recipe.yaml --unshare
+	terminer test zsh-starter --track-root /usr/local/bin
+`,
+	Args: recipecmd.ValidateTestArgs,
+	RunE: recipecmd.Test,
+}
+
+func init() {
+	recipecmd.SupportTestFlags(testCmd)
+	rootCmd.AddCommand(testCmd)
+}
@@ -4,8 +4,10 @@ go 1.16
 
 require (
 	github.com/fatih/color v1.12.0
+	github.com/mattn/go-isatty v0.0.12
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/cobra v1.2.1
 	github.com/stretchr/testify v1.7.0
+	golang.org/x/sys v0.0.0-20210510120138-977fb7262007
 	sigs.k8s.io/yaml v1.2.0
 )
@@ -0,0 +1,11 @@
+package backupscmd
+
+import "github.com/spf13/cobra"
+
+// RunID is a variable which stores an ID of a backup run to restore
+var RunID string
+
+// SupportRestoreFlags sets required flags for the backups restore command
+func SupportRestoreFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&RunID, "run", "", "ID of the backup run to restore. By default, the latest run is restored")
+}
@@ -0,0 +1,141 @@
+package backupscmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/pkosiec/terminer/pkg/backup"
+	"github.com/pkosiec/terminer/pkg/path"
+	"github.com/pkosiec/terminer/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+// ValidateListArgs validates arguments for the backups list command
+func ValidateListArgs(_ *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return exitcode.New(exitcode.Validation, errors.New("This command accepts at most one recipe name"))
+	}
+
+	return nil
+}
+
+// ValidateRestoreArgs validates arguments for the backups restore command
+func ValidateRestoreArgs(_ *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return exitcode.New(exitcode.Validation, errors.New("Recipe name is required"))
+	}
+
+	return nil
+}
+
+// List lists backed up files. Without a recipe name, it lists backups of all recipes.
+func List(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	store, err := newStore()
+	if err != nil {
+		return err
+	}
+
+	var recipe string
+	if len(args) > 0 {
+		recipe = args[0]
+	}
+
+	runs, err := store.List(recipe)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if len(runs) == 0 {
+		_, _ = fmt.Fprintln(out, "No backups found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "RECIPE\tRUN\tFILE")
+	for _, run := range runs {
+		for _, file := range run.Files {
+			name := file.Path
+			if file.File == "" {
+				name += " (didn't exist)"
+			}
+
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", run.Recipe, run.ID, name)
+		}
+	}
+
+	return w.Flush()
+}
+
+// Restore restores files from the latest or a given backup run of a recipe.
+// Without file paths, it restores all files of the run.
+func Restore(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	store, err := newStore()
+	if err != nil {
+		return err
+	}
+
+	run, err := store.Get(args[0], RunID)
+	if err != nil {
+		return err
+	}
+
+	paths, err := expandPaths(args[1:])
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	restored := map[string]bool{}
+	for _, file := range run.Files {
+		// The first backup of a file contains its original content
+		if restored[file.Path] || (len(paths) > 0 && !paths[file.Path]) {
+			continue
+		}
+
+		err := backup.Restore(file)
+		if err != nil {
+			return err
+		}
+
+		restored[file.Path] = true
+		_, _ = fmt.Fprintf(out, "Restored %s\n", file.Path)
+	}
+
+	for p := range paths {
+		if !restored[p] {
+			return fmt.Errorf("File %s isn't backed up in run `%s`", p, run.ID)
+		}
+	}
+
+	return nil
+}
+
+func newStore() (*backup.Store, error) {
+	stateDir, err := state.Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	return backup.NewStore(stateDir), nil
+}
+
+func expandPaths(paths []string) (map[string]bool, error) {
+	expanded := map[string]bool{}
+	for _, p := range paths {
+		e, err := path.ExpandHome(p)
+		if err != nil {
+			return nil, err
+		}
+
+		expanded[e] = true
+	}
+
+	return expanded, nil
+}
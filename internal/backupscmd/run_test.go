@@ -0,0 +1,128 @@
+package backupscmd_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkosiec/terminer/internal/backupscmd"
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/pkosiec/terminer/internal/testutil"
+	"github.com/pkosiec/terminer/pkg/backup"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	stateDir := testutil.SetStateDir(t)
+
+	dir := t.TempDir()
+	rcPath := filepath.Join(dir, ".zshrc")
+	newPath := filepath.Join(dir, ".zprofile")
+	err := ioutil.WriteFile(rcPath, []byte("original\n"), 0600)
+	require.NoError(t, err)
+
+	run, err := backup.NewStore(stateDir).Create("Zsh Starter")
+	require.NoError(t, err)
+	_, err = run.Snapshot(0, 0, rcPath)
+	require.NoError(t, err)
+	_, err = run.Snapshot(0, 1, newPath)
+	require.NoError(t, err)
+
+	t.Run("List", func(t *testing.T) {
+		var buf bytes.Buffer
+		cmd := &cobra.Command{}
+		cmd.SetOut(&buf)
+
+		err := backupscmd.List(cmd, nil)
+		require.NoError(t, err)
+
+		out := buf.String()
+		assert.Contains(t, out, "zsh-starter  "+run.ID+"  "+rcPath+"\n")
+		assert.Contains(t, out, newPath+" (didn't exist)\n")
+	})
+
+	t.Run("Restore given file", func(t *testing.T) {
+		writeFiles(t, rcPath, newPath)
+
+		out := runRestore(t, []string{"zsh-starter", newPath})
+		assert.Equal(t, "Restored "+newPath+"\n", out)
+
+		_, err := os.Stat(newPath)
+		assert.True(t, os.IsNotExist(err))
+		assertContent(t, rcPath, "modified\n")
+	})
+
+	t.Run("Restore all files", func(t *testing.T) {
+		writeFiles(t, rcPath, newPath)
+
+		backupscmd.RunID = run.ID
+		defer func() {
+			backupscmd.RunID = ""
+		}()
+
+		runRestore(t, []string{"Zsh Starter"})
+
+		_, err := os.Stat(newPath)
+		assert.True(t, os.IsNotExist(err))
+		assertContent(t, rcPath, "original\n")
+	})
+
+	t.Run("File not backed up", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		err := backupscmd.Restore(cmd, []string{"zsh-starter", filepath.Join(dir, ".bashrc")})
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "isn't backed up")
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		err := backupscmd.Restore(cmd, []string{"fish-starter"})
+
+		require.Error(t, err)
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("List with too many parameters", func(t *testing.T) {
+		err := backupscmd.ValidateListArgs(nil, []string{"test", "test2"})
+
+		require.Error(t, err)
+		assert.Equal(t, exitcode.Validation, exitcode.FromError(err))
+	})
+
+	t.Run("Restore without recipe", func(t *testing.T) {
+		err := backupscmd.ValidateRestoreArgs(nil, nil)
+
+		require.Error(t, err)
+		assert.Equal(t, exitcode.Validation, exitcode.FromError(err))
+	})
+}
+
+func runRestore(t *testing.T, args []string) string {
+	var buf bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&buf)
+
+	err := backupscmd.Restore(cmd, args)
+	require.NoError(t, err)
+
+	return buf.String()
+}
+
+func writeFiles(t *testing.T, paths ...string) {
+	for _, path := range paths {
+		err := ioutil.WriteFile(path, []byte("modified\n"), 0600)
+		require.NoError(t, err)
+	}
+}
+
+func assertContent(t *testing.T, path, expected string) {
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(content))
+}
@@ -0,0 +1,11 @@
+package changescmd
+
+import "github.com/spf13/cobra"
+
+// SuggestRollback is a variable which makes the command print suggested rollback commands for steps without rollback
+var SuggestRollback bool
+
+// SupportFlags sets required flags for the changes command
+func SupportFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&SuggestRollback, "suggest-rollback", false, "Print suggested rollback commands for steps without rollback")
+}
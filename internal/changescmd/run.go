@@ -0,0 +1,147 @@
+package changescmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/pkosiec/terminer/pkg/fstrack"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/state"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// ValidateArgs validates arguments for the changes command
+func ValidateArgs(_ *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return exitcode.New(exitcode.Validation, errors.New("This command requires a single recipe name"))
+	}
+
+	return nil
+}
+
+// Run prints files changed by steps of an installed recipe
+func Run(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	stateDir, err := state.Dir()
+	if err != nil {
+		return err
+	}
+
+	record, err := state.NewFileStore(stateDir).Get(args[0])
+	if err != nil {
+		return err
+	}
+
+	if record == nil {
+		return fmt.Errorf("Recipe `%s` isn't installed", args[0])
+	}
+
+	out := cmd.OutOrStdout()
+	if len(record.Changes) == 0 {
+		_, _ = fmt.Fprintln(out, "No changes recorded. Install the recipe with --track-changes to record them")
+		return nil
+	}
+
+	// The definition is used only for step names and rollback commands
+	var r recipe.Recipe
+	if len(record.Definition) > 0 {
+		_ = json.Unmarshal(record.Definition, &r)
+	}
+
+	err = printChanges(out, r, record.Changes)
+	if err != nil || !SuggestRollback {
+		return err
+	}
+
+	return printSuggestions(out, r, record.Changes)
+}
+
+func printChanges(out io.Writer, r recipe.Recipe, changes []state.FileChange) error {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STAGE\tSTEP\tCHANGE\tPATH")
+	for _, change := range changes {
+		stage, step := stepNames(r, change.Stage, change.Step)
+
+		path := change.Path
+		if change.Dir {
+			path += "/"
+		}
+
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", stage, step, change.Type, path)
+	}
+
+	return w.Flush()
+}
+
+// printSuggestions prints suggested rollback of every step, which changed files and doesn't have its own rollback
+func printSuggestions(out io.Writer, r recipe.Recipe, changes []state.FileChange) error {
+	var stepChanges []state.FileChange
+	for i, change := range changes {
+		stepChanges = append(stepChanges, change)
+
+		isLast := i == len(changes)-1 || changes[i+1].Stage != change.Stage || changes[i+1].Step != change.Step
+		if !isLast {
+			continue
+		}
+
+		err := printSuggestion(out, r, stepChanges)
+		if err != nil {
+			return err
+		}
+
+		stepChanges = nil
+	}
+
+	return nil
+}
+
+func printSuggestion(out io.Writer, r recipe.Recipe, changes []state.FileChange) error {
+	stageIndex, stepIndex := changes[0].Stage, changes[0].Step
+	if step, ok := findStep(r, stageIndex, stepIndex); ok && !step.Rollback.IsEmpty() {
+		return nil
+	}
+
+	commands := fstrack.SuggestRollback(changes)
+	if len(commands) == 0 {
+		return nil
+	}
+
+	bytes, err := yaml.Marshal(map[string]interface{}{
+		"rollback": map[string][]string{"run": commands},
+	})
+	if err != nil {
+		return errors.Wrap(err, "while encoding suggested rollback")
+	}
+
+	stage, step := stepNames(r, stageIndex, stepIndex)
+	_, _ = fmt.Fprintf(out, "\nSuggested rollback of step '%s' in stage '%s':\n%s", step, stage, bytes)
+	return nil
+}
+
+func stepNames(r recipe.Recipe, stageIndex, stepIndex int) (string, string) {
+	stage := fmt.Sprintf("Stage %d", stageIndex+1)
+	if stageIndex < len(r.Stages) && r.Stages[stageIndex].Metadata.Name != "" {
+		stage = r.Stages[stageIndex].Metadata.Name
+	}
+
+	step := fmt.Sprintf("Step %d", stepIndex+1)
+	if s, ok := findStep(r, stageIndex, stepIndex); ok && s.Metadata.Name != "" {
+		step = s.Metadata.Name
+	}
+
+	return stage, step
+}
+
+func findStep(r recipe.Recipe, stageIndex, stepIndex int) (recipe.Step, bool) {
+	if stageIndex >= len(r.Stages) || stepIndex >= len(r.Stages[stageIndex].Steps) {
+		return recipe.Step{}, false
+	}
+
+	return r.Stages[stageIndex].Steps[stepIndex], true
+}
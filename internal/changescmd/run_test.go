@@ -0,0 +1,108 @@
+package changescmd_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/pkosiec/terminer/internal/changescmd"
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/pkosiec/terminer/internal/testutil"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shell"
+	"github.com/pkosiec/terminer/pkg/state"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	stateDir := testutil.SetStateDir(t)
+
+	definition, err := json.Marshal(recipe.Recipe{
+		Stages: []recipe.Stage{
+			{
+				Metadata: recipe.UnitMetadata{Name: "Zsh"},
+				Steps: []recipe.Step{
+					{
+						Metadata: recipe.UnitMetadata{Name: "Install plugins"},
+						Execute:  shell.Command{Run: []string{"git clone https://github.com/zsh-users/zsh-autosuggestions ~/.zsh"}},
+					},
+					{
+						Metadata: recipe.UnitMetadata{Name: "Configure"},
+						Execute:  shell.Command{Run: []string{"echo 'source ~/.zsh/zsh-autosuggestions.zsh' >> ~/.zshrc"}},
+						Rollback: shell.Command{Run: []string{"sed -i '/zsh-autosuggestions/d' ~/.zshrc"}},
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	store := state.NewFileStore(stateDir)
+	err = store.Save(state.Record{
+		Recipe:     "zsh-plugins",
+		Status:     state.StatusInstalled,
+		Definition: definition,
+		Changes: []state.FileChange{
+			{Stage: 0, Step: 0, Path: "/home/user/.zsh", Type: state.ChangeCreated, Dir: true},
+			{Stage: 0, Step: 0, Path: "/home/user/.zsh/zsh-autosuggestions.zsh", Type: state.ChangeCreated},
+			{Stage: 0, Step: 1, Path: "/home/user/.zshrc", Type: state.ChangeModified},
+		},
+	})
+	require.NoError(t, err)
+	err = store.Save(state.Record{Recipe: "homebrew", Status: state.StatusInstalled})
+	require.NoError(t, err)
+
+	t.Run("Changes", func(t *testing.T) {
+		out := runCmd(t, []string{"zsh-plugins"})
+
+		assert.Contains(t, out, "Zsh    Install plugins  created   /home/user/.zsh/\n")
+		assert.Contains(t, out, "Zsh    Configure        modified  /home/user/.zshrc\n")
+		assert.NotContains(t, out, "Suggested rollback")
+	})
+
+	t.Run("Suggested rollback", func(t *testing.T) {
+		changescmd.SuggestRollback = true
+		defer func() {
+			changescmd.SuggestRollback = false
+		}()
+
+		out := runCmd(t, []string{"zsh-plugins"})
+
+		assert.Contains(t, out, "Suggested rollback of step 'Install plugins' in stage 'Zsh':\nrollback:\n  run:\n  - rm -f '/home/user/.zsh/zsh-autosuggestions.zsh'\n  - rmdir '/home/user/.zsh'\n")
+		assert.NotContains(t, out, "step 'Configure'")
+	})
+
+	t.Run("No changes", func(t *testing.T) {
+		out := runCmd(t, []string{"homebrew"})
+
+		assert.Contains(t, out, "No changes recorded")
+	})
+
+	t.Run("Not installed", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		err := changescmd.Run(cmd, []string{"fish-starter"})
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "isn't installed")
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	err := changescmd.ValidateArgs(nil, nil)
+
+	require.Error(t, err)
+	assert.Equal(t, exitcode.Validation, exitcode.FromError(err))
+}
+
+func runCmd(t *testing.T, args []string) string {
+	var buf bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&buf)
+
+	err := changescmd.Run(cmd, args)
+	require.NoError(t, err)
+
+	return buf.String()
+}
@@ -0,0 +1,90 @@
+package exitcode
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	// Success means that the command finished without errors
+	Success = 0
+
+	// Generic is used for errors, which don't have a dedicated exit code, such as invalid command usage
+	Generic = 1
+
+	// Validation means that the recipe or command arguments are invalid
+	Validation = 2
+
+	// Load means that the recipe couldn't be loaded from the official repository, path or URL
+	Load = 3
+
+	// StepFailure means that a recipe step failed during installation
+	StepFailure = 4
+
+	// RollbackFailure means that one or more recipe steps failed during rollback
+	RollbackFailure = 5
+
+	// ResidualChanges means that a tested recipe left changes after rollback
+	ResidualChanges = 6
+
+	// NotConfirmed means that user didn't confirm commands, which run with elevated privileges
+	NotConfirmed = 7
+
+	// Interrupted means that the operation was interrupted with a signal
+	Interrupted = 130
+)
+
+// Error is an error, which results in a given exit code.
+// Silent errors are already reported to the user, so they shouldn't be printed again.
+type Error struct {
+	Code   int
+	Err    error
+	Silent bool
+}
+
+// New creates a new Error with given exit code
+func New(code int, err error) *Error {
+	return &Error{Code: code, Err: err}
+}
+
+// NewSilent creates a new Error with given exit code, which has been already reported to the user
+func NewSilent(code int, err error) *Error {
+	return &Error{Code: code, Err: err, Silent: true}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the underlying error
+func (e *Error) Cause() error {
+	return e.Err
+}
+
+// FromError returns exit code for a given error
+func FromError(err error) int {
+	if err == nil {
+		return Success
+	}
+
+	var exitErr *Error
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	return Generic
+}
+
+// IsSilent checks if a given error has been already reported to the user
+func IsSilent(err error) bool {
+	var exitErr *Error
+	if errors.As(err, &exitErr) {
+		return exitErr.Silent
+	}
+
+	return false
+}
@@ -0,0 +1,56 @@
+package exitcode_test
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromError(t *testing.T) {
+	testErr := errors.New("Test")
+
+	testCases := []struct {
+		Name     string
+		Err      error
+		Expected int
+	}{
+		{
+			Name:     "No error",
+			Err:      nil,
+			Expected: exitcode.Success,
+		},
+		{
+			Name:     "Generic error",
+			Err:      testErr,
+			Expected: exitcode.Generic,
+		},
+		{
+			Name:     "Exit code error",
+			Err:      exitcode.New(exitcode.Load, testErr),
+			Expected: exitcode.Load,
+		},
+		{
+			Name:     "Wrapped exit code error",
+			Err:      errors.Wrap(exitcode.NewSilent(exitcode.StepFailure, testErr), "while installing"),
+			Expected: exitcode.StepFailure,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.Name, func(t *testing.T) {
+			assert.Equal(t, tC.Expected, exitcode.FromError(tC.Err))
+		})
+	}
+}
+
+func TestIsSilent(t *testing.T) {
+	testErr := errors.New("Test")
+
+	assert.False(t, exitcode.IsSilent(nil))
+	assert.False(t, exitcode.IsSilent(testErr))
+	assert.False(t, exitcode.IsSilent(exitcode.New(exitcode.Validation, testErr)))
+	assert.True(t, exitcode.IsSilent(exitcode.NewSilent(exitcode.RollbackFailure, testErr)))
+	assert.Equal(t, "Test", exitcode.NewSilent(exitcode.RollbackFailure, testErr).Error())
+}
@@ -0,0 +1,11 @@
+package freezecmd
+
+import "github.com/spf13/cobra"
+
+// ExportPath is a variable which stores a path, to which the profile is written instead of printing it
+var ExportPath string
+
+// SupportFlags sets required flags for the freeze command
+func SupportFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&ExportPath, "export", "", "Write the profile to a given file instead of printing it")
+}
@@ -0,0 +1,77 @@
+package freezecmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/pkosiec/terminer/pkg/profile"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+// ValidateArgs validates arguments for the freeze command
+func ValidateArgs(_ *cobra.Command, args []string) error {
+	if len(args) > 0 {
+		return exitcode.New(exitcode.Validation, errors.New("This command doesn't accept any arguments"))
+	}
+
+	return nil
+}
+
+// Run prints a profile with sources, checksums and parameters of all installed recipes
+func Run(cmd *cobra.Command, _ []string) error {
+	cmd.SilenceUsage = true
+
+	stateDir, err := state.Dir()
+	if err != nil {
+		return err
+	}
+
+	records, err := state.NewFileStore(stateDir).List()
+	if err != nil {
+		return err
+	}
+
+	p, skipped := profile.FromState(records)
+	for _, name := range skipped {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Skipping recipe `%s`, as its source isn't saved in its state. Install it again to include it in the profile.\n", name)
+	}
+
+	if len(p.Recipes) == 0 {
+		return errors.New("No installed recipes found")
+	}
+
+	p.Metadata = profileMetadata()
+
+	bytes, err := p.Marshal()
+	if err != nil {
+		return err
+	}
+
+	if ExportPath == "" {
+		_, err = cmd.OutOrStdout().Write(bytes)
+		return err
+	}
+
+	err = ioutil.WriteFile(ExportPath, bytes, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "while writing profile to %s", ExportPath)
+	}
+
+	return nil
+}
+
+func profileMetadata() recipe.UnitMetadata {
+	m := recipe.UnitMetadata{Name: "Frozen profile", Description: "Recipes installed on the machine"}
+
+	hostname, err := os.Hostname()
+	if err == nil && hostname != "" {
+		m.Description = fmt.Sprintf("Recipes installed on %s", hostname)
+	}
+
+	return m
+}
@@ -0,0 +1,80 @@
+package freezecmd_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/pkosiec/terminer/internal/freezecmd"
+	"github.com/pkosiec/terminer/internal/testutil"
+	"github.com/pkosiec/terminer/pkg/profile"
+	"github.com/pkosiec/terminer/pkg/state"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	stateDir := testutil.SetStateDir(t)
+
+	t.Run("No installed recipes", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		cmd.SetOut(&bytes.Buffer{})
+
+		err := freezecmd.Run(cmd, nil)
+
+		assert.Error(t, err)
+	})
+
+	store := state.NewFileStore(stateDir)
+	err := store.Save(state.Record{
+		Recipe:     "Zsh Starter",
+		Status:     state.StatusInstalled,
+		Source:     &state.Source{Name: "zsh-starter"},
+		Checksum:   "abc",
+		Parameters: map[string]string{"theme": "agnoster"},
+	})
+	require.NoError(t, err)
+	err = store.Save(state.Record{Recipe: "Legacy", Status: state.StatusInstalled})
+	require.NoError(t, err)
+
+	t.Run("Print profile", func(t *testing.T) {
+		var out, errOut bytes.Buffer
+		cmd := &cobra.Command{}
+		cmd.SetOut(&out)
+		cmd.SetErr(&errOut)
+
+		err := freezecmd.Run(cmd, nil)
+
+		require.NoError(t, err)
+		assert.Contains(t, out.String(), "- checksum: abc\n  name: zsh-starter\n  parameters:\n    theme: agnoster\n")
+		assert.Contains(t, errOut.String(), "Skipping recipe `Legacy`")
+	})
+
+	t.Run("Export profile", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "profile.yaml")
+		freezecmd.ExportPath = path
+		defer func() {
+			freezecmd.ExportPath = ""
+		}()
+		cmd := &cobra.Command{}
+		cmd.SetErr(ioutil.Discard)
+
+		err := freezecmd.Run(cmd, nil)
+		require.NoError(t, err)
+
+		p, err := profile.FromPath(path)
+		require.NoError(t, err)
+		require.NoError(t, p.Validate())
+		assert.Equal(t, []profile.Entry{{Name: "zsh-starter", Checksum: "abc", Parameters: map[string]string{"theme": "agnoster"}}}, p.Recipes)
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	err := freezecmd.ValidateArgs(nil, []string{"foo"})
+
+	require.Error(t, err)
+	assert.Equal(t, exitcode.Validation, exitcode.FromError(err))
+}
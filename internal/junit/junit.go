@@ -0,0 +1,86 @@
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TestSuites is a root element of a JUnit XML report
+type TestSuites struct {
+	XMLName xml.Name    `xml:"testsuites"`
+	Suites  []TestSuite `xml:"testsuite"`
+}
+
+// TestSuite is a group of test cases
+type TestSuite struct {
+	Name     string     `xml:"name,attr"`
+	Tests    int        `xml:"tests,attr"`
+	Failures int        `xml:"failures,attr"`
+	Skipped  int        `xml:"skipped,attr"`
+	Time     Seconds    `xml:"time,attr"`
+	Cases    []TestCase `xml:"testcase"`
+}
+
+// TestCase is a single test case. A test case without failure and skip details passed.
+type TestCase struct {
+	Name      string   `xml:"name,attr"`
+	ClassName string   `xml:"classname,attr"`
+	Time      Seconds  `xml:"time,attr"`
+	Failure   *Failure `xml:"failure,omitempty"`
+	Skipped   *Skipped `xml:"skipped,omitempty"`
+}
+
+// Failure describes a failed test case
+type Failure struct {
+	Message string `xml:"message,attr"`
+	Details string `xml:",chardata"`
+}
+
+// Skipped describes a test case, which wasn't run
+type Skipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// Seconds is a time.Duration, which is encoded as a number of seconds
+type Seconds time.Duration
+
+// MarshalXMLAttr encodes duration as a number of seconds
+func (s Seconds) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: fmt.Sprintf("%.3f", time.Duration(s).Seconds())}, nil
+}
+
+// NewSuite creates a test suite with given test cases and counts their results
+func NewSuite(name string, cases []TestCase) TestSuite {
+	suite := TestSuite{Name: name, Tests: len(cases), Cases: cases}
+	for _, c := range cases {
+		suite.Time += c.Time
+		if c.Failure != nil {
+			suite.Failures++
+		}
+		if c.Skipped != nil {
+			suite.Skipped++
+		}
+	}
+
+	return suite
+}
+
+// Write writes a JUnit XML report with given test suites to a file
+func Write(path string, suites ...TestSuite) error {
+	bytes, err := xml.MarshalIndent(TestSuites{Suites: suites}, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "while encoding JUnit report")
+	}
+
+	bytes = append([]byte(xml.Header), append(bytes, '\n')...)
+	err = ioutil.WriteFile(path, bytes, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "while writing JUnit report to %s", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package logscmd
+
+import "github.com/spf13/cobra"
+
+// RunID is a variable which stores an ID of a run to show
+var RunID string
+
+// ExportPath is a variable which stores a path, to which the run log is copied instead of printing it
+var ExportPath string
+
+// List is a variable which makes the command list runs of a recipe instead of printing the log
+var List bool
+
+// SupportFlags sets required flags for the logs command
+func SupportFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&RunID, "run", "", "ID of the run to show. By default, the latest run is shown")
+	cmd.Flags().StringVar(&ExportPath, "export", "", "Copy the run log to a given file instead of printing it")
+	cmd.Flags().BoolVar(&List, "list", false, "List runs of the recipe")
+}
@@ -0,0 +1,110 @@
+package logscmd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/pkosiec/terminer/internal/runlog"
+	"github.com/pkosiec/terminer/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+// ValidateArgs validates arguments for the logs command
+func ValidateArgs(_ *cobra.Command, args []string) error {
+	if len(args) > 1 {
+		return exitcode.New(exitcode.Validation, errors.New("This command accepts at most one recipe name"))
+	}
+
+	if len(args) == 0 && (RunID != "" || ExportPath != "") {
+		return exitcode.New(exitcode.Validation, errors.New("Recipe name is required to show a run log"))
+	}
+
+	return nil
+}
+
+// Run shows run logs. Without a recipe name, it lists runs of all recipes.
+func Run(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	stateDir, err := state.Dir()
+	if err != nil {
+		return err
+	}
+
+	store := runlog.NewStore(stateDir)
+	out := cmd.OutOrStdout()
+
+	if len(args) == 0 || List {
+		var recipe string
+		if len(args) > 0 {
+			recipe = args[0]
+		}
+
+		return listRuns(out, store, recipe)
+	}
+
+	run, err := store.Get(args[0], RunID)
+	if err != nil {
+		return err
+	}
+
+	if ExportPath != "" {
+		return exportRun(run, ExportPath)
+	}
+
+	return printRun(out, run)
+}
+
+func listRuns(out io.Writer, store *runlog.Store, recipe string) error {
+	runs, err := store.List(recipe)
+	if err != nil {
+		return err
+	}
+
+	if len(runs) == 0 {
+		_, _ = fmt.Fprintln(out, "No logs found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "RECIPE\tRUN")
+	for _, run := range runs {
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", run.Recipe, run.ID)
+	}
+
+	return w.Flush()
+}
+
+func printRun(out io.Writer, run runlog.Run) error {
+	file, err := os.Open(run.Path)
+	if err != nil {
+		return errors.Wrapf(err, "while opening log file %s", run.Path)
+	}
+	defer file.Close()
+
+	_, err = io.Copy(out, file)
+	if err != nil {
+		return errors.Wrapf(err, "while reading log file %s", run.Path)
+	}
+
+	return nil
+}
+
+func exportRun(run runlog.Run, path string) error {
+	bytes, err := ioutil.ReadFile(run.Path)
+	if err != nil {
+		return errors.Wrapf(err, "while reading log file %s", run.Path)
+	}
+
+	err = ioutil.WriteFile(path, bytes, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "while exporting log file to %s", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,108 @@
+package logscmd_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/pkosiec/terminer/internal/logscmd"
+	"github.com/pkosiec/terminer/internal/runlog"
+	"github.com/pkosiec/terminer/internal/testutil"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	stateDir := testutil.SetStateDir(t)
+
+	store := runlog.NewStore(stateDir)
+	startedAt := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+	for _, content := range []string{"First run\n", "Second run\n"} {
+		w, _, err := store.Create("Zsh Starter", startedAt)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+	}
+
+	t.Run("List all runs", func(t *testing.T) {
+		out := runCmd(t, nil)
+
+		assert.Contains(t, out, "zsh-starter  20210701T100000Z\n")
+		assert.Contains(t, out, "zsh-starter  20210701T100000Z-2\n")
+	})
+
+	t.Run("Latest run", func(t *testing.T) {
+		out := runCmd(t, []string{"zsh-starter"})
+
+		assert.Equal(t, "Second run\n", out)
+	})
+
+	t.Run("Given run", func(t *testing.T) {
+		logscmd.RunID = "20210701T100000Z"
+		defer func() {
+			logscmd.RunID = ""
+		}()
+
+		out := runCmd(t, []string{"Zsh Starter"})
+
+		assert.Equal(t, "First run\n", out)
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		logscmd.ExportPath = filepath.Join(t.TempDir(), "install.log")
+		defer func() {
+			logscmd.ExportPath = ""
+		}()
+
+		out := runCmd(t, []string{"zsh-starter"})
+		assert.Empty(t, out)
+
+		bytes, err := ioutil.ReadFile(logscmd.ExportPath)
+		require.NoError(t, err)
+		assert.Equal(t, "Second run\n", string(bytes))
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		err := logscmd.Run(cmd, []string{"fish-starter"})
+
+		require.Error(t, err)
+	})
+}
+
+func TestValidateArgs(t *testing.T) {
+	t.Run("Too many parameters", func(t *testing.T) {
+		err := logscmd.ValidateArgs(nil, []string{"test", "test2"})
+
+		require.Error(t, err)
+		assert.Equal(t, exitcode.Validation, exitcode.FromError(err))
+	})
+
+	t.Run("Run without recipe", func(t *testing.T) {
+		logscmd.RunID = "20210701T100000Z"
+		defer func() {
+			logscmd.RunID = ""
+		}()
+
+		err := logscmd.ValidateArgs(nil, nil)
+
+		require.Error(t, err)
+		assert.Equal(t, exitcode.Validation, exitcode.FromError(err))
+	})
+}
+
+func runCmd(t *testing.T, args []string) string {
+	var buf bytes.Buffer
+	cmd := &cobra.Command{}
+	cmd.SetOut(&buf)
+
+	err := logscmd.Run(cmd, args)
+	require.NoError(t, err)
+
+	return buf.String()
+}
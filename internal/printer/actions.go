@@ -0,0 +1,91 @@
+package printer
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// ActionType is a type of action taken for a recipe while applying a profile
+type ActionType string
+
+const (
+	// ActionInstall installs a recipe, which isn't installed
+	ActionInstall ActionType = "install"
+
+	// ActionUpgrade installs a new version of an installed recipe, or installs it with new parameters
+	ActionUpgrade ActionType = "upgrade"
+
+	// ActionRemove reverts an installed recipe, which isn't listed in the profile
+	ActionRemove ActionType = "remove"
+
+	// ActionNone means that the installed recipe is up to date
+	ActionNone ActionType = "none"
+)
+
+// ActionStatus is a status of an action taken for a recipe
+type ActionStatus string
+
+const (
+	// ActionSucceeded means that the action succeeded
+	ActionSucceeded ActionStatus = "succeeded"
+
+	// ActionFailed means that the action failed
+	ActionFailed ActionStatus = "failed"
+
+	// ActionNotStarted means that the action wasn't started, as a previous action failed
+	ActionNotStarted ActionStatus = "notStarted"
+)
+
+// Action is an action taken for a recipe while applying a profile
+type Action struct {
+	Recipe string       `json:"recipe"`
+	Type   ActionType   `json:"type"`
+	Status ActionStatus `json:"status"`
+}
+
+var actionResults = map[ActionType]string{
+	ActionInstall: "installed",
+	ActionUpgrade: "upgraded",
+	ActionRemove:  "removed",
+	ActionNone:    "up to date",
+}
+
+// writeActions prints a table with actions taken for all recipes of a profile
+func writeActions(w io.Writer, actions []Action) {
+	if len(actions) == 0 {
+		return
+	}
+
+	const indentation = "  "
+
+	nameWidth := 0
+	for _, action := range actions {
+		nameWidth = maxInt(nameWidth, utf8.RuneCountInString(action.Recipe))
+	}
+
+	_, _ = color.New(color.Bold).Fprintf(w, "\nProfile:\n")
+	for _, action := range actions {
+		var result string
+		var c *color.Color
+
+		switch action.Status {
+		case ActionFailed:
+			result = fmt.Sprintf("%s failed", action.Type)
+			c = color.New(color.FgRed)
+		case ActionNotStarted:
+			result = fmt.Sprintf("%s not started", action.Type)
+			c = color.New(color.Faint)
+		default:
+			result = actionResults[action.Type]
+			c = color.New(color.FgGreen)
+			if action.Type == ActionNone {
+				c = color.New(color.Faint)
+			}
+		}
+
+		_, _ = fmt.Fprintf(w, "%s%s  %s\n", indentation, pad(action.Recipe, nameWidth), c.Sprint(result))
+	}
+}
@@ -2,21 +2,33 @@
 package automock
 
 import (
+	"github.com/pkosiec/terminer/internal/printer"
 	"github.com/pkosiec/terminer/pkg/shared"
 	"github.com/stretchr/testify/mock"
 )
 import "github.com/pkosiec/terminer/pkg/recipe"
+import "time"
 
 // Printer is an autogenerated mock type for the Printer type
 type Printer struct {
 	mock.Mock
 }
 
+// Actions provides a mock function with given fields: actions
+func (_m *Printer) Actions(actions []printer.Action) {
+	_m.Called(actions)
+}
+
 // Command provides a mock function with given fields: cmd
 func (_m *Printer) Command(cmd string) {
 	_m.Called(cmd)
 }
 
+// CommandFinished provides a mock function with given fields: duration
+func (_m *Printer) CommandFinished(duration time.Duration) {
+	_m.Called(duration)
+}
+
 // ExecError provides a mock function with given fields: output
 func (_m *Printer) ExecError(output string) {
 	_m.Called(output)
@@ -27,14 +39,34 @@ func (_m *Printer) ExecOutput(output string) {
 	_m.Called(output)
 }
 
+// ExecTrace provides a mock function with given fields: trace
+func (_m *Printer) ExecTrace(trace string) {
+	_m.Called(trace)
+}
+
+// Interactive provides a mock function with given fields: active
+func (_m *Printer) Interactive(active bool) {
+	_m.Called(active)
+}
+
 // Recipe provides a mock function with given fields: r
 func (_m *Printer) Recipe(r recipe.UnitMetadata) {
 	_m.Called(r)
 }
 
-// SetContext provides a mock function with given fields: operation, stagesCount
-func (_m *Printer) SetContext(operation shared.Operation, stagesCount int) {
-	_m.Called(operation, stagesCount)
+// RecipeSkipped provides a mock function with given fields: name, reason
+func (_m *Printer) RecipeSkipped(name string, reason string) {
+	_m.Called(name, reason)
+}
+
+// Result provides a mock function with given fields: err
+func (_m *Printer) Result(err error) {
+	_m.Called(err)
+}
+
+// SetContext provides a mock function with given fields: operation, recipeName, stagesCount
+func (_m *Printer) SetContext(operation shared.Operation, recipeName string, stagesCount int) {
+	_m.Called(operation, recipeName, stagesCount)
 }
 
 // Stage provides a mock function with given fields: stageIndex, s
@@ -42,7 +74,27 @@ func (_m *Printer) Stage(stageIndex int, s recipe.Stage) {
 	_m.Called(stageIndex, s)
 }
 
+// StageFinished provides a mock function with given fields: duration
+func (_m *Printer) StageFinished(duration time.Duration) {
+	_m.Called(duration)
+}
+
 // Step provides a mock function with given fields: stepIndex, steps, s
 func (_m *Printer) Step(stepIndex int, steps int, s recipe.UnitMetadata) {
 	_m.Called(stepIndex, steps, s)
 }
+
+// StepFinished provides a mock function with given fields: duration, err
+func (_m *Printer) StepFinished(duration time.Duration, err error) {
+	_m.Called(duration, err)
+}
+
+// StepSkipped provides a mock function with given fields: condition
+func (_m *Printer) StepSkipped(condition string) {
+	_m.Called(condition)
+}
+
+// TestResult provides a mock function with given fields: result
+func (_m *Printer) TestResult(result printer.TestResult) {
+	_m.Called(result)
+}
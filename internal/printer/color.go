@@ -0,0 +1,40 @@
+package printer
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+)
+
+const (
+	// ColorAuto enables colors only if stdout is a terminal and NO_COLOR environment variable isn't set
+	ColorAuto = "auto"
+
+	// ColorAlways enables colors regardless of the environment
+	ColorAlways = "always"
+
+	// ColorNever disables colors
+	ColorNever = "never"
+)
+
+// NoColorEnv is a name of the environment variable, which disables colors in the auto mode.
+// See https://no-color.org for details.
+const NoColorEnv = "NO_COLOR"
+
+// SetColorMode sets a color policy for all printers
+func SetColorMode(mode string) error {
+	switch mode {
+	case ColorAuto:
+		_, noColor := os.LookupEnv(NoColorEnv)
+		color.NoColor = noColor || os.Getenv("TERM") == "dumb" || !IsTerminal(os.Stdout)
+	case ColorAlways:
+		color.NoColor = false
+	case ColorNever:
+		color.NoColor = true
+	default:
+		return fmt.Errorf("Invalid color mode `%s`. Expected: %s, %s or %s", mode, ColorAuto, ColorAlways, ColorNever)
+	}
+
+	return nil
+}
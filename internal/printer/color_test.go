@@ -0,0 +1,59 @@
+package printer_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/pkosiec/terminer/internal/printer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetColorMode(t *testing.T) {
+	noColor := color.NoColor
+	noColorEnv, noColorEnvSet := os.LookupEnv(printer.NoColorEnv)
+	defer func() {
+		color.NoColor = noColor
+		if noColorEnvSet {
+			_ = os.Setenv(printer.NoColorEnv, noColorEnv)
+			return
+		}
+		_ = os.Unsetenv(printer.NoColorEnv)
+	}()
+
+	t.Run("Always", func(t *testing.T) {
+		err := os.Setenv(printer.NoColorEnv, "1")
+		require.NoError(t, err)
+
+		err = printer.SetColorMode(printer.ColorAlways)
+
+		require.NoError(t, err)
+		assert.False(t, color.NoColor)
+	})
+
+	t.Run("Never", func(t *testing.T) {
+		err := printer.SetColorMode(printer.ColorNever)
+
+		require.NoError(t, err)
+		assert.True(t, color.NoColor)
+	})
+
+	t.Run("Auto with NO_COLOR", func(t *testing.T) {
+		err := os.Setenv(printer.NoColorEnv, "")
+		require.NoError(t, err)
+		color.NoColor = false
+
+		err = printer.SetColorMode(printer.ColorAuto)
+
+		require.NoError(t, err)
+		assert.True(t, color.NoColor)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		err := printer.SetColorMode("sometimes")
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Invalid color mode")
+	})
+}
@@ -0,0 +1,11 @@
+package printer
+
+import (
+	"io"
+	"time"
+)
+
+// NewTTYWithWriter creates a TTY printer, which writes to given writer and assumes a fixed terminal width
+func NewTTYWithWriter(out io.Writer, width int, interval time.Duration, opts ...Option) Printer {
+	return newTTY(newOptions(append([]Option{WithWriter(out)}, opts...)), func() int { return width }, interval)
+}
@@ -0,0 +1,259 @@
+package printer
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shared"
+)
+
+// JSONSchemaVersion is a version of the JSON event schema.
+// It is increased on every backward-incompatible change of the Event structure.
+const JSONSchemaVersion = 1
+
+// EventType is a type of the JSON event
+type EventType string
+
+const (
+	// EventRecipe is emitted when a recipe operation starts
+	EventRecipe EventType = "recipe"
+
+	// EventRecipeSkipped is emitted when a recipe is skipped before an operation, for example an already installed dependency
+	EventRecipeSkipped EventType = "recipeSkipped"
+
+	// EventStage is emitted when a stage starts
+	EventStage EventType = "stage"
+
+	// EventStageFinished is emitted when a stage finishes
+	EventStageFinished EventType = "stageFinished"
+
+	// EventStep is emitted when a step starts
+	EventStep EventType = "step"
+
+	// EventStepSkipped is emitted when a step is skipped as its condition isn't met
+	EventStepSkipped EventType = "stepSkipped"
+
+	// EventStepFinished is emitted when a step finishes
+	EventStepFinished EventType = "stepFinished"
+
+	// EventCommand is emitted when a command starts
+	EventCommand EventType = "command"
+
+	// EventCommandFinished is emitted when a command, or a whole script, finishes
+	EventCommandFinished EventType = "commandFinished"
+
+	// EventExecOutput is emitted for every line of command standard output
+	EventExecOutput EventType = "execOutput"
+
+	// EventExecError is emitted for every line of command standard error output
+	EventExecError EventType = "execError"
+
+	// EventExecTrace is emitted before a process starts, in verbose mode only
+	EventExecTrace EventType = "execTrace"
+
+	// EventResult is emitted when a recipe operation finishes
+	EventResult EventType = "result"
+
+	// EventActions is emitted when a profile is applied, with actions taken for all recipes of the profile
+	EventActions EventType = "actions"
+
+	// EventTestResult is emitted when recipes are tested, with changes left after their rollback
+	EventTestResult EventType = "testResult"
+)
+
+// Event is a single JSON event emitted by the JSON printer.
+// Stage and step indexes are indexes in the recipe, regardless of the operation order.
+// Duration is a number of seconds.
+type Event struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Type          EventType        `json:"type"`
+	Time          time.Time        `json:"time"`
+	Operation     shared.Operation `json:"operation,omitempty"`
+	StageIndex    *int             `json:"stageIndex,omitempty"`
+	StepIndex     *int             `json:"stepIndex,omitempty"`
+	StagesCount   int              `json:"stagesCount,omitempty"`
+	StepsCount    int              `json:"stepsCount,omitempty"`
+	Name          string           `json:"name,omitempty"`
+	Description   string           `json:"description,omitempty"`
+	URL           string           `json:"url,omitempty"`
+	Condition     string           `json:"condition,omitempty"`
+	Reason        string           `json:"reason,omitempty"`
+	Command       string           `json:"command,omitempty"`
+	Output        *string          `json:"output,omitempty"`
+	Success       *bool            `json:"success,omitempty"`
+	Duration      *float64         `json:"duration,omitempty"`
+	Error         string           `json:"error,omitempty"`
+	Actions       []Action         `json:"actions,omitempty"`
+	TestResult    *TestResult      `json:"testResult,omitempty"`
+}
+
+type jsonPrinter struct {
+	options
+
+	mu      sync.Mutex
+	encoder *json.Encoder
+	now     func() time.Time
+
+	operation  shared.Operation
+	stages     int
+	stageIndex *int
+	stepIndex  *int
+}
+
+// NewJSON creates a new Printer, which writes JSON Lines events to a given writer.
+// The quiet option is ignored, as all events are emitted.
+func NewJSON(w io.Writer, opts ...Option) Printer {
+	return &jsonPrinter{options: newOptions(opts), encoder: json.NewEncoder(w), now: time.Now}
+}
+
+func (p *jsonPrinter) SetContext(operation shared.Operation, _ string, stagesCount int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.operation = operation
+	p.stages = stagesCount
+	p.stageIndex = nil
+	p.stepIndex = nil
+}
+
+func (p *jsonPrinter) Recipe(r recipe.UnitMetadata) {
+	p.emit(Event{
+		Type:        EventRecipe,
+		StagesCount: p.stages,
+		Name:        r.Name,
+		Description: r.Description,
+		URL:         r.URL,
+	})
+}
+
+func (p *jsonPrinter) Actions(actions []Action) {
+	p.emit(Event{Type: EventActions, Actions: actions})
+}
+
+func (p *jsonPrinter) TestResult(result TestResult) {
+	p.emit(Event{Type: EventTestResult, TestResult: &result})
+}
+
+func (p *jsonPrinter) RecipeSkipped(name, reason string) {
+	p.emit(Event{Type: EventRecipeSkipped, Name: name, Reason: reason})
+}
+
+func (p *jsonPrinter) Stage(stageIndex int, s recipe.Stage) {
+	p.mu.Lock()
+	index := p.recipeIndex(stageIndex, p.stages)
+	p.stageIndex = &index
+	p.stepIndex = nil
+	p.mu.Unlock()
+
+	p.emit(Event{
+		Type:        EventStage,
+		StepsCount:  len(s.Steps),
+		Name:        s.Metadata.Name,
+		Description: s.Metadata.Description,
+		URL:         s.Metadata.URL,
+	})
+}
+
+func (p *jsonPrinter) StageFinished(duration time.Duration) {
+	p.emit(Event{Type: EventStageFinished, Duration: seconds(duration)})
+}
+
+func (p *jsonPrinter) Step(stepIndex, steps int, s recipe.UnitMetadata) {
+	p.mu.Lock()
+	index := p.recipeIndex(stepIndex, steps)
+	p.stepIndex = &index
+	p.mu.Unlock()
+
+	p.emit(Event{
+		Type:        EventStep,
+		StepsCount:  steps,
+		Name:        s.Name,
+		Description: s.Description,
+		URL:         s.URL,
+	})
+}
+
+func (p *jsonPrinter) StepSkipped(condition string) {
+	p.emit(Event{Type: EventStepSkipped, Condition: condition})
+}
+
+func (p *jsonPrinter) StepFinished(duration time.Duration, err error) {
+	event := p.resultEvent(EventStepFinished, err)
+	event.Duration = seconds(duration)
+	p.emit(event)
+}
+
+func (p *jsonPrinter) Command(cmd string) {
+	p.emit(Event{Type: EventCommand, Command: cmd})
+}
+
+func (p *jsonPrinter) CommandFinished(duration time.Duration) {
+	p.emit(Event{Type: EventCommandFinished, Duration: seconds(duration)})
+}
+
+func (p *jsonPrinter) ExecOutput(output string) {
+	p.emit(Event{Type: EventExecOutput, Output: &output})
+}
+
+func (p *jsonPrinter) ExecError(output string) {
+	p.emit(Event{Type: EventExecError, Output: &output})
+}
+
+func (p *jsonPrinter) ExecTrace(trace string) {
+	if !p.verbose {
+		return
+	}
+
+	p.emit(Event{Type: EventExecTrace, Command: trace})
+}
+
+// Interactive isn't emitted, as output of interactive commands is emitted as execOutput events
+func (p *jsonPrinter) Interactive(_ bool) {}
+
+func (p *jsonPrinter) Result(err error) {
+	p.emit(p.resultEvent(EventResult, err))
+}
+
+func (p *jsonPrinter) resultEvent(eventType EventType, err error) Event {
+	success := err == nil
+	event := Event{Type: eventType, Success: &success}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	return event
+}
+
+func (p *jsonPrinter) emit(event Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	event.SchemaVersion = JSONSchemaVersion
+	event.Time = p.now()
+	event.Operation = p.operation
+	if event.Type != EventRecipe && event.Type != EventResult {
+		event.StageIndex = p.stageIndex
+		if event.Type != EventStage && event.Type != EventStageFinished {
+			event.StepIndex = p.stepIndex
+		}
+	}
+
+	// Errors are ignored the same way as for the default printer
+	_ = p.encoder.Encode(event)
+}
+
+func seconds(d time.Duration) *float64 {
+	s := d.Seconds()
+	return &s
+}
+
+func (p *jsonPrinter) recipeIndex(index, count int) int {
+	if p.operation == shared.OperationRollback {
+		return count - 1 - index
+	}
+
+	return index
+}
@@ -0,0 +1,122 @@
+package printer_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/printer"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shared"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONPrinter(t *testing.T) {
+	t.Run("Install", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.NewJSON(&buf)
+
+		p.SetContext(shared.OperationInstall, "Recipe", 1)
+		p.Recipe(recipe.UnitMetadata{Name: "Recipe", URL: "https://example.com"})
+		p.Stage(0, recipe.Stage{Metadata: recipe.UnitMetadata{Name: "Stage 1"}, Steps: []recipe.Step{{}, {}}})
+		p.Step(0, 2, recipe.UnitMetadata{Name: "Step 1"})
+		p.Command("echo 'Foo'")
+		p.ExecOutput("Foo")
+		p.ExecError("")
+		p.StepFinished(1500*time.Millisecond, nil)
+		p.Step(1, 2, recipe.UnitMetadata{Name: "Step 2"})
+		p.StepSkipped("{{ .foo }}")
+		p.Result(nil)
+
+		events := decodeEvents(t, &buf)
+		require.Len(t, events, 10)
+
+		for _, event := range events {
+			assert.Equal(t, printer.JSONSchemaVersion, event.SchemaVersion)
+			assert.Equal(t, shared.OperationInstall, event.Operation)
+			assert.False(t, event.Time.IsZero())
+		}
+
+		assert.Equal(t, printer.EventRecipe, events[0].Type)
+		assert.Equal(t, "Recipe", events[0].Name)
+		assert.Equal(t, 1, events[0].StagesCount)
+		assert.Nil(t, events[0].StageIndex)
+
+		assert.Equal(t, printer.EventStage, events[1].Type)
+		assert.Equal(t, 0, *events[1].StageIndex)
+		assert.Nil(t, events[1].StepIndex)
+		assert.Equal(t, 2, events[1].StepsCount)
+
+		assert.Equal(t, printer.EventCommand, events[3].Type)
+		assert.Equal(t, "echo 'Foo'", events[3].Command)
+		assert.Equal(t, 0, *events[3].StepIndex)
+
+		assert.Equal(t, printer.EventExecOutput, events[4].Type)
+		assert.Equal(t, "Foo", *events[4].Output)
+		assert.Equal(t, printer.EventExecError, events[5].Type)
+		assert.Equal(t, "", *events[5].Output)
+
+		assert.Equal(t, printer.EventStepFinished, events[6].Type)
+		assert.Equal(t, 0, *events[6].StepIndex)
+		assert.True(t, *events[6].Success)
+		assert.Equal(t, 1.5, *events[6].Duration)
+
+		assert.Equal(t, printer.EventStepSkipped, events[8].Type)
+		assert.Equal(t, 1, *events[8].StepIndex)
+		assert.Equal(t, "{{ .foo }}", events[8].Condition)
+
+		assert.Equal(t, printer.EventResult, events[9].Type)
+		assert.True(t, *events[9].Success)
+		assert.Empty(t, events[9].Error)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.NewJSON(&buf)
+
+		p.SetContext(shared.OperationRollback, "Recipe", 2)
+		p.Stage(0, recipe.Stage{Metadata: recipe.UnitMetadata{Name: "Stage 2"}})
+		p.Step(0, 3, recipe.UnitMetadata{Name: "Step 3"})
+		p.Result(errors.New("Test"))
+
+		events := decodeEvents(t, &buf)
+		require.Len(t, events, 3)
+		assert.Equal(t, 1, *events[0].StageIndex)
+		assert.Equal(t, 1, *events[1].StageIndex)
+		assert.Equal(t, 2, *events[1].StepIndex)
+		assert.False(t, *events[2].Success)
+		assert.Equal(t, "Test", events[2].Error)
+	})
+
+	t.Run("Skipped recipe", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.NewJSON(&buf)
+
+		p.RecipeSkipped("homebrew", "dependency already installed")
+
+		events := decodeEvents(t, &buf)
+		require.Len(t, events, 1)
+		assert.Equal(t, printer.EventRecipeSkipped, events[0].Type)
+		assert.Equal(t, "homebrew", events[0].Name)
+		assert.Equal(t, "dependency already installed", events[0].Reason)
+	})
+}
+
+func decodeEvents(t *testing.T, buf *bytes.Buffer) []printer.Event {
+	var events []printer.Event
+
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var event printer.Event
+		err := json.Unmarshal(scanner.Bytes(), &event)
+		require.NoError(t, err)
+
+		events = append(events, event)
+	}
+
+	return events
+}
@@ -0,0 +1,56 @@
+package printer
+
+import (
+	"io"
+
+	"github.com/fatih/color"
+	"github.com/pkosiec/terminer/pkg/shell"
+)
+
+// Option configures a Printer
+type Option func(o *options)
+
+type options struct {
+	out     io.Writer
+	quiet   bool
+	verbose bool
+	limits  shell.OutputLimits
+}
+
+// WithWriter sets a writer, to which a Printer writes its output
+func WithWriter(w io.Writer) Option {
+	return func(o *options) {
+		o.out = w
+	}
+}
+
+// WithQuiet makes a Printer print command output only for failed steps
+func WithQuiet() Option {
+	return func(o *options) {
+		o.quiet = true
+	}
+}
+
+// WithVerbose makes a Printer print details of executed processes, such as used shell and elevation wrapper
+func WithVerbose() Option {
+	return func(o *options) {
+		o.verbose = true
+	}
+}
+
+// WithOutputLimits sets limits of output of a single step, which is kept until the step finishes.
+// If the output exceeds them, only its first and last lines are kept.
+func WithOutputLimits(limits shell.OutputLimits) Option {
+	return func(o *options) {
+		o.limits = limits
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{out: color.Output, limits: shell.DefaultOutputLimits}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
@@ -0,0 +1,64 @@
+package printer
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/pkosiec/terminer/pkg/shell"
+)
+
+type outputLine struct {
+	text      string
+	formatter *color.Color
+}
+
+// boundedOutput keeps output of a step within limits. If the output exceeds them, it keeps the first half
+// of allowed lines and the last half, and counts lines omitted in between.
+type boundedOutput struct {
+	limits  shell.OutputLimits
+	limiter *shell.OutputLimiter
+	head    []outputLine
+	tail    []outputLine
+}
+
+func newBoundedOutput(limits shell.OutputLimits) boundedOutput {
+	return boundedOutput{limits: limits, limiter: shell.NewOutputLimiter(limits)}
+}
+
+func (o *boundedOutput) add(line outputLine) {
+	head, omitted := o.limiter.Add(line.text)
+	if head {
+		o.head = append(o.head, line)
+		return
+	}
+
+	o.tail = append(o.tail, line)[omitted:]
+}
+
+// lines returns all kept lines. If some lines were omitted, a note is put between first and last lines.
+func (o *boundedOutput) lines() []outputLine {
+	lines := make([]outputLine, 0, len(o.head)+len(o.tail)+1)
+	lines = append(lines, o.head...)
+	if omitted, _ := o.limiter.Omitted(); omitted > 0 {
+		lines = append(lines, outputLine{
+			text:      fmt.Sprintf("... %d lines of output omitted ...", omitted),
+			formatter: color.New(color.Faint),
+		})
+	}
+
+	return append(lines, o.tail...)
+}
+
+// last returns at most n last kept lines
+func (o *boundedOutput) last(n int) []outputLine {
+	lines := o.lines()
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+
+	return lines
+}
+
+func (o *boundedOutput) reset() {
+	*o = newBoundedOutput(o.limits)
+}
@@ -2,33 +2,45 @@ package printer
 
 import (
 	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkosiec/terminer/pkg/installer"
 	"github.com/pkosiec/terminer/pkg/shared"
 
 	"github.com/fatih/color"
 	"github.com/pkosiec/terminer/pkg/recipe"
 )
 
-// Printer is an interface of a module, which outputs text to the standard output
+// Printer is an interface of a module, which outputs text to the standard output.
+// Apart from installer events, it prints recipes skipped before an operation, the operation result,
+// actions taken while applying a profile and results of recipe tests.
 //go:generate mockery -name=Printer -output=automock -outpkg=automock -case=underscore
 type Printer interface {
-	SetContext(operation shared.Operation, stagesCount int)
-	Recipe(r recipe.UnitMetadata)
-	Stage(stageIndex int, s recipe.Stage)
-	Step(stepIndex, steps int, s recipe.UnitMetadata)
-	Command(cmd string)
-	ExecOutput(output string)
-	ExecError(output string)
+	installer.Observer
+	RecipeSkipped(name, reason string)
+	Result(err error)
+	Actions(actions []Action)
+	TestResult(result TestResult)
 }
 
 type printer struct {
+	options
+
 	operation   shared.Operation
 	stages      int
 	indentation string
+
+	mu          sync.Mutex
+	buffered    boundedOutput
+	report      report
+	interactive bool
 }
 
-// New creates a new Printer
-func New() *printer {
-	return &printer{}
+// New creates a new Printer, which prints all output line by line
+func New(opts ...Option) *printer {
+	o := newOptions(opts)
+	return &printer{options: o, buffered: newBoundedOutput(o.limits)}
 }
 
 func stagesIndentation(stagesCount int) string {
@@ -49,23 +61,31 @@ func stagesIndentation(stagesCount int) string {
 	return indentation
 }
 
-func (p *printer) SetContext(operation shared.Operation, stagesCount int) {
+func (p *printer) SetContext(operation shared.Operation, _ string, stagesCount int) {
 	p.operation = operation
 	p.stages = stagesCount
 	p.indentation = stagesIndentation(stagesCount)
+	p.report.reset()
 }
 
 func (p *printer) Recipe(r recipe.UnitMetadata) {
-	c := color.New(color.Bold, color.FgBlue)
+	p.printf("Starting %s...\n\n", p.operation)
+	_, _ = color.New(color.Bold, color.FgBlue).Fprintf(p.out, "%s\n", r.Name)
+
+	p.descriptionAndURL(r, "")
+}
 
-	c.DisableColor()
-	_, _ = c.Printf("Starting %s...\n\n", p.operation)
+func (p *printer) Actions(actions []Action) {
+	writeActions(p.out, actions)
+}
 
-	c.EnableColor()
-	_, _ = c.Printf("%s\n", r.Name)
-	c.DisableColor()
+func (p *printer) TestResult(result TestResult) {
+	writeTestResult(p.out, result)
+}
 
-	p.descriptionAndURL(r, "")
+func (p *printer) RecipeSkipped(name, reason string) {
+	_, _ = color.New(color.Faint, color.Bold).Fprintf(p.out, "Skipped %s: ", name)
+	_, _ = color.New(color.Faint).Fprintf(p.out, "%s\n\n", reason)
 }
 
 func (p *printer) Stage(stageIndex int, s recipe.Stage) {
@@ -79,15 +99,20 @@ func (p *printer) Stage(stageIndex int, s recipe.Stage) {
 	}
 
 	stageCounter := fmt.Sprintf("[%d/%d] ", stageIndex+1, p.stages)
-	_, _ = c.Printf("\n%s%s\n", stageCounter, name)
+	_, _ = c.Fprintf(p.out, "\n%s%s\n", stageCounter, name)
 
 	p.descriptionAndURL(s.Metadata, p.indentation)
+	p.report.stage(s.Metadata.Name)
+}
+
+func (p *printer) StageFinished(duration time.Duration) {
+	p.report.stageFinished(duration)
 }
 
 func (p *printer) Step(stepIndex, steps int, s recipe.UnitMetadata) {
 	c := color.New(color.Bold, color.FgCyan)
 
-	fmt.Printf("\n")
+	p.printf("\n")
 
 	var stepCounter string
 	if steps > 1 {
@@ -95,59 +120,137 @@ func (p *printer) Step(stepIndex, steps int, s recipe.UnitMetadata) {
 	}
 
 	if s.Name != "" {
-		_, _ = c.Printf("%s%s%s\n", p.indentation, stepCounter, s.Name)
+		_, _ = c.Fprintf(p.out, "%s%s%s\n", p.indentation, stepCounter, s.Name)
 	}
 
 	p.descriptionAndURL(s, p.indentation)
+	p.report.step(s.Name)
+}
+
+func (p *printer) StepSkipped(condition string) {
+	p.discardBuffered()
+	p.report.stepSkipped()
+
+	_, _ = color.New(color.Faint, color.Bold).Fprintf(p.out, "%sSkipped: ", p.indentation)
+	_, _ = color.New(color.Faint).Fprintf(p.out, "condition %s not met\n", condition)
+}
+
+func (p *printer) StepFinished(duration time.Duration, err error) {
+	p.report.stepFinished(duration, err)
+
+	if err == nil {
+		p.discardBuffered()
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, line := range p.buffered.lines() {
+		if line.formatter != nil {
+			p.printf("%s%s\n", p.indentation, line.formatter.Sprint(line.text))
+			continue
+		}
+
+		p.printf("%s", line.text)
+	}
+	p.buffered.reset()
 }
 
 func (p *printer) Command(cmd string) {
-	header := color.New(color.Faint, color.Bold)
-	_, _ = header.Printf("%sCommand: ", p.indentation)
-	_, _ = color.New(color.Faint).Printf("%s\n", cmd)
+	header := color.New(color.Faint, color.Bold).Sprintf("%sCommand: ", p.indentation)
+	p.stepOutput(fmt.Sprintf("%s%s\n", header, color.New(color.Faint).Sprint(cmd)))
 }
 
+func (p *printer) CommandFinished(_ time.Duration) {}
+
 func (p *printer) ExecOutput(output string) {
-	p.stepOutput(output, color.New(color.Faint))
+	p.execOutput(output, color.New(color.Faint))
 }
 
 func (p *printer) ExecError(output string) {
-	p.stepOutput(output, color.New(color.Faint, color.FgRed))
+	p.execOutput(output, color.New(color.Faint, color.FgRed))
+}
+
+func (p *printer) ExecTrace(trace string) {
+	if !p.verbose {
+		return
+	}
+
+	p.stepOutput(color.New(color.Faint, color.Italic).Sprintf("%sExecuting: %s\n", p.indentation, trace))
+}
+
+// Interactive makes the printer skip output of an interactive command, as it is already displayed in the terminal
+func (p *printer) Interactive(active bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.interactive = active
 }
 
 func (p *printer) AppInfo(appName, version, url string) {
 	appNameFmt := color.New(color.Bold).Sprint(appName)
-	fmt.Printf("%s %s\n", appNameFmt, version)
-	fmt.Printf("URL: %s\n", url)
+	p.printf("%s %s\n", appNameFmt, version)
+	p.printf("URL: %s\n", url)
 }
 
 func (p *printer) Result(err error) {
+	p.report.write(p.out)
+
 	result := color.New(color.Bold)
-	_, _ = result.Printf("\n")
+	p.printf("\n")
 
 	if err != nil {
-		_, _ = result.Add(color.FgRed).Printf("Error:\n")
-		_, _ = color.New(color.FgRed).Println(err.Error())
+		_, _ = result.Add(color.FgRed).Fprintf(p.out, "Error:\n")
+		_, _ = color.New(color.FgRed).Fprintln(p.out, err.Error())
 		return
 	}
 
-	_, _ = result.Add(color.FgGreen).Println("Success")
+	_, _ = result.Add(color.FgGreen).Fprintln(p.out, "Success")
 }
 
 func (p *printer) descriptionAndURL(m recipe.UnitMetadata, indentation string) {
 	if m.Description != "" {
-		fmt.Printf("%s%s\n", indentation, m.Description)
+		p.printf("%s%s\n", indentation, m.Description)
 	}
 
 	if m.URL != "" {
-		fmt.Printf("%s%s\n", indentation, m.URL)
+		p.printf("%s%s\n", indentation, m.URL)
 	}
 }
 
-func (p *printer) stepOutput(output string, outputFormatter *color.Color) {
+func (p *printer) execOutput(output string, outputFormatter *color.Color) {
 	if output == "" {
 		return
 	}
 
-	_, _ = outputFormatter.Printf("%s%s\n", p.indentation, output)
+	p.stepOutput(outputFormatter.Sprintf("%s%s\n", p.indentation, output))
+}
+
+// stepOutput prints formatted output of a step. In quiet mode, the output is buffered until the step fails.
+func (p *printer) stepOutput(text string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.interactive {
+		return
+	}
+
+	if p.quiet {
+		p.buffered.add(outputLine{text: text})
+		return
+	}
+
+	p.printf("%s", text)
+}
+
+func (p *printer) discardBuffered() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.buffered.reset()
+}
+
+func (p *printer) printf(format string, a ...interface{}) {
+	_, _ = fmt.Fprintf(p.out, format, a...)
 }
@@ -0,0 +1,187 @@
+package printer_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/printer"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shared"
+	"github.com/pkosiec/terminer/pkg/shell"
+	"github.com/pkosiec/terminer/pkg/state"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrinter(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.New(printer.WithWriter(&buf))
+
+		printStep(p, nil)
+
+		out := buf.String()
+		assert.Contains(t, out, "Command: echo 'Foo'")
+		assert.Contains(t, out, "Foo\n")
+		assert.NotContains(t, out, "Executing:")
+	})
+
+	t.Run("Quiet", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.New(printer.WithWriter(&buf), printer.WithQuiet())
+
+		printStep(p, nil)
+
+		out := buf.String()
+		assert.Contains(t, out, "Step 1")
+		assert.NotContains(t, out, "Foo")
+	})
+
+	t.Run("Quiet with failed step", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.New(printer.WithWriter(&buf), printer.WithQuiet())
+
+		printStep(p, errors.New("Test"))
+
+		out := buf.String()
+		assert.Contains(t, out, "Command: echo 'Foo'")
+		assert.Contains(t, out, "Foo\n")
+	})
+
+	t.Run("Quiet with output limits", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.New(printer.WithWriter(&buf), printer.WithQuiet(), printer.WithOutputLimits(shell.OutputLimits{Lines: 4}))
+
+		p.SetContext(shared.OperationInstall, "Recipe", 1)
+		p.Step(0, 1, recipe.UnitMetadata{Name: "Step 1"})
+		for i := 1; i <= 10; i++ {
+			p.ExecOutput(fmt.Sprintf("Line %d", i))
+		}
+		p.StepFinished(time.Second, errors.New("Test"))
+
+		out := buf.String()
+		assert.Contains(t, out, "Line 2\n")
+		assert.Contains(t, out, "... 6 lines of output omitted ...")
+		assert.Contains(t, out, "Line 10\n")
+		assert.NotContains(t, out, "Line 5\n")
+	})
+
+	t.Run("Verbose", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.New(printer.WithWriter(&buf), printer.WithVerbose())
+
+		printStep(p, nil)
+
+		assert.Contains(t, buf.String(), "Executing: /bin/sh -c 'echo '\\''Foo'\\'''")
+	})
+
+	t.Run("Interactive", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.New(printer.WithWriter(&buf))
+
+		p.SetContext(shared.OperationInstall, "Recipe", 1)
+		p.Interactive(true)
+		p.ExecOutput("Already displayed")
+		p.Interactive(false)
+		p.ExecOutput("Foo")
+
+		out := buf.String()
+		assert.NotContains(t, out, "Already displayed")
+		assert.Contains(t, out, "Foo")
+	})
+}
+
+func TestPrinter_Summary(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.New(printer.WithWriter(&buf))
+
+	p.SetContext(shared.OperationInstall, "Recipe", 2)
+	p.Recipe(recipe.UnitMetadata{Name: "Recipe"})
+	p.Stage(0, recipe.Stage{Metadata: recipe.UnitMetadata{Name: "Stage 1"}})
+	p.Step(0, 2, recipe.UnitMetadata{Name: "Fast step"})
+	p.StepFinished(time.Second, nil)
+	p.Step(1, 2, recipe.UnitMetadata{Name: "Slow step"})
+	p.StepFinished(time.Minute+30*time.Second, nil)
+	p.StageFinished(time.Minute + 31*time.Second)
+	p.Stage(1, recipe.Stage{Metadata: recipe.UnitMetadata{Name: "Stage 2"}})
+	p.Step(0, 2, recipe.UnitMetadata{Name: "Skipped step"})
+	p.StepSkipped("{{ .foo }}")
+	p.Step(1, 2, recipe.UnitMetadata{})
+	p.StepFinished(2*time.Second, errors.New("Test"))
+	p.StageFinished(2 * time.Second)
+	p.Result(errors.New("Test"))
+
+	out := buf.String()
+	assert.Contains(t, out, "Summary:\n")
+	assert.Contains(t, out, "  Stage 1                       1m31s\n")
+	assert.Contains(t, out, "      Fast step     applied      1.0s\n")
+	assert.Contains(t, out, "      Slow step     applied     1m30s  slow\n")
+	assert.Contains(t, out, "      Skipped step  skipped      0.0s\n")
+	assert.Contains(t, out, "      Step 2        failed       2.0s  slow\n")
+	assert.Contains(t, out, "  Total                         1m33s\n")
+}
+
+func TestPrinter_Actions(t *testing.T) {
+	var buf bytes.Buffer
+	p := printer.New(printer.WithWriter(&buf))
+
+	p.Actions([]printer.Action{
+		{Recipe: "homebrew", Type: printer.ActionNone, Status: printer.ActionSucceeded},
+		{Recipe: "zsh", Type: printer.ActionInstall, Status: printer.ActionSucceeded},
+		{Recipe: "go", Type: printer.ActionUpgrade, Status: printer.ActionFailed},
+		{Recipe: "node", Type: printer.ActionRemove, Status: printer.ActionNotStarted},
+	})
+
+	out := buf.String()
+	assert.Contains(t, out, "Profile:\n")
+	assert.Contains(t, out, "  homebrew  up to date\n")
+	assert.Contains(t, out, "  zsh       installed\n")
+	assert.Contains(t, out, "  go        upgrade failed\n")
+	assert.Contains(t, out, "  node      remove not started\n")
+}
+
+func TestPrinter_TestResult(t *testing.T) {
+	t.Run("Residual changes", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.New(printer.WithWriter(&buf))
+
+		p.TestResult(printer.TestResult{
+			Changed: 3,
+			Residual: []state.FileChange{
+				{Path: "~/.zsh", Type: state.ChangeCreated, Dir: true},
+				{Path: "~/.zshrc", Type: state.ChangeModified},
+			},
+		})
+
+		out := buf.String()
+		assert.Contains(t, out, "Test:\n")
+		assert.Contains(t, out, "  Installation changed 3 file(s)\n")
+		assert.Contains(t, out, "  Rollback left 2 change(s):\n")
+		assert.Contains(t, out, "    created   ~/.zsh/\n")
+		assert.Contains(t, out, "    modified  ~/.zshrc\n")
+	})
+
+	t.Run("No residual changes", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.New(printer.WithWriter(&buf))
+
+		p.TestResult(printer.TestResult{Changed: 3})
+
+		assert.Contains(t, buf.String(), "  Rollback reverted all changes\n")
+	})
+}
+
+func printStep(p printer.Printer, err error) {
+	p.SetContext(shared.OperationInstall, "Recipe", 1)
+	p.Recipe(recipe.UnitMetadata{Name: "Recipe"})
+	p.Stage(0, recipe.Stage{Metadata: recipe.UnitMetadata{Name: "Stage 1"}})
+	p.Step(0, 1, recipe.UnitMetadata{Name: "Step 1"})
+	p.Command("echo 'Foo'")
+	p.ExecTrace("/bin/sh -c 'echo '\\''Foo'\\'''")
+	p.ExecOutput("Foo")
+	p.StepFinished(time.Second, err)
+	p.StageFinished(time.Second)
+	p.Result(err)
+}
@@ -0,0 +1,191 @@
+package printer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/pkosiec/terminer/pkg/state"
+)
+
+// slowestStepsCount is a number of the slowest steps highlighted in the summary table
+const slowestStepsCount = 3
+
+type stageReport struct {
+	name     string
+	duration time.Duration
+	steps    []*stepReport
+}
+
+type stepReport struct {
+	name     string
+	status   state.StepStatus
+	duration time.Duration
+}
+
+// report collects statuses and durations of stages and steps to print a summary table at the end of operation
+type report struct {
+	stages []*stageReport
+}
+
+func (r *report) reset() {
+	r.stages = nil
+}
+
+func (r *report) stage(name string) {
+	r.stages = append(r.stages, &stageReport{name: name})
+}
+
+func (r *report) stageFinished(duration time.Duration) {
+	if stage := r.currentStage(); stage != nil {
+		stage.duration = duration
+	}
+}
+
+func (r *report) step(name string) {
+	stage := r.currentStage()
+	if stage == nil {
+		return
+	}
+
+	if name == "" {
+		name = fmt.Sprintf("Step %d", len(stage.steps)+1)
+	}
+
+	stage.steps = append(stage.steps, &stepReport{name: name})
+}
+
+func (r *report) stepSkipped() {
+	if step := r.currentStep(); step != nil {
+		step.status = state.StepSkipped
+	}
+}
+
+func (r *report) stepFinished(duration time.Duration, err error) {
+	step := r.currentStep()
+	if step == nil {
+		return
+	}
+
+	step.duration = duration
+	step.status = state.StepApplied
+	if err != nil {
+		step.status = state.StepFailed
+	}
+}
+
+func (r *report) currentStage() *stageReport {
+	if len(r.stages) == 0 {
+		return nil
+	}
+
+	return r.stages[len(r.stages)-1]
+}
+
+func (r *report) currentStep() *stepReport {
+	stage := r.currentStage()
+	if stage == nil || len(stage.steps) == 0 {
+		return nil
+	}
+
+	return stage.steps[len(stage.steps)-1]
+}
+
+// write prints a summary table with all stages and steps, highlighting the slowest steps
+func (r *report) write(w io.Writer) {
+	if len(r.stages) == 0 {
+		return
+	}
+
+	const indentation = "  "
+	const stepIndentation = "    "
+
+	nameWidth := utf8.RuneCountInString("Total")
+	for _, stage := range r.stages {
+		nameWidth = maxInt(nameWidth, utf8.RuneCountInString(stage.name))
+		for _, step := range stage.steps {
+			nameWidth = maxInt(nameWidth, utf8.RuneCountInString(stepIndentation)+utf8.RuneCountInString(step.name))
+		}
+	}
+
+	slowest := r.slowestSteps()
+	var total time.Duration
+
+	_, _ = color.New(color.Bold).Fprintf(w, "\nSummary:\n")
+	for _, stage := range r.stages {
+		total += stage.duration
+		_, _ = fmt.Fprintf(w, "%s%s  %-7s  %8s\n", indentation, pad(stage.name, nameWidth), "", formatDuration(stage.duration))
+
+		for _, step := range stage.steps {
+			line := fmt.Sprintf("%s%s  %s  %8s", indentation, pad(stepIndentation+step.name, nameWidth), statusColor(step.status).Sprintf("%-7s", step.status), formatDuration(step.duration))
+			if slowest[step] {
+				line = fmt.Sprintf("%s  %s", line, color.New(color.Bold, color.FgYellow).Sprint("slow"))
+			}
+
+			_, _ = fmt.Fprintln(w, line)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "%s%s  %-7s  %8s\n", indentation, pad("Total", nameWidth), "", formatDuration(total))
+}
+
+// slowestSteps returns the slowest executed steps, if there is more than one executed step
+func (r *report) slowestSteps() map[*stepReport]bool {
+	var steps []*stepReport
+	for _, stage := range r.stages {
+		for _, step := range stage.steps {
+			if step.status == state.StepApplied || step.status == state.StepFailed {
+				steps = append(steps, step)
+			}
+		}
+	}
+
+	slowest := make(map[*stepReport]bool)
+	if len(steps) < 2 {
+		return slowest
+	}
+
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].duration > steps[j].duration
+	})
+
+	for i := 0; i < len(steps) && i < slowestStepsCount && i < len(steps)-1; i++ {
+		slowest[steps[i]] = true
+	}
+
+	return slowest
+}
+
+func statusColor(status state.StepStatus) *color.Color {
+	switch status {
+	case state.StepApplied:
+		return color.New(color.FgGreen)
+	case state.StepFailed:
+		return color.New(color.FgRed)
+	}
+
+	return color.New(color.Faint)
+}
+
+func pad(text string, width int) string {
+	return fmt.Sprintf("%s%s", text, strings.Repeat(" ", maxInt(0, width-utf8.RuneCountInString(text))))
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+
+	return d.Truncate(time.Second).String()
+}
@@ -0,0 +1,15 @@
+package printer
+
+import (
+	"os"
+
+	"github.com/mattn/go-isatty"
+)
+
+// defaultTerminalWidth is used when the terminal width cannot be detected
+const defaultTerminalWidth = 80
+
+// IsTerminal checks if a given file is a terminal
+func IsTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package printer
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func terminalWidth(f *os.File) int {
+	ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 {
+		return defaultTerminalWidth
+	}
+
+	return int(ws.Col)
+}
@@ -0,0 +1,7 @@
+package printer
+
+import "os"
+
+func terminalWidth(_ *os.File) int {
+	return defaultTerminalWidth
+}
@@ -0,0 +1,38 @@
+package printer
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/fatih/color"
+	"github.com/pkosiec/terminer/pkg/state"
+)
+
+// TestResult describes files changed by installation of tested recipes, and changes left after their rollback
+type TestResult struct {
+	Changed  int                `json:"changed"`
+	Residual []state.FileChange `json:"residual"`
+}
+
+// writeTestResult prints changes left after rollback of tested recipes
+func writeTestResult(w io.Writer, result TestResult) {
+	const indentation = "  "
+
+	_, _ = color.New(color.Bold).Fprintf(w, "\nTest:\n")
+	_, _ = fmt.Fprintf(w, "%sInstallation changed %d file(s)\n", indentation, result.Changed)
+
+	if len(result.Residual) == 0 {
+		_, _ = fmt.Fprintf(w, "%s%s\n", indentation, color.New(color.FgGreen).Sprint("Rollback reverted all changes"))
+		return
+	}
+
+	_, _ = fmt.Fprintf(w, "%s%s\n", indentation, color.New(color.FgRed).Sprintf("Rollback left %d change(s):", len(result.Residual)))
+	for _, change := range result.Residual {
+		path := change.Path
+		if change.Dir {
+			path += "/"
+		}
+
+		_, _ = fmt.Fprintf(w, "%s%s%s  %s\n", indentation, indentation, pad(string(change.Type), len(state.ChangeModified)), path)
+	}
+}
@@ -0,0 +1,385 @@
+package printer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shared"
+)
+
+// defaultTailLines is a number of last output lines displayed for a running step
+const defaultTailLines = 5
+
+const refreshInterval = 100 * time.Millisecond
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// ttyPrinter displays a live tree of stages and steps. For a running step, it shows a spinner, elapsed time and
+// last lines of the command output. Full output is printed only if the step fails.
+type ttyPrinter struct {
+	options
+
+	mu        sync.Mutex
+	width     func() int
+	now       func() time.Time
+	tailLines int
+
+	operation   shared.Operation
+	stages      int
+	indentation string
+
+	stepActive  bool
+	stepName    string
+	stepStart   time.Time
+	stepOutput  boundedOutput
+	interactive bool
+
+	report report
+
+	liveLines    int
+	spinnerFrame int
+	interval     time.Duration
+	refreshing   bool
+	stop         chan struct{}
+	stopped      chan struct{}
+}
+
+// NewTTY creates a new Printer, which displays a live progress in a terminal.
+// If stdout isn't a terminal, it returns the plain text Printer.
+func NewTTY(opts ...Option) Printer {
+	if !IsTerminal(os.Stdout) {
+		return New(opts...)
+	}
+
+	return newTTY(newOptions(opts), func() int { return terminalWidth(os.Stdout) }, refreshInterval)
+}
+
+func newTTY(o options, width func() int, interval time.Duration) *ttyPrinter {
+	p := &ttyPrinter{
+		options:    o,
+		stepOutput: newBoundedOutput(o.limits),
+		width:      width,
+		now:        time.Now,
+		tailLines:  defaultTailLines,
+		interval:   interval,
+	}
+	if o.quiet {
+		p.tailLines = 0
+	}
+
+	p.startRefresh()
+
+	return p
+}
+
+func (p *ttyPrinter) SetContext(operation shared.Operation, _ string, stagesCount int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.operation = operation
+	p.stages = stagesCount
+	p.indentation = stagesIndentation(stagesCount)
+	p.report.reset()
+	p.startRefresh()
+}
+
+func (p *ttyPrinter) Recipe(r recipe.UnitMetadata) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clearLive()
+	p.printf("Starting %s...\n\n", p.operation)
+	p.printf("%s\n", color.New(color.Bold, color.FgBlue).Sprint(r.Name))
+	p.descriptionAndURL(r, "")
+}
+
+func (p *ttyPrinter) Actions(actions []Action) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clearLive()
+	writeActions(p.out, actions)
+}
+
+func (p *ttyPrinter) TestResult(result TestResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clearLive()
+	writeTestResult(p.out, result)
+}
+
+func (p *ttyPrinter) RecipeSkipped(name, reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clearLive()
+	p.printf("%s %s\n\n", color.New(color.Faint, color.Bold).Sprintf("Skipped %s:", name), color.New(color.Faint).Sprint(reason))
+}
+
+func (p *ttyPrinter) Stage(stageIndex int, s recipe.Stage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name := s.Metadata.Name
+	if p.operation == shared.OperationRollback {
+		name = fmt.Sprintf("Reverting '%s'", s.Metadata.Name)
+	}
+
+	p.clearLive()
+	p.printf("\n%s\n", color.New(color.Bold, color.FgBlue).Sprintf("[%d/%d] %s", stageIndex+1, p.stages, name))
+	p.descriptionAndURL(s.Metadata, p.indentation)
+	p.report.stage(s.Metadata.Name)
+}
+
+func (p *ttyPrinter) StageFinished(duration time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.report.stageFinished(duration)
+}
+
+func (p *ttyPrinter) Step(stepIndex, steps int, s recipe.UnitMetadata) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	name := s.Name
+	if name == "" {
+		name = "Step"
+	}
+
+	if steps > 1 {
+		name = fmt.Sprintf("[%d/%d] %s", stepIndex+1, steps, name)
+	}
+
+	p.report.step(s.Name)
+	p.stepActive = true
+	p.stepName = name
+	p.stepStart = p.now()
+	p.stepOutput.reset()
+	p.drawLive()
+}
+
+func (p *ttyPrinter) StepSkipped(condition string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.report.stepSkipped()
+	p.stepActive = false
+	p.clearLive()
+	p.printf("%s%s %s %s\n", p.indentation, color.New(color.Faint).Sprint("-"), p.stepName,
+		color.New(color.Faint).Sprintf("(skipped: condition %s not met)", condition))
+}
+
+func (p *ttyPrinter) StepFinished(duration time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.stepActive {
+		return
+	}
+
+	p.report.stepFinished(duration, err)
+	p.stepActive = false
+	p.clearLive()
+
+	elapsed := color.New(color.Faint).Sprintf("(%s)", formatDuration(duration))
+	if err == nil {
+		p.printf("%s%s %s %s\n", p.indentation, color.New(color.FgGreen).Sprint("✓"), p.stepName, elapsed)
+		return
+	}
+
+	p.printf("%s%s %s %s\n", p.indentation, color.New(color.FgRed).Sprint("✗"), p.stepName, elapsed)
+	for _, line := range p.stepOutput.lines() {
+		p.printf("%s  %s\n", p.indentation, line.formatter.Sprint(line.text))
+	}
+}
+
+func (p *ttyPrinter) Command(cmd string) {
+	p.appendOutput(fmt.Sprintf("Command: %s", cmd), color.New(color.Faint, color.Bold))
+}
+
+func (p *ttyPrinter) CommandFinished(_ time.Duration) {}
+
+func (p *ttyPrinter) ExecOutput(output string) {
+	p.appendOutput(output, color.New(color.Faint))
+}
+
+func (p *ttyPrinter) ExecError(output string) {
+	p.appendOutput(output, color.New(color.Faint, color.FgRed))
+}
+
+func (p *ttyPrinter) ExecTrace(trace string) {
+	if !p.verbose {
+		return
+	}
+
+	p.appendOutput(fmt.Sprintf("Executing: %s", trace), color.New(color.Faint, color.Italic))
+}
+
+// Interactive stops the live area while an interactive command uses the terminal. Output of the command
+// isn't kept, as it is already displayed in the terminal.
+func (p *ttyPrinter) Interactive(active bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.interactive = active
+	if !active {
+		p.startRefresh()
+		return
+	}
+
+	p.stopRefresh()
+	p.clearLive()
+	if p.stepActive {
+		p.printf("%s%s %s\n", p.indentation, color.New(color.FgCyan).Sprint("▸"), p.stepName)
+	}
+}
+
+func (p *ttyPrinter) Result(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stopRefresh()
+	p.clearLive()
+	p.report.write(p.out)
+
+	if err != nil {
+		p.printf("\n%s\n", color.New(color.Bold, color.FgRed).Sprint("Error:"))
+		p.printf("%s\n", color.New(color.FgRed).Sprint(err.Error()))
+		return
+	}
+
+	p.printf("\n%s\n", color.New(color.Bold, color.FgGreen).Sprint("Success"))
+}
+
+func (p *ttyPrinter) appendOutput(text string, formatter *color.Color) {
+	if text == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.interactive {
+		return
+	}
+
+	p.stepOutput.add(outputLine{text: text, formatter: formatter})
+}
+
+func (p *ttyPrinter) refresh(interval time.Duration, stop <-chan struct{}, stopped chan<- struct{}) {
+	defer close(stopped)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.spinnerFrame = (p.spinnerFrame + 1) % len(spinnerFrames)
+			p.drawLive()
+			p.mu.Unlock()
+		}
+	}
+}
+
+// startRefresh starts refreshing the live area, unless it is already refreshed.
+// It has to be called with the mutex held.
+func (p *ttyPrinter) startRefresh() {
+	if p.refreshing {
+		return
+	}
+
+	p.refreshing = true
+	p.stop = make(chan struct{})
+	p.stopped = make(chan struct{})
+	go p.refresh(p.interval, p.stop, p.stopped)
+}
+
+// stopRefresh stops refreshing the live area, so that the printer can be used for another operation.
+// It has to be called with the mutex held.
+func (p *ttyPrinter) stopRefresh() {
+	if !p.refreshing {
+		return
+	}
+
+	p.refreshing = false
+	close(p.stop)
+
+	// The refresh goroutine may wait for the mutex, so it has to be released until the goroutine exits
+	p.mu.Unlock()
+	<-p.stopped
+	p.mu.Lock()
+}
+
+// drawLive redraws the live area with the running step. It has to be called with the mutex held.
+func (p *ttyPrinter) drawLive() {
+	p.clearLive()
+	if !p.stepActive {
+		return
+	}
+
+	width := p.width()
+	lines := []string{
+		fmt.Sprintf("%s%s %s %s", p.indentation, color.New(color.FgCyan).Sprint(spinnerFrames[p.spinnerFrame]), p.stepName,
+			color.New(color.Faint).Sprintf("(%s)", formatDuration(p.now().Sub(p.stepStart)))),
+	}
+
+	tail := p.stepOutput.last(p.tailLines)
+
+	prefix := fmt.Sprintf("%s  │ ", p.indentation)
+	for _, line := range tail {
+		text := truncate(line.text, width-utf8.RuneCountInString(prefix))
+		lines = append(lines, fmt.Sprintf("%s%s", color.New(color.Faint).Sprint(prefix), line.formatter.Sprint(text)))
+	}
+
+	for _, line := range lines {
+		p.printf("%s\n", line)
+	}
+	p.liveLines = len(lines)
+}
+
+// clearLive removes the live area from the terminal. It has to be called with the mutex held.
+func (p *ttyPrinter) clearLive() {
+	if p.liveLines == 0 {
+		return
+	}
+
+	// Move cursor up to the first line of the live area and clear the screen below
+	p.printf("\x1b[%dA\r\x1b[J", p.liveLines)
+	p.liveLines = 0
+}
+
+func (p *ttyPrinter) descriptionAndURL(m recipe.UnitMetadata, indentation string) {
+	if m.Description != "" {
+		p.printf("%s%s\n", indentation, m.Description)
+	}
+
+	if m.URL != "" {
+		p.printf("%s%s\n", indentation, m.URL)
+	}
+}
+
+func (p *ttyPrinter) printf(format string, a ...interface{}) {
+	_, _ = fmt.Fprintf(p.out, format, a...)
+}
+
+func truncate(text string, width int) string {
+	text = strings.ReplaceAll(text, "\t", "    ")
+	if width <= 1 || utf8.RuneCountInString(text) <= width {
+		return text
+	}
+
+	runes := []rune(text)
+	return fmt.Sprintf("%s…", string(runes[:width-1]))
+}
@@ -0,0 +1,143 @@
+package printer_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/printer"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shared"
+	"github.com/pkosiec/terminer/pkg/shell"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTYPrinter(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.NewTTYWithWriter(&buf, 80, time.Hour)
+
+		p.SetContext(shared.OperationInstall, "Recipe", 1)
+		p.Recipe(recipe.UnitMetadata{Name: "Recipe"})
+		p.Stage(0, recipe.Stage{Metadata: recipe.UnitMetadata{Name: "Stage 1"}})
+		p.Step(0, 2, recipe.UnitMetadata{Name: "Step 1"})
+		p.Command("echo 'Foo'")
+		p.ExecOutput("Foo")
+		p.StepFinished(time.Second, nil)
+		p.Step(1, 2, recipe.UnitMetadata{Name: "Step 2"})
+		p.StepSkipped("{{ .foo }}")
+		p.Result(nil)
+
+		out := buf.String()
+		assert.Contains(t, out, "[1/1] Stage 1")
+		assert.Contains(t, out, "✓ [1/2] Step 1")
+		assert.Contains(t, out, "- [2/2] Step 2 (skipped: condition {{ .foo }} not met)")
+		assert.Contains(t, out, "Success")
+		assert.NotContains(t, out, "Foo")
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.NewTTYWithWriter(&buf, 80, time.Hour)
+
+		p.SetContext(shared.OperationInstall, "Recipe", 1)
+		p.Step(0, 1, recipe.UnitMetadata{Name: "Step 1"})
+		for i := 0; i < 10; i++ {
+			p.ExecOutput("Line")
+		}
+		p.ExecError("Error output")
+		p.StepFinished(time.Second, errors.New("Test"))
+		p.Result(errors.New("Test"))
+
+		out := buf.String()
+		assert.Contains(t, out, "✗ Step 1")
+		assert.Equal(t, 10, strings.Count(out, "Line"))
+		assert.Contains(t, out, "Error output")
+		assert.Contains(t, out, "Error:\nTest")
+	})
+
+	t.Run("Output limits", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.NewTTYWithWriter(&buf, 80, time.Hour, printer.WithOutputLimits(shell.OutputLimits{Lines: 4}))
+
+		p.SetContext(shared.OperationInstall, "Recipe", 1)
+		p.Step(0, 1, recipe.UnitMetadata{Name: "Step 1"})
+		for i := 1; i <= 10; i++ {
+			p.ExecOutput(fmt.Sprintf("Line %d", i))
+		}
+		p.StepFinished(time.Second, errors.New("Test"))
+		p.Result(errors.New("Test"))
+
+		out := buf.String()
+		assert.Contains(t, out, "Line 2")
+		assert.Contains(t, out, "... 6 lines of output omitted ...")
+		assert.Contains(t, out, "Line 10")
+		assert.NotContains(t, out, "Line 5")
+	})
+
+	t.Run("Interactive", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.NewTTYWithWriter(&buf, 80, time.Hour)
+
+		p.SetContext(shared.OperationInstall, "Recipe", 1)
+		p.Step(0, 1, recipe.UnitMetadata{Name: "Step 1"})
+		p.Interactive(true)
+		p.ExecOutput("Already displayed")
+		p.Interactive(false)
+		p.StepFinished(time.Second, errors.New("Test"))
+		p.Result(errors.New("Test"))
+
+		out := buf.String()
+		assert.Contains(t, out, "▸ Step 1")
+		assert.Contains(t, out, "✗ Step 1")
+		assert.NotContains(t, out, "Already displayed")
+	})
+
+	t.Run("Live output", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.NewTTYWithWriter(&buf, 20, 10*time.Millisecond)
+
+		p.SetContext(shared.OperationInstall, "Recipe", 1)
+		p.Step(0, 1, recipe.UnitMetadata{Name: "Step 1"})
+		for i := 0; i < 10; i++ {
+			p.ExecOutput("Line")
+		}
+		p.ExecOutput(strings.Repeat("x", 100))
+
+		// Wait for the live area to be redrawn at least once
+		time.Sleep(100 * time.Millisecond)
+		p.Result(nil)
+
+		out := buf.String()
+		assert.Contains(t, out, "│")
+		assert.Contains(t, out, "\x1b[J")
+		assert.Contains(t, out, "…")
+		assert.NotContains(t, out, strings.Repeat("x", 20))
+	})
+	t.Run("Multiple operations", func(t *testing.T) {
+		var buf bytes.Buffer
+		p := printer.NewTTYWithWriter(&buf, 80, 10*time.Millisecond)
+
+		p.RecipeSkipped("Base", "dependency already installed")
+		for _, name := range []string{"Recipe 1", "Recipe 2"} {
+			p.SetContext(shared.OperationInstall, "Recipe", 1)
+			p.Recipe(recipe.UnitMetadata{Name: name})
+			p.Step(0, 1, recipe.UnitMetadata{Name: "Step 1"})
+			p.ExecOutput("Line")
+
+			// Wait for the live area to be redrawn at least once
+			time.Sleep(50 * time.Millisecond)
+			p.StepFinished(time.Second, nil)
+			p.Result(nil)
+		}
+
+		out := buf.String()
+		assert.Contains(t, out, "Skipped Base: dependency already installed")
+		assert.Contains(t, out, "Recipe 2")
+		assert.Equal(t, 2, strings.Count(out, "Success"))
+		assert.True(t, strings.Count(out, "│ Line") >= 2)
+	})
+}
@@ -0,0 +1,210 @@
+package recipecmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/pkosiec/terminer/internal/printer"
+	"github.com/pkosiec/terminer/internal/summary"
+	"github.com/pkosiec/terminer/pkg/installer"
+	"github.com/pkosiec/terminer/pkg/profile"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shared"
+	"github.com/pkosiec/terminer/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+// noDefinitionReason is printed for recipes, which can't be pruned, as their state doesn't contain a recipe definition
+const noDefinitionReason = "recipe definition isn't saved in its state, so it can't be reverted"
+
+// Apply handles the apply command. It installs missing recipes of a profile, upgrades changed ones and,
+// with the prune flag, reverts installed recipes, which aren't listed in the profile.
+func Apply(cmd *cobra.Command, args []string) error {
+	return execute(applyProfile)(cmd, args)
+}
+
+func applyProfile(ctx context.Context, args []string, s stores, p printer.Printer) ([]summary.Summary, error) {
+	plan, err := planProfile(ctx, args[0], s, p)
+	if err != nil {
+		return failedSummaries(p, err)
+	}
+
+	summaries, err := runPlan(ctx, plan)
+
+	actions := make([]printer.Action, 0, len(plan))
+	for _, item := range plan {
+		status := item.status
+		if status == "" {
+			status = printer.ActionNotStarted
+		}
+
+		actions = append(actions, printer.Action{Recipe: item.name, Type: item.action, Status: status})
+	}
+	p.Actions(actions)
+
+	return summaries, err
+}
+
+// planProfile plans actions for all recipes of a profile and their dependencies, in the order of installation.
+// Recipes to prune are reverted at the end, in reverse order of their dependencies.
+func planProfile(ctx context.Context, path string, s stores, p printer.Printer) ([]plannedRecipe, error) {
+	prof, err := profile.FromPath(path)
+	if err != nil {
+		return nil, exitcode.New(exitcode.Load, err)
+	}
+
+	err = prof.Validate()
+	if err != nil {
+		return nil, exitcode.New(exitcode.Validation, err)
+	}
+
+	var recipes []*recipe.Recipe
+	srcs := sources{}
+	parameters := make(map[string]map[string]string)
+	for _, entry := range prof.Recipes {
+		r, err := entry.Load(http.DefaultClient)
+		if err != nil {
+			return nil, exitcode.New(exitcode.Load, errors.Wrapf(err, "while loading recipe %s", entry))
+		}
+
+		recipes = append(recipes, r)
+		parameters[state.Key(r.Metadata.Name)] = entry.Parameters
+
+		source := entry.Source()
+		if source.Path != "" {
+			source.Path = absPath(source.Path)
+		}
+		srcs[r] = source
+	}
+
+	recipes, skipped, err := loadDependencies(recipes, srcs, s.recipes, p)
+	if err != nil {
+		return nil, exitcode.New(exitcode.Load, err)
+	}
+
+	recipes, err = recipe.SortByDependencies(recipes)
+	if err != nil {
+		return nil, exitcode.New(exitcode.Validation, err)
+	}
+
+	keep := make(map[string]bool)
+	for _, name := range skipped {
+		keep[state.Key(name)] = true
+	}
+
+	var plan []plannedRecipe
+	for _, r := range recipes {
+		keep[state.Key(r.Metadata.Name)] = true
+
+		item, err := planProfileRecipe(ctx, r, srcs[r], parameters[state.Key(r.Metadata.Name)], s, p)
+		if err != nil {
+			return nil, err
+		}
+
+		plan = append(plan, item)
+	}
+
+	if !Prune {
+		return plan, nil
+	}
+
+	pruned, err := planPrune(ctx, keep, s, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(plan, pruned...), nil
+}
+
+// planProfileRecipe installs a recipe, which isn't installed, and upgrades it if its definition or parameters changed
+func planProfileRecipe(ctx context.Context, r *recipe.Recipe, source state.Source, parameters map[string]string, s stores, p printer.Printer) (plannedRecipe, error) {
+	record, err := s.recipes.Get(r.Metadata.Name)
+	if err != nil {
+		return plannedRecipe{}, exitcode.New(exitcode.Load, err)
+	}
+
+	checksum, err := r.Checksum()
+	if err != nil {
+		return plannedRecipe{}, err
+	}
+
+	installed := record != nil && record.Status == state.StatusInstalled
+	if installed && record.Checksum == checksum && equalParameters(record.Parameters, parameters) {
+		return plannedRecipe{name: r.Metadata.Name, action: printer.ActionNone}, nil
+	}
+
+	item, err := newPlannedRecipe(ctx, r, shared.OperationInstall, s, p, installer.WithSource(source), installer.WithParameters(parameters))
+	if err != nil {
+		return plannedRecipe{}, err
+	}
+
+	item.action = printer.ActionInstall
+	if installed {
+		item.action = printer.ActionUpgrade
+	}
+
+	return item, nil
+}
+
+// planPrune reverts installed recipes, which aren't kept, using definitions saved in their state
+func planPrune(ctx context.Context, keep map[string]bool, s stores, p printer.Printer) ([]plannedRecipe, error) {
+	records, err := s.recipes.List()
+	if err != nil {
+		return nil, exitcode.New(exitcode.Load, err)
+	}
+
+	var recipes []*recipe.Recipe
+	for _, record := range records {
+		if keep[state.Key(record.Recipe)] {
+			continue
+		}
+
+		if len(record.Definition) == 0 {
+			p.RecipeSkipped(record.Recipe, noDefinitionReason)
+			continue
+		}
+
+		var r *recipe.Recipe
+		err := json.Unmarshal(record.Definition, &r)
+		if err != nil {
+			return nil, exitcode.New(exitcode.Load, errors.Wrapf(err, "while loading definition of recipe `%s`", record.Recipe))
+		}
+
+		recipes = append(recipes, r)
+	}
+
+	recipes, err = recipe.SortByDependencies(recipes)
+	if err != nil {
+		return nil, exitcode.New(exitcode.Validation, err)
+	}
+
+	plan := make([]plannedRecipe, 0, len(recipes))
+	for i := len(recipes) - 1; i >= 0; i-- {
+		item, err := newPlannedRecipe(ctx, recipes[i], shared.OperationRollback, s, p)
+		if err != nil {
+			return nil, err
+		}
+
+		item.action = printer.ActionRemove
+		plan = append(plan, item)
+	}
+
+	return plan, nil
+}
+
+func equalParameters(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for name, value := range a {
+		if other, ok := b[name]; !ok || other != value {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,94 @@
+package recipecmd_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/pkosiec/terminer/internal/recipecmd"
+	"github.com/pkosiec/terminer/internal/summary"
+	"github.com/pkosiec/terminer/internal/testutil"
+	"github.com/pkosiec/terminer/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApply(t *testing.T) {
+	stateDir := testutil.SetStateDir(t)
+
+	store := state.NewFileStore(stateDir)
+
+	t.Run("Install missing recipes", func(t *testing.T) {
+		summaries, err := applyInCI(t, "./testdata/profile.yaml", false)
+
+		require.NoError(t, err)
+		require.Len(t, summaries, 2)
+		assert.Equal(t, "Base", summaries[0].Recipe)
+		assert.Equal(t, "Dependent", summaries[1].Recipe)
+	})
+
+	t.Run("Nothing changed", func(t *testing.T) {
+		summaries, err := applyInCI(t, "./testdata/profile.yaml", false)
+
+		require.NoError(t, err)
+		assert.Empty(t, summaries)
+	})
+
+	t.Run("Upgrade and prune", func(t *testing.T) {
+		summaries, err := applyInCI(t, "./testdata/base-profile.yaml", true)
+
+		require.NoError(t, err)
+		require.Len(t, summaries, 2)
+		assert.Equal(t, "Base", summaries[0].Recipe)
+		assert.Equal(t, "Dependent", summaries[1].Recipe)
+
+		record, err := store.Get("Base")
+		require.NoError(t, err)
+		require.NotNil(t, record)
+		assert.Equal(t, map[string]string{"greeting": "Hello"}, record.Parameters)
+		require.NotNil(t, record.Source)
+		assert.True(t, filepath.IsAbs(record.Source.Path))
+		assert.Equal(t, "base-recipe.yaml", filepath.Base(record.Source.Path))
+
+		record, err = store.Get("Dependent")
+		require.NoError(t, err)
+		assert.Nil(t, record)
+	})
+
+	t.Run("Invalid profile path", func(t *testing.T) {
+		_, err := applyInCI(t, "./testdata/not-existing.yaml", false)
+
+		require.Error(t, err)
+		assert.Equal(t, exitcode.Load, exitcode.FromError(err))
+	})
+}
+
+// applyInCI applies a profile in CI mode and returns summaries of all operations
+func applyInCI(t *testing.T, path string, prune bool) ([]summary.Summary, error) {
+	summaryPath := filepath.Join(t.TempDir(), "summary.json")
+
+	recipecmd.Prune = prune
+	recipecmd.CI = true
+	recipecmd.SummaryPath = summaryPath
+	noColorBak := color.NoColor
+	defer func() {
+		recipecmd.Prune = false
+		recipecmd.CI = false
+		recipecmd.SummaryPath = recipecmd.DefaultSummaryPath
+		color.NoColor = noColorBak
+	}()
+
+	applyErr := recipecmd.Apply(nil, []string{path})
+
+	bytes, err := ioutil.ReadFile(summaryPath)
+	require.NoError(t, err)
+
+	var summaries []summary.Summary
+	err = json.Unmarshal(bytes, &summaries)
+	require.NoError(t, err)
+
+	return summaries, applyErr
+}
@@ -0,0 +1,58 @@
+package recipecmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/pkosiec/terminer/pkg/installer"
+	"github.com/pkosiec/terminer/pkg/recipe"
+)
+
+// confirmPrivileged returns a function, which lists commands with elevated privileges and asks user to confirm them.
+// In non-interactive mode, the commands can be confirmed only with the --yes flag.
+func confirmPrivileged(in io.Reader, out io.Writer, nonInteractive bool) installer.ConfirmFn {
+	reader := bufio.NewReader(in)
+
+	return func(recipeName string, commands []recipe.PrivilegedCommand) error {
+		fmt.Fprintf(out, "Recipe `%s` runs the following commands with elevated privileges:\n", recipeName)
+		for _, command := range commands {
+			fmt.Fprintf(out, "  %s / %s (%s):\n", command.Stage, command.Step, privilegeDescription(command))
+			for _, line := range strings.Split(strings.TrimRight(command.Command, "\n"), "\n") {
+				fmt.Fprintf(out, "    %s\n", line)
+			}
+		}
+
+		if nonInteractive {
+			return exitcode.New(exitcode.NotConfirmed, fmt.Errorf("Commands with elevated privileges of recipe `%s` have to be confirmed. Review them and use the --yes flag", recipeName))
+		}
+
+		fmt.Fprint(out, "Continue? [y/N] ")
+		answer, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return exitcode.New(exitcode.NotConfirmed, err)
+		}
+
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return nil
+		}
+
+		return exitcode.New(exitcode.NotConfirmed, fmt.Errorf("Commands with elevated privileges of recipe `%s` weren't confirmed", recipeName))
+	}
+}
+
+// privilegeDescription describes why a command runs with elevated privileges
+func privilegeDescription(command recipe.PrivilegedCommand) string {
+	var reasons []string
+	if command.User != "" {
+		reasons = append(reasons, fmt.Sprintf("as %s", command.User))
+	}
+	if len(command.Programs) > 0 {
+		reasons = append(reasons, fmt.Sprintf("invokes %s", strings.Join(command.Programs, ", ")))
+	}
+
+	return strings.Join(reasons, ", ")
+}
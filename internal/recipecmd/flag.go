@@ -1,15 +1,117 @@
 package recipecmd
 
-import "github.com/spf13/cobra"
+import (
+	"github.com/pkosiec/terminer/pkg/elevation"
+	"github.com/pkosiec/terminer/pkg/shell"
+	"github.com/spf13/cobra"
+)
 
-// URL is a variable which stores an address to a recipe given by user
-var URL string
+// OutputText is an output format for humans. It displays a live progress if stdout is a terminal
+const OutputText = "text"
 
-// FilePath is a variable which stores a file path to a recipe given by user
-var FilePath string
+// OutputPlain is an output format for humans, which prints all command output line by line
+const OutputPlain = "plain"
+
+// OutputJSON is an output format, which prints a JSON event per line
+const OutputJSON = "json"
+
+// DefaultSummaryPath is a default path of the JSON summary file written in CI mode
+const DefaultSummaryPath = "terminer-summary.json"
+
+// URLs is a variable which stores addresses of recipes given by user
+var URLs []string
+
+// FilePaths is a variable which stores file paths of recipes given by user
+var FilePaths []string
+
+// CI is a variable which enables non-interactive mode without colors, which stops on first error and writes a summary file
+var CI bool
+
+// SummaryPath is a variable which stores a path of the JSON summary file written in CI mode
+var SummaryPath = DefaultSummaryPath
+
+// DryRun is a variable which makes operations only print commands without executing them
+var DryRun bool
+
+// Quiet is a variable which makes printers show command output only for failed steps
+var Quiet bool
+
+// Verbose is a variable which makes printers show full command lines of executed processes
+var Verbose bool
+
+// Prune is a variable which makes the apply command revert installed recipes, which aren't listed in the profile
+var Prune bool
+
+// TrackChanges is a variable which makes installations save files changed by every step in the recipe state
+var TrackChanges bool
+
+// TrackRoots is a variable which stores directories, in which changed files are tracked
+var TrackRoots []string
+
+// Home is a variable which stores a home directory, in which recipes are installed instead of the current user's one
+var Home string
+
+// RootPrefix is a variable which stores a root prefix, under which recipes are installed
+var RootPrefix string
+
+// Yes is a variable which skips the confirmation of commands, which run with elevated privileges
+var Yes bool
+
+// Elevation is a variable which stores a method of running commands as root or as another user
+var Elevation = string(elevation.MethodAuto)
+
+// AsUser is a variable which stores a user, who runs commands, which define neither root nor user
+var AsUser string
+
+// MaxOutputLines is a variable which stores a maximum number of printed lines of output of a single command
+var MaxOutputLines = shell.DefaultOutputLimits.Lines
+
+// MaxOutputBytes is a variable which stores a maximum size of printed output of a single command
+var MaxOutputBytes = shell.DefaultOutputLimits.Bytes
+
+// JUnitPath is a variable which stores a path of the JUnit XML report written by the test command
+var JUnitPath string
+
+// Unshare is a variable which makes the test command run in new Linux user and mount namespaces
+var Unshare bool
+
+// Output is a variable which stores an output format
+var Output = OutputText
 
 // SupportFlags sets required flags for recipe operations
 func SupportFlags(cmd *cobra.Command) {
-	cmd.Flags().StringVarP(&URL, "url", "u", "", "Recipe URL")
-	cmd.Flags().StringVarP(&FilePath, "filepath", "f", "", "Recipe file path")
+	cmd.Flags().StringArrayVarP(&URLs, "url", "u", nil, "Recipe URL. Can be repeated")
+	cmd.Flags().StringArrayVarP(&FilePaths, "filepath", "f", nil, "Recipe file path. Can be repeated")
+	supportOperationFlags(cmd)
+}
+
+// SupportApplyFlags sets required flags for the apply command
+func SupportApplyFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&Prune, "prune", false, "Revert installed recipes, which aren't listed in the profile")
+	supportOperationFlags(cmd)
+}
+
+// SupportTestFlags sets required flags for the test command
+func SupportTestFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVarP(&URLs, "url", "u", nil, "Recipe URL. Can be repeated")
+	cmd.Flags().StringArrayVarP(&FilePaths, "filepath", "f", nil, "Recipe file path. Can be repeated")
+	cmd.Flags().StringVarP(&Output, "output", "o", OutputText, "Output format. One of: text, plain, json")
+	cmd.Flags().BoolVar(&CI, "ci", false, "Disable colors and prompts, stop on first error and write a JSON summary file")
+	cmd.Flags().StringVar(&SummaryPath, "summary-file", DefaultSummaryPath, "Path of the JSON summary file written in CI mode")
+	cmd.Flags().BoolVarP(&Yes, "yes", "y", false, "Run commands with elevated privileges without confirmation")
+	cmd.Flags().StringVar(&JUnitPath, "junit", "", "Write test results as a JUnit XML report to a given file")
+	cmd.Flags().BoolVar(&Unshare, "unshare", false, "Run the test in new user and mount namespaces, with the throwaway home directory mounted over the real one. Linux only")
+	cmd.Flags().StringArrayVar(&TrackRoots, "track-root", nil, "Additional directory, which must be unchanged after rollback. Can be repeated")
+}
+
+func supportOperationFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&Output, "output", "o", OutputText, "Output format. One of: text, plain, json")
+	cmd.Flags().BoolVar(&DryRun, "dry-run", false, "Print commands without executing them")
+	cmd.Flags().BoolVarP(&Yes, "yes", "y", false, "Run commands with elevated privileges without confirmation")
+	cmd.Flags().BoolVar(&CI, "ci", false, "Disable colors and prompts, stop on first error and write a JSON summary file")
+	cmd.Flags().StringVar(&SummaryPath, "summary-file", DefaultSummaryPath, "Path of the JSON summary file written in CI mode")
+	cmd.Flags().StringVar(&Home, "home", "", "Home directory, in which recipes are installed. By default, the home directory saved in the recipe state is used for rollback")
+	cmd.Flags().StringVar(&RootPrefix, "root-prefix", "", "Root prefix, under which recipes are installed, passed to commands in TERMINER_PREFIX. By default, the prefix saved in the recipe state is used for rollback")
+	cmd.Flags().BoolVar(&TrackChanges, "track-changes", false, "Save files created, modified and deleted by every installed step")
+	cmd.Flags().StringArrayVar(&TrackRoots, "track-root", nil, "Directory, in which changed files are tracked. Can be repeated. By default, the home directory is tracked")
 }
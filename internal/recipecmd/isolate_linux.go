@@ -0,0 +1,24 @@
+package recipecmd
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+// isolateHome mounts the test home directory over the real one, so that recipes, which use absolute paths
+// of the home directory, change only the test home directory. It requires a new mount namespace.
+func isolateHome(home string) error {
+	realHome, err := os.UserHomeDir()
+	if err != nil {
+		return errors.Wrap(err, "while getting home directory")
+	}
+
+	err = syscall.Mount(home, realHome, "", syscall.MS_BIND, "")
+	if err != nil {
+		return errors.Wrapf(err, "while mounting test home directory over %s", realHome)
+	}
+
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package recipecmd
+
+import "github.com/pkg/errors"
+
+// isolateHome isn't supported outside Linux, as it requires mount namespaces
+func isolateHome(_ string) error {
+	return errors.New("Mounting the test home directory is supported only on Linux")
+}
@@ -1,60 +1,452 @@
 package recipecmd
 
 import (
-	"github.com/pkosiec/terminer/pkg/shared"
+	"context"
+	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 
+	"github.com/fatih/color"
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/exitcode"
 	"github.com/pkosiec/terminer/internal/printer"
+	"github.com/pkosiec/terminer/internal/runlog"
+	"github.com/pkosiec/terminer/internal/summary"
+	"github.com/pkosiec/terminer/pkg/backup"
+	"github.com/pkosiec/terminer/pkg/elevation"
+	"github.com/pkosiec/terminer/pkg/fstrack"
 	"github.com/pkosiec/terminer/pkg/installer"
+	"github.com/pkosiec/terminer/pkg/path"
 	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shared"
+	"github.com/pkosiec/terminer/pkg/shell"
+	"github.com/pkosiec/terminer/pkg/state"
 	"github.com/spf13/cobra"
 )
 
+// alreadyInstalledReason is printed for dependencies, which are skipped as they are already installed
+const alreadyInstalledReason = "dependency already installed"
+
 // Run returns an function to handle command operation
 func Run(operation shared.Operation) func(cmd *cobra.Command, args []string) error {
+	return execute(func(ctx context.Context, args []string, s stores, p printer.Printer) ([]summary.Summary, error) {
+		plan, err := planRecipes(ctx, operation, args, s, p)
+		if err != nil {
+			return failedSummaries(p, err)
+		}
+
+		return runPlan(ctx, plan)
+	})
+}
+
+// stores persist recipe states and file backups between operations.
+// Tracker is set if files changed by steps are tracked. Elevator is shared by all recipes,
+// so that the elevation method is detected and credentials are asked for once.
+type stores struct {
+	recipes  state.Store
+	backups  *backup.Store
+	tracker  *fstrack.Tracker
+	elevator *elevation.Elevator
+}
+
+// operationFn runs recipe operations and returns their summaries
+type operationFn func(ctx context.Context, args []string, s stores, p printer.Printer) ([]summary.Summary, error)
+
+// execute returns a command handler, which sets up a printer, runs operations and writes a summary file in CI mode
+func execute(fn operationFn) func(cmd *cobra.Command, args []string) error {
 	return func(cmd *cobra.Command, args []string) error {
-		p := printer.New()
-		i, err := loadRecipeAndSetupInstaller(args, URL, FilePath, p)
+		if cmd != nil {
+			// Errors returned from now on are not related to command usage
+			cmd.SilenceUsage = true
+		}
+
+		if CI {
+			color.NoColor = true
+		}
+
+		p, err := newPrinter(Output)
+		if err != nil {
+			return err
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		stateDir, err := state.Dir()
+		if err != nil {
+			return err
+		}
+
+		tracker, err := newTracker(stateDir)
 		if err != nil {
 			return err
 		}
 
-		err = func() error {
-			switch operation {
+		s := stores{
+			recipes:  state.NewFileStore(stateDir),
+			backups:  backup.NewStore(stateDir),
+			tracker:  tracker,
+			elevator: newElevator(),
+		}
+		summaries, err := fn(ctx, args, s, runlog.NewTee(p, runlog.NewStore(stateDir)))
+		if CI {
+			writeErr := summary.WriteJSON(summaries, SummaryPath)
+			if writeErr != nil {
+				if err == nil {
+					return writeErr
+				}
+
+				fmt.Fprintln(os.Stderr, writeErr.Error())
+			}
+		}
+
+		return err
+	}
+}
+
+// plannedRecipe is a recipe, which is ready to run, with its own summary recorder.
+// A recipe without installer doesn't need any operation.
+type plannedRecipe struct {
+	name      string
+	operation shared.Operation
+	action    printer.ActionType
+	status    printer.ActionStatus
+	installer *installer.Installer
+	recorder  *summary.Recorder
+}
+
+// runPlan runs operations of all recipes one by one and returns their summaries.
+// It stops on the first failed recipe.
+func runPlan(ctx context.Context, plan []plannedRecipe) ([]summary.Summary, error) {
+	var summaries []summary.Summary
+	for i := range plan {
+		item := &plan[i]
+		if item.installer == nil {
+			item.status = printer.ActionSucceeded
+			continue
+		}
+
+		err := func() error {
+			switch item.operation {
 			case shared.OperationInstall:
-				return i.Install()
+				return item.installer.Install()
 			case shared.OperationRollback:
-				return i.Rollback()
+				return item.installer.Rollback()
 			}
 
-			return i.Install()
+			return item.installer.Install()
 		}()
-		p.Result(err)
+		item.recorder.Result(err)
+
+		item.status = printer.ActionSucceeded
+		if err != nil {
+			item.status = printer.ActionFailed
 
-		return nil
+			// The error has been already printed
+			err = exitcode.NewSilent(operationExitCode(ctx, item.operation, err), err)
+		}
+
+		summaries = append(summaries, item.recorder.Finish(err, exitcode.FromError(err)))
+		if err != nil {
+			return summaries, err
+		}
 	}
+
+	return summaries, nil
 }
 
-func loadRecipeAndSetupInstaller(recipeNames []string, URL, filePath string, p printer.Printer) (*installer.Installer, error) {
-	var r *recipe.Recipe
-	var err error
+// failedSummaries prints an error, which occurred before any recipe operation, and returns its summary
+func failedSummaries(p printer.Printer, err error) ([]summary.Summary, error) {
+	recorder := summary.NewRecorder(p)
+	recorder.Result(err)
+	return []summary.Summary{recorder.Finish(err, exitcode.FromError(err))}, exitcode.NewSilent(exitcode.FromError(err), err)
+}
 
-	if len(recipeNames) > 0 && recipeNames[0] != "" {
-		r, err = recipe.FromRepository(recipeNames[0], http.DefaultClient)
-	} else if URL != "" {
-		r, _, err = recipe.FromURL(URL, http.DefaultClient)
-	} else if filePath != "" {
-		r, err = recipe.FromPath(filePath)
+func newPrinter(output string) (printer.Printer, error) {
+	opts := []printer.Option{
+		printer.WithOutputLimits(shell.OutputLimits{Lines: MaxOutputLines, Bytes: MaxOutputBytes}),
+	}
+	if Quiet {
+		opts = append(opts, printer.WithQuiet())
+	}
+	if Verbose {
+		opts = append(opts, printer.WithVerbose())
 	}
 
+	switch output {
+	case OutputText:
+		if CI {
+			return printer.New(opts...), nil
+		}
+
+		return printer.NewTTY(opts...), nil
+	case OutputPlain:
+		return printer.New(opts...), nil
+	case OutputJSON:
+		return printer.NewJSON(os.Stdout, opts...), nil
+	}
+
+	return nil, exitcode.New(exitcode.Validation, fmt.Errorf("Invalid output format `%s`. Expected: %s, %s or %s", output, OutputText, OutputPlain, OutputJSON))
+}
+
+// newElevator returns an elevator with the method given by user. Elevation doesn't ask for passwords
+// in CI mode or if the standard input isn't a terminal.
+func newElevator() *elevation.Elevator {
+	// The method is validated before running any command
+	method, _ := elevation.ParseMethod(Elevation)
+
+	var opts []elevation.Option
+	if CI || !printer.IsTerminal(os.Stdin) {
+		opts = append(opts, elevation.WithNonInteractive())
+	}
+
+	return elevation.New(method, opts...)
+}
+
+// newTracker returns a tracker of files changed by steps, or nil if changes aren't tracked.
+// The state directory is never tracked, as it changes during every operation.
+func newTracker(stateDir string) (*fstrack.Tracker, error) {
+	if !TrackChanges {
+		return nil, nil
+	}
+
+	var roots []string
+	for _, root := range TrackRoots {
+		expanded, err := path.ExpandHome(root)
+		if err != nil {
+			return nil, err
+		}
+
+		roots = append(roots, absPath(expanded))
+	}
+
+	if len(roots) == 0 {
+		home := target().Home
+		if home == "" && AsUser != "" {
+			var err error
+			home, err = shell.HomeDir(AsUser)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if home == "" {
+			var err error
+			home, err = os.UserHomeDir()
+			if err != nil {
+				return nil, errors.Wrap(err, "while getting home directory")
+			}
+		}
+
+		roots = append(roots, home)
+	}
+
+	return fstrack.New(roots, fstrack.WithExcludes(stateDir)), nil
+}
+
+// target returns the home directory and root prefix given by user, as absolute paths, and the user running commands
+func target() state.Target {
+	t := state.Target{User: AsUser}
+	if Home != "" {
+		t.Home = absPath(Home)
+	}
+	if RootPrefix != "" {
+		t.Prefix = absPath(RootPrefix)
+	}
+
+	return t
+}
+
+func operationExitCode(ctx context.Context, operation shared.Operation, err error) int {
+	if ctx.Err() != nil {
+		return exitcode.Interrupted
+	}
+
+	var exitErr *exitcode.Error
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+
+	if operation == shared.OperationRollback {
+		return exitcode.RollbackFailure
+	}
+
+	return exitcode.StepFailure
+}
+
+// planRecipes loads all recipes and sets up their installers in the order of the operation.
+// Recipes are installed after their dependencies, and reverted in reverse order.
+func planRecipes(ctx context.Context, operation shared.Operation, recipeNames []string, s stores, p printer.Printer) ([]plannedRecipe, error) {
+	recipes, srcs, err := loadRecipes(recipeNames, URLs, FilePaths)
+	if err != nil {
+		return nil, exitcode.New(exitcode.Load, err)
+	}
+
+	if operation == shared.OperationInstall {
+		recipes, _, err = loadDependencies(recipes, srcs, s.recipes, p)
+		if err != nil {
+			return nil, exitcode.New(exitcode.Load, err)
+		}
+	}
+
+	recipes, err = recipe.SortByDependencies(recipes)
+	if err != nil {
+		return nil, exitcode.New(exitcode.Validation, err)
+	}
+
+	if operation == shared.OperationRollback {
+		for i, j := 0, len(recipes)-1; i < j; i, j = i+1, j-1 {
+			recipes[i], recipes[j] = recipes[j], recipes[i]
+		}
+	}
+
+	plan := make([]plannedRecipe, 0, len(recipes))
+	for _, r := range recipes {
+		item, err := newPlannedRecipe(ctx, r, operation, s, p, installer.WithSource(srcs[r]))
+		if err != nil {
+			return nil, err
+		}
+
+		plan = append(plan, item)
+	}
+
+	return plan, nil
+}
+
+// newPlannedRecipe sets up an installer for a recipe with options given by user and additional options
+func newPlannedRecipe(ctx context.Context, r *recipe.Recipe, operation shared.Operation, s stores, p printer.Printer, extraOpts ...installer.Option) (plannedRecipe, error) {
+	recorder := summary.NewRecorder(p)
+
+	opts := []installer.Option{
+		installer.WithObserver(recorder),
+		installer.WithStateStore(s.recipes),
+		installer.WithBackupStore(s.backups),
+		installer.WithContext(ctx),
+		installer.WithOutputLimits(shell.OutputLimits{Lines: MaxOutputLines, Bytes: MaxOutputBytes}),
+	}
+	if s.elevator != nil {
+		opts = append(opts, installer.WithElevator(s.elevator))
+	}
+	if !Yes {
+		opts = append(opts, installer.WithConfirmation(confirmPrivileged(os.Stdin, os.Stderr, CI || !printer.IsTerminal(os.Stdin))))
+	}
+	opts = append(opts, extraOpts...)
+	if CI {
+		opts = append(opts, installer.WithFailFast())
+	}
+	if DryRun {
+		opts = append(opts, installer.WithDryRun())
+	}
+	if s.tracker != nil {
+		opts = append(opts, installer.WithChangeTracking(s.tracker))
+	}
+	if Home != "" || RootPrefix != "" || AsUser != "" {
+		opts = append(opts, installer.WithTarget(target()))
+	}
+
+	i, err := installer.New(r, opts...)
 	if err != nil {
-		return nil, err
+		return plannedRecipe{}, exitcode.New(exitcode.Validation, err)
+	}
+
+	return plannedRecipe{
+		name:      r.Metadata.Name,
+		operation: operation,
+		installer: i,
+		recorder:  recorder,
+	}, nil
+}
+
+// sources stores sources of loaded recipes, which are saved in recipe states
+type sources map[*recipe.Recipe]state.Source
+
+// loadRecipes loads recipes from the official repository, files and URLs, in this order
+func loadRecipes(recipeNames, URLs, filePaths []string) ([]*recipe.Recipe, sources, error) {
+	var recipes []*recipe.Recipe
+	srcs := sources{}
+
+	for _, name := range recipeNames {
+		r, err := recipe.FromRepository(name, http.DefaultClient)
+		if err != nil {
+			return nil, nil, err
+		}
+		recipes = append(recipes, r)
+		srcs[r] = state.Source{Name: name}
+	}
+
+	for _, filePath := range filePaths {
+		r, err := recipe.FromPath(filePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		recipes = append(recipes, r)
+		srcs[r] = state.Source{Path: absPath(filePath)}
+	}
+
+	for _, URL := range URLs {
+		r, _, err := recipe.FromURL(URL, http.DefaultClient)
+		if err != nil {
+			return nil, nil, err
+		}
+		recipes = append(recipes, r)
+		srcs[r] = state.Source{URL: URL}
 	}
 
-	i, err := installer.New(r, p)
+	return recipes, srcs, nil
+}
+
+// absPath returns an absolute path, so that the recipe can be loaded again from any directory
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
 	if err != nil {
-		return nil, err
+		return path
 	}
 
-	return i, nil
+	return abs
+}
+
+// loadDependencies loads missing dependencies of recipes from the official repository.
+// Dependencies, which are already installed, are skipped and their names are returned separately.
+func loadDependencies(recipes []*recipe.Recipe, srcs sources, store state.Store, p printer.Printer) ([]*recipe.Recipe, []string, error) {
+	// handled stores keys of dependencies, which have been already skipped or loaded
+	handled := make(map[string]bool)
+	var skipped []string
+
+	for {
+		var loaded bool
+
+		for _, name := range recipe.MissingDependencies(recipes) {
+			key := state.Key(name)
+			if handled[key] {
+				continue
+			}
+			handled[key] = true
+
+			record, err := store.Get(name)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "while loading state of dependency `%s`", name)
+			}
+
+			if record != nil && record.Status == state.StatusInstalled {
+				p.RecipeSkipped(name, alreadyInstalledReason)
+				skipped = append(skipped, name)
+				continue
+			}
+
+			r, err := recipe.FromRepository(name, http.DefaultClient)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "while loading dependency `%s`", name)
+			}
+
+			recipes = append(recipes, r)
+			srcs[r] = state.Source{Name: name}
+			loaded = true
+		}
+
+		if !loaded {
+			return recipes, skipped, nil
+		}
+	}
 }
@@ -1,13 +1,20 @@
 package recipecmd_test
 
 import (
+	"encoding/json"
+	"github.com/fatih/color"
+	"github.com/pkosiec/terminer/internal/exitcode"
 	"github.com/pkosiec/terminer/internal/recipecmd"
+	"github.com/pkosiec/terminer/internal/summary"
+	"github.com/pkosiec/terminer/internal/testutil"
 	"github.com/pkosiec/terminer/pkg/shared"
+	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"testing"
 )
 
@@ -15,17 +22,21 @@ const ValidRecipePath = "./testdata/valid-recipe.yaml"
 const InvalidRecipePath = "./testdata/invalid-recipe.yaml"
 const EmptyRecipePath = "./testdata/empty-recipe.yaml"
 const FailingRecipePath = "./testdata/failing-recipe.yaml"
+const BaseRecipePath = "./testdata/base-recipe.yaml"
+const DependentRecipePath = "./testdata/dependent-recipe.yaml"
+const PrivilegedRecipePath = "./testdata/privileged-recipe.yaml"
 
 func TestRun(t *testing.T) {
-	filePathBak := recipecmd.FilePath
-	urlBak := recipecmd.URL
+	filePathsBak := recipecmd.FilePaths
+	urlsBak := recipecmd.URLs
+	testutil.SetStateDir(t)
 
 	t.Run("Install", func(t *testing.T) {
 		installFn := recipecmd.Run(shared.OperationInstall)
 
 		t.Run("Valid recipe from path", func(t *testing.T) {
-			recipecmd.FilePath = ValidRecipePath
-			recipecmd.URL = ""
+			recipecmd.FilePaths = []string{ValidRecipePath}
+			recipecmd.URLs = nil
 			err := installFn(nil, []string{})
 
 			assert.NoError(t, err)
@@ -35,36 +46,38 @@ func TestRun(t *testing.T) {
 			server := setupRemoteRecipeServer(t, "./testdata/valid-recipe.yaml")
 			defer server.Close()
 
-			recipecmd.FilePath = ""
-			recipecmd.URL = server.URL
+			recipecmd.FilePaths = nil
+			recipecmd.URLs = []string{server.URL}
 			err := installFn(nil, []string{})
 
 			require.NoError(t, err)
 		})
 
 		t.Run("Invalid Recipe from path", func(t *testing.T) {
-			recipecmd.FilePath = InvalidRecipePath
-			recipecmd.URL = ""
+			recipecmd.FilePaths = []string{InvalidRecipePath}
+			recipecmd.URLs = nil
 			err := installFn(nil, []string{})
 
 			assert.Error(t, err)
+			assert.Equal(t, exitcode.Load, exitcode.FromError(err))
 		})
 
 		t.Run("Invalid path", func(t *testing.T) {
 			path := "./testdata/file.yaml"
 
-			recipecmd.FilePath = path
-			recipecmd.URL = ""
+			recipecmd.FilePaths = []string{path}
+			recipecmd.URLs = nil
 			err := installFn(nil, []string{})
 
 			assert.Error(t, err)
+			assert.Equal(t, exitcode.Load, exitcode.FromError(err))
 		})
 
 		t.Run("Invalid URL", func(t *testing.T) {
 			url := "https://example.com/foo/bar"
 
-			recipecmd.FilePath = ""
-			recipecmd.URL = url
+			recipecmd.FilePaths = nil
+			recipecmd.URLs = []string{url}
 			err := installFn(nil, []string{})
 
 			assert.Error(t, err)
@@ -73,29 +86,142 @@ func TestRun(t *testing.T) {
 		t.Run("Failing Recipe", func(t *testing.T) {
 			path := FailingRecipePath
 
-			recipecmd.FilePath = path
-			recipecmd.URL = ""
+			recipecmd.FilePaths = []string{path}
+			recipecmd.URLs = nil
 
 			err := installFn(nil, []string{})
 
-			// should not exit with error. It should print error instead
-			// TODO: Test printing error
-			assert.NoError(t, err)
+			require.Error(t, err)
+			assert.Equal(t, exitcode.StepFailure, exitcode.FromError(err))
+			assert.True(t, exitcode.IsSilent(err))
 		})
 
 		t.Run("Empty Recipe", func(t *testing.T) {
 			path := EmptyRecipePath
 
-			recipecmd.FilePath = path
-			recipecmd.URL = ""
+			recipecmd.FilePaths = []string{path}
+			recipecmd.URLs = nil
 			err := installFn(nil, []string{})
 
 			assert.Error(t, err)
+			assert.Equal(t, exitcode.Validation, exitcode.FromError(err))
+		})
+
+		t.Run("Invalid output format", func(t *testing.T) {
+			recipecmd.FilePaths = []string{ValidRecipePath}
+			recipecmd.URLs = nil
+			recipecmd.Output = "xml"
+			defer func() {
+				recipecmd.Output = recipecmd.OutputText
+			}()
+
+			err := installFn(nil, []string{})
+
+			require.Error(t, err)
+			assert.Equal(t, exitcode.Validation, exitcode.FromError(err))
+			assert.Contains(t, err.Error(), "Invalid output format")
+		})
+
+		t.Run("JSON output", func(t *testing.T) {
+			recipecmd.FilePaths = []string{ValidRecipePath}
+			recipecmd.URLs = nil
+			recipecmd.Output = recipecmd.OutputJSON
+			defer func() {
+				recipecmd.Output = recipecmd.OutputText
+			}()
+
+			err := installFn(nil, []string{})
+
+			assert.NoError(t, err)
+		})
+
+		t.Run("CI mode", func(t *testing.T) {
+			summaryPath := filepath.Join(t.TempDir(), "summary.json")
+
+			recipecmd.FilePaths = []string{FailingRecipePath}
+			recipecmd.URLs = nil
+			recipecmd.CI = true
+			recipecmd.SummaryPath = summaryPath
+			noColorBak := color.NoColor
+			defer func() {
+				recipecmd.CI = false
+				recipecmd.SummaryPath = recipecmd.DefaultSummaryPath
+				color.NoColor = noColorBak
+			}()
+
+			err := installFn(nil, []string{})
+			require.Error(t, err)
+
+			bytes, err := ioutil.ReadFile(summaryPath)
+			require.NoError(t, err)
+
+			var summaries []summary.Summary
+			err = json.Unmarshal(bytes, &summaries)
+			require.NoError(t, err)
+			require.Len(t, summaries, 1)
+			s := summaries[0]
+			assert.Equal(t, summary.StatusFailed, s.Status)
+			assert.Equal(t, exitcode.StepFailure, s.ExitCode)
+			require.Len(t, s.Stages, 1)
+			require.Len(t, s.Stages[0].Steps, 1)
+			assert.Equal(t, 1, s.Stages[0].Steps[0].Errors[0].ExitCode)
 		})
 
-		t.Run("Too many parameters", func(t *testing.T) {
-			recipecmd.FilePath = ""
-			recipecmd.URL = ""
+		t.Run("Multiple recipes", func(t *testing.T) {
+			summaries := runInCI(t, installFn, DependentRecipePath, BaseRecipePath)
+
+			require.Len(t, summaries, 2)
+			assert.Equal(t, "Base", summaries[0].Recipe)
+			assert.Equal(t, "Dependent", summaries[1].Recipe)
+		})
+
+		t.Run("Already installed dependency", func(t *testing.T) {
+			summaries := runInCI(t, installFn, DependentRecipePath)
+
+			require.Len(t, summaries, 1)
+			assert.Equal(t, "Dependent", summaries[0].Recipe)
+		})
+
+		t.Run("Privileged commands", func(t *testing.T) {
+			recipecmd.FilePaths = []string{PrivilegedRecipePath}
+			recipecmd.URLs = nil
+			recipecmd.CI = true
+			recipecmd.SummaryPath = filepath.Join(t.TempDir(), "summary.json")
+			noColorBak := color.NoColor
+			defer func() {
+				recipecmd.CI = false
+				recipecmd.SummaryPath = recipecmd.DefaultSummaryPath
+				color.NoColor = noColorBak
+			}()
+
+			err := installFn(nil, []string{})
+			require.Error(t, err)
+			assert.Equal(t, exitcode.NotConfirmed, exitcode.FromError(err))
+			assert.Contains(t, err.Error(), "use the --yes flag")
+
+			recipecmd.Yes = true
+			defer func() {
+				recipecmd.Yes = false
+			}()
+
+			summaries := runInCI(t, installFn, PrivilegedRecipePath)
+			require.Len(t, summaries, 1)
+			assert.Equal(t, summary.StatusSuccess, summaries[0].Status)
+		})
+
+		t.Run("Dependency cycle", func(t *testing.T) {
+			recipecmd.FilePaths = []string{"./testdata/cyclic-recipe-a.yaml", "./testdata/cyclic-recipe-b.yaml"}
+			recipecmd.URLs = nil
+			err := installFn(nil, []string{})
+
+			require.Error(t, err)
+			assert.Equal(t, exitcode.Validation, exitcode.FromError(err))
+			assert.Contains(t, err.Error(), "Cyclic A -> Cyclic B -> Cyclic A")
+		})
+
+		t.Run("Unknown recipes", func(t *testing.T) {
+			recipecmd.FilePaths = nil
+			recipecmd.URLs = nil
 			err := installFn(nil, []string{"test", "test2"})
 
 			assert.Error(t, err)
@@ -106,16 +232,16 @@ func TestRun(t *testing.T) {
 		rollbackFn := recipecmd.Run(shared.OperationRollback)
 
 		t.Run("Valid recipe", func(t *testing.T) {
-			recipecmd.FilePath = ValidRecipePath
-			recipecmd.URL = ""
+			recipecmd.FilePaths = []string{ValidRecipePath}
+			recipecmd.URLs = nil
 			err := rollbackFn(nil, []string{})
 
 			assert.NoError(t, err)
 		})
 
 		t.Run("Invalid Recipe", func(t *testing.T) {
-			recipecmd.FilePath = InvalidRecipePath
-			recipecmd.URL = ""
+			recipecmd.FilePaths = []string{InvalidRecipePath}
+			recipecmd.URLs = nil
 			err := rollbackFn(nil, []string{})
 
 			assert.Error(t, err)
@@ -124,8 +250,8 @@ func TestRun(t *testing.T) {
 		t.Run("Invalid Path", func(t *testing.T) {
 			path := "./testdata/file.yaml"
 
-			recipecmd.FilePath = path
-			recipecmd.URL = ""
+			recipecmd.FilePaths = []string{path}
+			recipecmd.URLs = nil
 			err := rollbackFn(nil, []string{})
 
 			assert.Error(t, err)
@@ -134,8 +260,8 @@ func TestRun(t *testing.T) {
 		t.Run("Invalid URL", func(t *testing.T) {
 			url := "https://example.com/foo/bar"
 
-			recipecmd.FilePath = ""
-			recipecmd.URL = url
+			recipecmd.FilePaths = nil
+			recipecmd.URLs = []string{url}
 			err := rollbackFn(nil, []string{})
 
 			assert.Error(t, err)
@@ -144,36 +270,70 @@ func TestRun(t *testing.T) {
 		t.Run("Failing Recipe", func(t *testing.T) {
 			path := FailingRecipePath
 
-			recipecmd.FilePath = path
-			recipecmd.URL = ""
+			recipecmd.FilePaths = []string{path}
+			recipecmd.URLs = nil
 			err := rollbackFn(nil, []string{})
 
-			// should not exit with error. It should print error instead
-			// TODO: Test printing error
-			assert.NoError(t, err)
+			require.Error(t, err)
+			assert.Equal(t, exitcode.RollbackFailure, exitcode.FromError(err))
 		})
 
 		t.Run("Empty Recipe", func(t *testing.T) {
 			path := EmptyRecipePath
 
-			recipecmd.FilePath = path
-			recipecmd.URL = ""
+			recipecmd.FilePaths = []string{path}
+			recipecmd.URLs = nil
 			err := rollbackFn(nil, []string{})
 
 			assert.Error(t, err)
 		})
 
-		t.Run("Too many parameters", func(t *testing.T) {
-			recipecmd.FilePath = ""
-			recipecmd.URL = ""
+		t.Run("Multiple recipes", func(t *testing.T) {
+			summaries := runInCI(t, rollbackFn, BaseRecipePath, DependentRecipePath)
+
+			require.Len(t, summaries, 2)
+			assert.Equal(t, "Dependent", summaries[0].Recipe)
+			assert.Equal(t, "Base", summaries[1].Recipe)
+		})
+
+		t.Run("Unknown recipes", func(t *testing.T) {
+			recipecmd.FilePaths = nil
+			recipecmd.URLs = nil
 			err := rollbackFn(nil, []string{"test", "test2"})
 
 			assert.Error(t, err)
 		})
 	})
 
-	recipecmd.FilePath = filePathBak
-	recipecmd.URL = urlBak
+	recipecmd.FilePaths = filePathsBak
+	recipecmd.URLs = urlsBak
+}
+
+// runInCI runs a successful operation for recipes from given paths in CI mode and returns summaries of all recipes
+func runInCI(t *testing.T, fn func(cmd *cobra.Command, args []string) error, paths ...string) []summary.Summary {
+	summaryPath := filepath.Join(t.TempDir(), "summary.json")
+
+	recipecmd.FilePaths = paths
+	recipecmd.URLs = nil
+	recipecmd.CI = true
+	recipecmd.SummaryPath = summaryPath
+	noColorBak := color.NoColor
+	defer func() {
+		recipecmd.CI = false
+		recipecmd.SummaryPath = recipecmd.DefaultSummaryPath
+		color.NoColor = noColorBak
+	}()
+
+	err := fn(nil, []string{})
+	require.NoError(t, err)
+
+	bytes, err := ioutil.ReadFile(summaryPath)
+	require.NoError(t, err)
+
+	var summaries []summary.Summary
+	err = json.Unmarshal(bytes, &summaries)
+	require.NoError(t, err)
+	return summaries
 }
 
 func setupRemoteRecipeServer(t *testing.T, recipePath string) *httptest.Server {
@@ -0,0 +1,282 @@
+package recipecmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/pkosiec/terminer/internal/junit"
+	"github.com/pkosiec/terminer/internal/printer"
+	"github.com/pkosiec/terminer/internal/summary"
+	"github.com/pkosiec/terminer/pkg/fstrack"
+	"github.com/pkosiec/terminer/pkg/path"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shared"
+	"github.com/pkosiec/terminer/pkg/state"
+	"github.com/spf13/cobra"
+)
+
+// unsharedEnv is set for a test process, which has been already started in new namespaces
+const unsharedEnv = "TERMINER_UNSHARED"
+
+// junitSuiteName is a name of the test suite in JUnit reports
+const junitSuiteName = "terminer"
+
+// residualTestName is a name of the test case, which checks changes left after rollback
+const residualTestName = "rollback leaves system unchanged"
+
+// Test handles the test command. It installs and reverts recipes with a throwaway home directory and state,
+// and fails if the rollback didn't revert all changes made by the installation.
+func Test(cmd *cobra.Command, args []string) error {
+	if cmd != nil {
+		// Errors returned from now on are not related to command usage
+		cmd.SilenceUsage = true
+	}
+
+	if Unshare && os.Getenv(unsharedEnv) == "" {
+		return runUnshared()
+	}
+
+	roots, err := expandRoots(TrackRoots)
+	if err != nil {
+		return err
+	}
+
+	dir, err := ioutil.TempDir("", "terminer-test-")
+	if err != nil {
+		return errors.Wrap(err, "while creating test directory")
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	home := filepath.Join(dir, "home")
+	err = os.Mkdir(home, 0700)
+	if err != nil {
+		return errors.Wrap(err, "while creating test home directory")
+	}
+
+	if Unshare {
+		err = isolateHome(home)
+		if err != nil {
+			return err
+		}
+	}
+
+	restoreEnv := setTestEnv(home, filepath.Join(dir, "state"))
+	defer restoreEnv()
+
+	tracker := fstrack.New(append([]string{home}, roots...))
+	return execute(func(ctx context.Context, args []string, s stores, p printer.Printer) ([]summary.Summary, error) {
+		return testRecipes(ctx, args, s, p, tracker, home)
+	})(cmd, args)
+}
+
+// testRecipes installs recipes with their dependencies and reverts them in reverse order.
+// Files are snapshotted before the installation, after it and after the rollback.
+// Changed files in the test home directory are reported with paths relative to the home directory.
+func testRecipes(ctx context.Context, args []string, s stores, p printer.Printer, tracker *fstrack.Tracker, home string) ([]summary.Summary, error) {
+	recipes, srcs, err := loadRecipes(args, URLs, FilePaths)
+	if err != nil {
+		return failedSummaries(p, exitcode.New(exitcode.Load, err))
+	}
+
+	recipes, _, err = loadDependencies(recipes, srcs, s.recipes, p)
+	if err != nil {
+		return failedSummaries(p, exitcode.New(exitcode.Load, err))
+	}
+
+	recipes, err = recipe.SortByDependencies(recipes)
+	if err != nil {
+		return failedSummaries(p, exitcode.New(exitcode.Validation, err))
+	}
+
+	installPlan, err := newTestPlan(ctx, recipes, shared.OperationInstall, s, p)
+	if err != nil {
+		return failedSummaries(p, err)
+	}
+
+	before := tracker.Snapshot(nil)
+	summaries, installErr := runPlan(ctx, installPlan)
+	afterInstall := tracker.Snapshot(before)
+
+	// Recipes are reverted also if their installation failed, to clean up partial changes
+	var installed []*recipe.Recipe
+	for i := len(installPlan) - 1; i >= 0; i-- {
+		if installPlan[i].status != "" {
+			installed = append(installed, recipes[i])
+		}
+	}
+
+	rollbackPlan, err := newTestPlan(ctx, installed, shared.OperationRollback, s, p)
+	if err != nil {
+		return failedSummaries(p, err)
+	}
+
+	rollbackSummaries, rollbackErr := runPlan(ctx, rollbackPlan)
+	summaries = append(summaries, rollbackSummaries...)
+	afterRollback := tracker.Snapshot(afterInstall)
+
+	result := printer.TestResult{
+		Changed:  len(fstrack.Diff(0, 0, before, afterInstall)),
+		Residual: fstrack.Diff(0, 0, before, afterRollback),
+	}
+	for i, change := range result.Residual {
+		if rel, err := filepath.Rel(home, change.Path); err == nil && !strings.HasPrefix(rel, "..") {
+			result.Residual[i].Path = filepath.Join("~", rel)
+		}
+	}
+	p.TestResult(result)
+
+	if JUnitPath != "" {
+		err := junit.Write(JUnitPath, junitSuite(installPlan, rollbackPlan, summaries, result))
+		if err != nil {
+			return summaries, err
+		}
+	}
+
+	switch {
+	case installErr != nil:
+		return summaries, installErr
+	case rollbackErr != nil:
+		return summaries, rollbackErr
+	case len(result.Residual) > 0:
+		// The residual changes have been already printed
+		return summaries, exitcode.NewSilent(exitcode.ResidualChanges, fmt.Errorf("Rollback left %d change(s)", len(result.Residual)))
+	}
+
+	return summaries, nil
+}
+
+func newTestPlan(ctx context.Context, recipes []*recipe.Recipe, operation shared.Operation, s stores, p printer.Printer) ([]plannedRecipe, error) {
+	plan := make([]plannedRecipe, 0, len(recipes))
+	for _, r := range recipes {
+		item, err := newPlannedRecipe(ctx, r, operation, s, p)
+		if err != nil {
+			return nil, err
+		}
+
+		plan = append(plan, item)
+	}
+
+	return plan, nil
+}
+
+// junitSuite returns a test case for every planned operation, and a test case for changes left after rollback
+func junitSuite(installPlan, rollbackPlan []plannedRecipe, summaries []summary.Summary, result printer.TestResult) junit.TestSuite {
+	plan := make([]plannedRecipe, 0, len(installPlan)+len(rollbackPlan))
+	plan = append(plan, installPlan...)
+	plan = append(plan, rollbackPlan...)
+
+	// Summaries are returned only for operations, which have been run, in the order of the plan
+	var cases []junit.TestCase
+	next := 0
+	for _, item := range plan {
+		c := junit.TestCase{Name: string(item.operation), ClassName: item.name}
+		if item.status == "" {
+			c.Skipped = &junit.Skipped{Message: "previous recipe operation failed"}
+			cases = append(cases, c)
+			continue
+		}
+
+		if next < len(summaries) {
+			s := summaries[next]
+			next++
+
+			c.Time = junit.Seconds(s.Duration)
+			if s.Status == summary.StatusFailed {
+				c.Failure = &junit.Failure{Message: s.Error}
+			}
+		}
+
+		cases = append(cases, c)
+	}
+
+	residual := junit.TestCase{Name: residualTestName, ClassName: junitSuiteName}
+	if len(result.Residual) > 0 {
+		lines := make([]string, 0, len(result.Residual))
+		for _, change := range result.Residual {
+			lines = append(lines, fmt.Sprintf("%s %s", change.Type, change.Path))
+		}
+
+		residual.Failure = &junit.Failure{
+			Message: fmt.Sprintf("Rollback left %d change(s)", len(result.Residual)),
+			Details: strings.Join(lines, "\n"),
+		}
+	}
+	cases = append(cases, residual)
+
+	return junit.NewSuite(junitSuiteName, cases)
+}
+
+// setTestEnv replaces the home and state directories for the test, and returns a function, which restores them
+func setTestEnv(home, stateDir string) func() {
+	vars := map[string]string{"HOME": home, state.DirEnv: stateDir}
+
+	restore := make(map[string]*string, len(vars))
+	for name, value := range vars {
+		if old, ok := os.LookupEnv(name); ok {
+			restore[name] = &old
+		} else {
+			restore[name] = nil
+		}
+
+		_ = os.Setenv(name, value)
+	}
+
+	return func() {
+		for name, old := range restore {
+			if old == nil {
+				_ = os.Unsetenv(name)
+				continue
+			}
+
+			_ = os.Setenv(name, *old)
+		}
+	}
+}
+
+// runUnshared runs the same test command again in new user and mount namespaces
+func runUnshared() error {
+	self, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "while getting path of the executable")
+	}
+
+	args := append([]string{"--user", "--map-root-user", "--mount", "--fork", self}, os.Args[1:]...)
+	c := exec.Command("unshare", args...)
+	c.Env = append(os.Environ(), unsharedEnv+"=1")
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	err = c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// The error has been already printed by the test process
+		return exitcode.NewSilent(exitErr.ExitCode(), err)
+	}
+	if err != nil {
+		return errors.Wrap(err, "while starting test in new namespaces")
+	}
+
+	return nil
+}
+
+func expandRoots(roots []string) ([]string, error) {
+	expanded := make([]string, 0, len(roots))
+	for _, root := range roots {
+		p, err := path.ExpandHome(root)
+		if err != nil {
+			return nil, err
+		}
+
+		expanded = append(expanded, absPath(p))
+	}
+
+	return expanded, nil
+}
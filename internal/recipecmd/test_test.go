@@ -0,0 +1,77 @@
+package recipecmd_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkosiec/terminer/internal/exitcode"
+	"github.com/pkosiec/terminer/internal/recipecmd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const LeakingRecipePath = "./testdata/leaking-recipe.yaml"
+
+func TestTest(t *testing.T) {
+	homeBak := os.Getenv("HOME")
+
+	t.Run("Rollback reverts all changes", func(t *testing.T) {
+		junitPath := filepath.Join(t.TempDir(), "report.xml")
+
+		err := testRecipes(junitPath, ValidRecipePath)
+		require.NoError(t, err)
+		assert.Equal(t, homeBak, os.Getenv("HOME"))
+
+		report, err := ioutil.ReadFile(junitPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(report), `<testsuite name="terminer" tests="3" failures="0" skipped="0"`)
+		assert.Contains(t, string(report), `<testcase name="installation" classname="Recipe"`)
+		assert.Contains(t, string(report), `<testcase name="rollback" classname="Recipe"`)
+	})
+
+	t.Run("Residual changes", func(t *testing.T) {
+		junitPath := filepath.Join(t.TempDir(), "report.xml")
+
+		err := testRecipes(junitPath, LeakingRecipePath)
+		require.Error(t, err)
+		assert.Equal(t, exitcode.ResidualChanges, exitcode.FromError(err))
+		assert.Equal(t, homeBak, os.Getenv("HOME"))
+
+		_, err = os.Stat(filepath.Join(homeBak, ".leaking-recipe-history"))
+		assert.True(t, os.IsNotExist(err))
+
+		report, err := ioutil.ReadFile(junitPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(report), `failures="1"`)
+		assert.Contains(t, string(report), `<failure message="Rollback left 1 change(s)">created ~/.leaking-recipe-history</failure>`)
+	})
+
+	t.Run("Failed installation", func(t *testing.T) {
+		junitPath := filepath.Join(t.TempDir(), "report.xml")
+
+		err := testRecipes(junitPath, FailingRecipePath)
+		require.Error(t, err)
+		assert.Equal(t, exitcode.StepFailure, exitcode.FromError(err))
+
+		report, err := ioutil.ReadFile(junitPath)
+		require.NoError(t, err)
+		assert.Contains(t, string(report), `<testcase name="installation" classname="Recipe"`)
+		assert.Contains(t, string(report), `<failure message=`)
+	})
+}
+
+func testRecipes(junitPath string, paths ...string) error {
+	recipecmd.FilePaths = paths
+	recipecmd.URLs = nil
+	recipecmd.JUnitPath = junitPath
+	recipecmd.Output = recipecmd.OutputPlain
+	defer func() {
+		recipecmd.FilePaths = nil
+		recipecmd.JUnitPath = ""
+		recipecmd.Output = recipecmd.OutputText
+	}()
+
+	return recipecmd.Test(nil, nil)
+}
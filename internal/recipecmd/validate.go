@@ -1,16 +1,49 @@
 package recipecmd
 
 import (
+	"runtime"
+
 	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/exitcode"
 	"github.com/spf13/cobra"
 )
 
 // ValidateArgs validates arguments for commands related to recipes
 func ValidateArgs(_ *cobra.Command, args []string) error {
-	if (len(args) == 0 || len(args) > 1) && URL == "" && FilePath == "" {
-		return errors.New(`This command requires single recipe name from the official repository.
-You can also use additional flags to load recipe from disk or URL.
-`)
+	if len(args) == 0 && len(URLs) == 0 && len(FilePaths) == 0 {
+		return exitcode.New(exitcode.Validation, errors.New(`This command requires at least one recipe name from the official repository.
+You can also use additional flags to load recipes from disk or URL.
+`))
+	}
+
+	return validateOperationFlags()
+}
+
+// ValidateTestArgs validates arguments for the test command
+func ValidateTestArgs(_ *cobra.Command, args []string) error {
+	if len(args) == 0 && len(URLs) == 0 && len(FilePaths) == 0 {
+		return exitcode.New(exitcode.Validation, errors.New("This command requires at least one recipe name, path or URL"))
+	}
+
+	if Unshare && runtime.GOOS != "linux" {
+		return exitcode.New(exitcode.Validation, errors.New("The --unshare flag is supported only on Linux"))
+	}
+
+	return nil
+}
+
+// ValidateApplyArgs validates arguments for the apply command
+func ValidateApplyArgs(_ *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return exitcode.New(exitcode.Validation, errors.New("This command requires a single profile path"))
+	}
+
+	return validateOperationFlags()
+}
+
+func validateOperationFlags() error {
+	if len(TrackRoots) > 0 && !TrackChanges {
+		return exitcode.New(exitcode.Validation, errors.New("The --track-root flag requires --track-changes"))
 	}
 
 	return nil
@@ -8,21 +8,23 @@ import (
 )
 
 func TestValidateArgs(t *testing.T) {
-	filePathBak := recipecmd.FilePath
-	urlBak := recipecmd.URL
+	filePathsBak := recipecmd.FilePaths
+	urlsBak := recipecmd.URLs
+	trackRootsBak := recipecmd.TrackRoots
 
 	testCases := []struct {
-		FilePath    string
-		URL         string
+		FilePaths   []string
+		URLs        []string
+		TrackRoots  []string
 		args        []string
 		expectedErr bool
 	}{
 		{
-			FilePath:    "./test.md",
+			FilePaths:   []string{"./test.md"},
 			expectedErr: false,
 		},
 		{
-			URL:         "https://example.com",
+			URLs:        []string{"https://example.com"},
 			expectedErr: false,
 		},
 		{
@@ -31,17 +33,29 @@ func TestValidateArgs(t *testing.T) {
 		},
 		{
 			args:        []string{"test-recipe", "test-recipe2"},
+			expectedErr: false,
+		},
+		{
+			args:        []string{"test-recipe"},
+			FilePaths:   []string{"./test.md", "./test2.md"},
+			URLs:        []string{"https://example.com"},
+			expectedErr: false,
+		},
+		{
 			expectedErr: true,
 		},
 		{
+			args:        []string{"test-recipe"},
+			TrackRoots:  []string{"~/.config"},
 			expectedErr: true,
 		},
 	}
 
 	for tN, tC := range testCases {
 		t.Run(fmt.Sprintf("Test Case %d", tN), func(t *testing.T) {
-			recipecmd.URL = tC.URL
-			recipecmd.FilePath = tC.FilePath
+			recipecmd.URLs = tC.URLs
+			recipecmd.FilePaths = tC.FilePaths
+			recipecmd.TrackRoots = tC.TrackRoots
 			err := recipecmd.ValidateArgs(nil, tC.args)
 
 			if tC.expectedErr {
@@ -52,6 +66,7 @@ func TestValidateArgs(t *testing.T) {
 		})
 	}
 
-	recipecmd.FilePath = filePathBak
-	recipecmd.URL = urlBak
+	recipecmd.FilePaths = filePathsBak
+	recipecmd.URLs = urlsBak
+	recipecmd.TrackRoots = trackRootsBak
 }
@@ -0,0 +1,73 @@
+package runlog
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/pkg/rundir"
+)
+
+const logsDirName = "logs"
+const logExtension = ".log"
+
+// Run describes a log file of a single recipe operation
+type Run struct {
+	Recipe string
+	ID     string
+	Path   string
+}
+
+// Store keeps logs of recipe operations in a state directory.
+// Logs of every recipe are kept in a separate directory, in files named after the run ID.
+type Store struct {
+	dir rundir.Dir
+}
+
+// NewStore creates a new Store in given state directory
+func NewStore(stateDir string) *Store {
+	return &Store{dir: rundir.Dir{Path: filepath.Join(stateDir, logsDirName), Kind: "logs", Name: "Run", Ext: logExtension}}
+}
+
+// Create creates a new log file for a given recipe
+func (s *Store) Create(recipe string, startedAt time.Time) (io.WriteCloser, Run, error) {
+	var file *os.File
+	entry, err := s.dir.Create(recipe, startedAt, func(path string) error {
+		var err error
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		return err
+	})
+	if err != nil {
+		return nil, Run{}, errors.Wrap(err, "while creating log file")
+	}
+
+	return file, Run(entry), nil
+}
+
+// List returns runs of a given recipe, or of all recipes if the recipe is empty.
+// Runs are sorted by recipe and then from the oldest to the newest.
+func (s *Store) List(recipe string) ([]Run, error) {
+	entries, err := s.dir.List(recipe)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]Run, 0, len(entries))
+	for _, entry := range entries {
+		runs = append(runs, Run(entry))
+	}
+
+	return runs, nil
+}
+
+// Get returns a run of a given recipe. If the ID is empty, it returns the latest run.
+func (s *Store) Get(recipe, id string) (Run, error) {
+	entry, err := s.dir.Get(recipe, id)
+	if err != nil {
+		return Run{}, err
+	}
+
+	return Run(entry), nil
+}
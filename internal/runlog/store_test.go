@@ -0,0 +1,55 @@
+package runlog_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkosiec/terminer/internal/runlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	t.Run("Create and get", func(t *testing.T) {
+		store := runlog.NewStore(t.TempDir())
+		startedAt := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+
+		for i := 0; i < 3; i++ {
+			w, _, err := store.Create("Zsh Starter", startedAt)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+		}
+		w, _, err := store.Create("Fish Starter", startedAt.Add(time.Hour))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+
+		runs, err := store.List("")
+		require.NoError(t, err)
+		require.Len(t, runs, 4)
+		assert.Equal(t, "fish-starter", runs[0].Recipe)
+		assert.Equal(t, "20210701T110000Z", runs[0].ID)
+		assert.Equal(t, "zsh-starter", runs[1].Recipe)
+		assert.Equal(t, "20210701T100000Z", runs[1].ID)
+		assert.Equal(t, "20210701T100000Z-3", runs[3].ID)
+
+		latest, err := store.Get("zsh-starter", "")
+		require.NoError(t, err)
+		assert.Equal(t, "20210701T100000Z-3", latest.ID)
+
+		run, err := store.Get("Zsh Starter", "20210701T100000Z-2")
+		require.NoError(t, err)
+		assert.Equal(t, "20210701T100000Z-2", run.ID)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		store := runlog.NewStore(t.TempDir())
+
+		runs, err := store.List("")
+		require.NoError(t, err)
+		assert.Empty(t, runs)
+
+		_, err = store.Get("zsh-starter", "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "No logs found")
+	})
+}
@@ -0,0 +1,232 @@
+package runlog
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/printer"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shared"
+	"github.com/pkosiec/terminer/pkg/shell"
+)
+
+const timeLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// Tee is a Printer, which writes a complete, uncolored and timestamped log of a recipe operation
+// to a new file in a Store and passes all calls to a next Printer
+type Tee struct {
+	mu    sync.Mutex
+	next  printer.Printer
+	store *Store
+	now   func() time.Time
+	w     io.WriteCloser
+
+	operation   shared.Operation
+	stagesCount int
+	startedAt   time.Time
+}
+
+// NewTee creates a new Tee
+func NewTee(next printer.Printer, store *Store) *Tee {
+	return &Tee{next: next, store: store, now: time.Now}
+}
+
+// SetContext creates a log file for a new recipe operation, so that also errors, which occur before any step,
+// such as a declined confirmation or a failed elevation, are logged
+func (t *Tee) SetContext(operation shared.Operation, recipeName string, stagesCount int) {
+	t.mu.Lock()
+	t.operation = operation
+	t.stagesCount = stagesCount
+	t.startedAt = t.now()
+	t.open(recipeName)
+	t.logf("%s of recipe %q started", operation, recipeName)
+	t.mu.Unlock()
+
+	t.next.SetContext(operation, recipeName, stagesCount)
+}
+
+// Recipe passes recipe details to the next Printer. The log file is already created when the operation starts.
+func (t *Tee) Recipe(r recipe.UnitMetadata) {
+	t.next.Recipe(r)
+}
+
+// Actions passes actions taken while applying a profile to the next Printer. They are printed after all log files are closed.
+func (t *Tee) Actions(actions []printer.Action) {
+	t.next.Actions(actions)
+}
+
+// TestResult passes a result of recipe tests to the next Printer. It is printed after all log files are closed.
+func (t *Tee) TestResult(result printer.TestResult) {
+	t.next.TestResult(result)
+}
+
+// RecipeSkipped passes a skipped recipe to the next Printer. It isn't logged, as it precedes any recipe operation.
+func (t *Tee) RecipeSkipped(name, reason string) {
+	t.next.RecipeSkipped(name, reason)
+}
+
+// Stage logs a start of a stage
+func (t *Tee) Stage(stageIndex int, s recipe.Stage) {
+	t.log("stage [%d/%d] %s", stageIndex+1, t.stagesCount, s.Metadata.Name)
+	t.next.Stage(stageIndex, s)
+}
+
+// StageFinished logs stage duration
+func (t *Tee) StageFinished(duration time.Duration) {
+	t.log("stage finished after %s", formatDuration(duration))
+	t.next.StageFinished(duration)
+}
+
+// Step logs a start of a step
+func (t *Tee) Step(stepIndex, steps int, s recipe.UnitMetadata) {
+	t.log("step [%d/%d] %s", stepIndex+1, steps, s.Name)
+
+	t.next.Step(stepIndex, steps, s)
+}
+
+// StepSkipped logs that the current step has been skipped
+func (t *Tee) StepSkipped(condition string) {
+	t.log("step skipped: condition %s not met", condition)
+	t.next.StepSkipped(condition)
+}
+
+// StepFinished logs step result, exit codes and duration
+func (t *Tee) StepFinished(duration time.Duration, err error) {
+	t.mu.Lock()
+	if err != nil {
+		t.logf("step failed after %s: %s", formatDuration(duration), indent(err.Error()))
+		for _, exitErr := range exitErrors(err) {
+			t.logf("exit code %d: %s", exitErr.ExitCode, exitErr.Command)
+		}
+	} else {
+		t.logf("step finished after %s", formatDuration(duration))
+	}
+	t.mu.Unlock()
+
+	t.next.StepFinished(duration, err)
+}
+
+// Command logs a command
+func (t *Tee) Command(cmd string) {
+	t.log("command: %s", cmd)
+	t.next.Command(cmd)
+}
+
+// CommandFinished logs command duration
+func (t *Tee) CommandFinished(duration time.Duration) {
+	t.log("command finished after %s", formatDuration(duration))
+	t.next.CommandFinished(duration)
+}
+
+// ExecOutput logs command output
+func (t *Tee) ExecOutput(output string) {
+	t.log("stdout: %s", output)
+	t.next.ExecOutput(output)
+}
+
+// ExecError logs command error output
+func (t *Tee) ExecError(output string) {
+	t.log("stderr: %s", output)
+	t.next.ExecError(output)
+}
+
+// ExecTrace logs a full command line of an executed process
+func (t *Tee) ExecTrace(trace string) {
+	t.log("exec: %s", trace)
+	t.next.ExecTrace(trace)
+}
+
+// Interactive logs the beginning and the end of an interactive command, which output is logged as standard output
+func (t *Tee) Interactive(active bool) {
+	if active {
+		t.log("interactive: start")
+	} else {
+		t.log("interactive: end")
+	}
+	t.next.Interactive(active)
+}
+
+// Result logs operation result and duration, and closes the log file
+func (t *Tee) Result(err error) {
+	t.mu.Lock()
+	duration := formatDuration(t.now().Sub(t.startedAt))
+	if err != nil {
+		t.logf("%s failed after %s: %s", t.operation, duration, indent(err.Error()))
+	} else {
+		t.logf("%s succeeded after %s", t.operation, duration)
+	}
+	t.close()
+	t.mu.Unlock()
+
+	t.next.Result(err)
+}
+
+func (t *Tee) open(recipeName string) {
+	t.close()
+
+	w, _, err := t.store.Create(recipeName, t.startedAt)
+	if err != nil {
+		// Logging is best-effort and it shouldn't break the operation
+		fmt.Fprintln(os.Stderr, errors.Wrap(err, "while creating run log").Error())
+		return
+	}
+
+	t.w = w
+}
+
+func (t *Tee) close() {
+	if t.w == nil {
+		return
+	}
+
+	_ = t.w.Close()
+	t.w = nil
+}
+
+func (t *Tee) log(format string, a ...interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.logf(format, a...)
+}
+
+// logf writes a timestamped line to the log file. It has to be called with the mutex held.
+func (t *Tee) logf(format string, a ...interface{}) {
+	if t.w == nil {
+		return
+	}
+
+	_, _ = fmt.Fprintf(t.w, "%s %s\n", t.now().Format(timeLayout), fmt.Sprintf(format, a...))
+}
+
+func exitErrors(err error) []*shell.ExitError {
+	var errs []error
+	if multiErr, ok := errors.Cause(err).(*shell.MultiError); ok {
+		errs = multiErr.Errors
+	} else {
+		errs = []error{err}
+	}
+
+	var exitErrs []*shell.ExitError
+	for _, err := range errs {
+		if exitErr, ok := errors.Cause(err).(*shell.ExitError); ok {
+			exitErrs = append(exitErrs, exitErr)
+		}
+	}
+
+	return exitErrs
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// indent distinguishes continuation lines of multi-line messages from timestamped lines
+func indent(text string) string {
+	return strings.ReplaceAll(text, "\n", "\n    ")
+}
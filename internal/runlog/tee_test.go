@@ -0,0 +1,104 @@
+package runlog_test
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/printer"
+	"github.com/pkosiec/terminer/internal/printer/automock"
+	"github.com/pkosiec/terminer/internal/runlog"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shared"
+	"github.com/pkosiec/terminer/pkg/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTee(t *testing.T) {
+	store := runlog.NewStore(t.TempDir())
+
+	stepErr := errors.Wrap(&shell.ExitError{Command: "exit 3", ExitCode: 3}, "while executing exit 3")
+
+	next := &automock.Printer{}
+	next.On("SetContext", shared.OperationInstall, "Test", 1).Return().Once()
+	next.On("Recipe", mock.Anything).Return().Once()
+	next.On("Stage", 0, mock.Anything).Return().Once()
+	next.On("Step", 0, 1, mock.Anything).Return().Once()
+	next.On("Command", "echo 'Foo'; exit 3").Return().Once()
+	next.On("ExecTrace", "/bin/sh -c 'echo Foo; exit 3'").Return().Once()
+	next.On("ExecOutput", "Foo").Return().Once()
+	next.On("ExecError", "Bar").Return().Once()
+	next.On("StepFinished", 2*time.Second, stepErr).Return().Once()
+	next.On("StageFinished", 3*time.Second).Return().Once()
+	next.On("CommandFinished", time.Second).Return().Once()
+	next.On("Result", stepErr).Return().Once()
+	defer next.AssertExpectations(t)
+
+	var p printer.Printer = runlog.NewTee(next, store)
+
+	p.SetContext(shared.OperationInstall, "Test", 1)
+	p.Recipe(recipe.UnitMetadata{Name: "Test"})
+	p.Stage(0, recipe.Stage{Metadata: recipe.UnitMetadata{Name: "Stage 1"}})
+	p.Step(0, 1, recipe.UnitMetadata{Name: "Step 1"})
+	p.Command("echo 'Foo'; exit 3")
+	p.ExecTrace("/bin/sh -c 'echo Foo; exit 3'")
+	p.ExecOutput("Foo")
+	p.ExecError("Bar")
+	p.CommandFinished(time.Second)
+	p.StepFinished(2*time.Second, stepErr)
+	p.StageFinished(3 * time.Second)
+	p.Result(stepErr)
+
+	run, err := store.Get("test", "")
+	require.NoError(t, err)
+
+	bytes, err := ioutil.ReadFile(run.Path)
+	require.NoError(t, err)
+
+	log := string(bytes)
+	assert.Contains(t, log, `installation of recipe "Test" started`)
+	assert.Contains(t, log, "stage [1/1] Stage 1")
+	assert.Contains(t, log, "command: echo 'Foo'; exit 3")
+	assert.Contains(t, log, "exec: /bin/sh -c 'echo Foo; exit 3'")
+	assert.Contains(t, log, "stdout: Foo")
+	assert.Contains(t, log, "stderr: Bar")
+	assert.Contains(t, log, "exit code 3: exit 3")
+	assert.Contains(t, log, "command finished after 1s")
+	assert.Contains(t, log, "step failed after 2s")
+	assert.Contains(t, log, "stage finished after 3s")
+	assert.Contains(t, log, "installation failed after")
+	assert.NotContains(t, log, "\x1b[")
+
+	timestamp := strings.SplitN(log, " ", 2)[0]
+	_, err = time.Parse("2006-01-02T15:04:05.000Z07:00", timestamp)
+	assert.NoError(t, err)
+}
+
+func TestTee_ErrorBeforeSteps(t *testing.T) {
+	store := runlog.NewStore(t.TempDir())
+	confirmErr := errors.New("Commands with elevated privileges of recipe `Test` have to be confirmed")
+
+	next := &automock.Printer{}
+	next.On("SetContext", shared.OperationInstall, "Test", 1).Return().Once()
+	next.On("Result", confirmErr).Return().Once()
+	defer next.AssertExpectations(t)
+
+	p := runlog.NewTee(next, store)
+	p.SetContext(shared.OperationInstall, "Test", 1)
+	p.Result(confirmErr)
+
+	run, err := store.Get("test", "")
+	require.NoError(t, err)
+
+	bytes, err := ioutil.ReadFile(run.Path)
+	require.NoError(t, err)
+
+	log := string(bytes)
+	assert.Contains(t, log, `installation of recipe "Test" started`)
+	assert.Contains(t, log, "installation failed after")
+	assert.Contains(t, log, "have to be confirmed")
+}
@@ -0,0 +1,7 @@
+package summary
+
+import "time"
+
+func (r *Recorder) SetNowFn(now func() time.Time) {
+	r.now = now
+}
@@ -0,0 +1,340 @@
+package summary
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/internal/printer"
+	"github.com/pkosiec/terminer/pkg/installer"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shared"
+	"github.com/pkosiec/terminer/pkg/state"
+)
+
+// Status is a result of a recipe operation, stage or step
+type Status string
+
+const (
+	// StatusSuccess means that the unit finished without errors
+	StatusSuccess Status = "success"
+
+	// StatusFailed means that the unit failed
+	StatusFailed Status = "failed"
+
+	// StatusSkipped means that the step was skipped as its condition wasn't met
+	StatusSkipped Status = "skipped"
+)
+
+// Summary is a machine-readable report of a recipe operation.
+// Stage and step indexes are indexes in the recipe, regardless of the operation order.
+type Summary struct {
+	Recipe    string           `json:"recipe"`
+	Operation shared.Operation `json:"operation"`
+	Status    Status           `json:"status"`
+	ExitCode  int              `json:"exitCode"`
+	Error     string           `json:"error,omitempty"`
+	StartedAt time.Time        `json:"startedAt"`
+	Duration  state.Duration   `json:"duration"`
+	Stages    []*Stage         `json:"stages"`
+}
+
+// Stage is a report of a single recipe stage
+type Stage struct {
+	Index    int            `json:"index"`
+	Name     string         `json:"name"`
+	Status   Status         `json:"status"`
+	Duration state.Duration `json:"duration"`
+	Steps    []*Step        `json:"steps"`
+}
+
+// Step is a report of a single recipe step
+type Step struct {
+	Index    int            `json:"index"`
+	Name     string         `json:"name"`
+	Status   Status         `json:"status"`
+	Duration state.Duration `json:"duration"`
+	Commands []string       `json:"commands"`
+	Errors   []StepError    `json:"errors,omitempty"`
+}
+
+// StepError describes a failed command of a step
+type StepError struct {
+	Message  string `json:"message"`
+	Command  string `json:"command,omitempty"`
+	ExitCode int    `json:"exitCode"`
+	Stderr   string `json:"stderr,omitempty"`
+}
+
+// Recorder is a Printer, which records a Summary of a recipe operation and passes all calls to a next Printer
+type Recorder struct {
+	next    printer.Printer
+	now     func() time.Time
+	summary Summary
+
+	stagesCount int
+	stageStart  *time.Time
+	stepStart   *time.Time
+}
+
+// NewRecorder creates a new Recorder
+func NewRecorder(next printer.Printer) *Recorder {
+	return &Recorder{next: next, now: time.Now}
+}
+
+// SetContext starts recording a new recipe operation
+func (r *Recorder) SetContext(operation shared.Operation, recipeName string, stagesCount int) {
+	r.summary = Summary{
+		Recipe:    recipeName,
+		Operation: operation,
+		StartedAt: r.now(),
+		Stages:    []*Stage{},
+	}
+	r.stagesCount = stagesCount
+	r.stageStart = nil
+	r.stepStart = nil
+	r.next.SetContext(operation, recipeName, stagesCount)
+}
+
+// Recipe records recipe details
+func (r *Recorder) Recipe(m recipe.UnitMetadata) {
+	r.summary.Recipe = m.Name
+	r.next.Recipe(m)
+}
+
+// Actions passes actions taken while applying a profile to the next Printer
+func (r *Recorder) Actions(actions []printer.Action) {
+	r.next.Actions(actions)
+}
+
+// TestResult passes a result of recipe tests to the next Printer
+func (r *Recorder) TestResult(result printer.TestResult) {
+	r.next.TestResult(result)
+}
+
+// RecipeSkipped passes a skipped recipe to the next Printer
+func (r *Recorder) RecipeSkipped(name, reason string) {
+	r.next.RecipeSkipped(name, reason)
+}
+
+// Stage records a start of a stage
+func (r *Recorder) Stage(stageIndex int, s recipe.Stage) {
+	now := r.now()
+	r.finishStep(now)
+	r.finishStage(now)
+
+	index := stageIndex
+	if r.summary.Operation == shared.OperationRollback {
+		index = r.stagesCount - 1 - stageIndex
+	}
+
+	r.summary.Stages = append(r.summary.Stages, &Stage{
+		Index:  index,
+		Name:   s.Metadata.Name,
+		Status: StatusSuccess,
+		Steps:  []*Step{},
+	})
+	r.stageStart = &now
+	r.next.Stage(stageIndex, s)
+}
+
+// StageFinished records duration of the current stage
+func (r *Recorder) StageFinished(duration time.Duration) {
+	r.finishStep(r.now())
+	if stage := r.currentStage(); stage != nil {
+		stage.Duration = state.Duration(duration)
+	}
+	r.stageStart = nil
+	r.next.StageFinished(duration)
+}
+
+// Step records a start of a step
+func (r *Recorder) Step(stepIndex, steps int, s recipe.UnitMetadata) {
+	now := r.now()
+	r.finishStep(now)
+
+	index := stepIndex
+	if r.summary.Operation == shared.OperationRollback {
+		index = steps - 1 - stepIndex
+	}
+
+	if stage := r.currentStage(); stage != nil {
+		stage.Steps = append(stage.Steps, &Step{
+			Index:    index,
+			Name:     s.Name,
+			Status:   StatusSuccess,
+			Commands: []string{},
+		})
+	}
+	r.stepStart = &now
+	r.next.Step(stepIndex, steps, s)
+}
+
+// StepSkipped records that the current step has been skipped
+func (r *Recorder) StepSkipped(condition string) {
+	if step := r.currentStep(); step != nil {
+		step.Status = StatusSkipped
+	}
+	r.next.StepSkipped(condition)
+}
+
+// StepFinished records duration of the current step
+func (r *Recorder) StepFinished(duration time.Duration, err error) {
+	if step := r.currentStep(); step != nil {
+		step.Duration = state.Duration(duration)
+	}
+	r.stepStart = nil
+	r.next.StepFinished(duration, err)
+}
+
+// Command records a command executed in the current step
+func (r *Recorder) Command(cmd string) {
+	if step := r.currentStep(); step != nil {
+		step.Commands = append(step.Commands, cmd)
+	}
+	r.next.Command(cmd)
+}
+
+// CommandFinished passes command duration to the next Printer
+func (r *Recorder) CommandFinished(duration time.Duration) {
+	r.next.CommandFinished(duration)
+}
+
+// ExecOutput passes command output to the next Printer
+func (r *Recorder) ExecOutput(output string) {
+	r.next.ExecOutput(output)
+}
+
+// ExecError passes command error output to the next Printer
+func (r *Recorder) ExecError(output string) {
+	r.next.ExecError(output)
+}
+
+// ExecTrace passes details of an executed process to the next Printer
+func (r *Recorder) ExecTrace(trace string) {
+	r.next.ExecTrace(trace)
+}
+
+// Interactive passes the beginning and the end of an interactive command to the next Printer
+func (r *Recorder) Interactive(active bool) {
+	r.next.Interactive(active)
+}
+
+// Result passes operation result to the next Printer
+func (r *Recorder) Result(err error) {
+	r.next.Result(err)
+}
+
+// Finish finishes recording with a given operation error and exit code, and returns the Summary
+func (r *Recorder) Finish(err error, exitCode int) Summary {
+	now := r.now()
+	r.finishStep(now)
+	r.finishStage(now)
+
+	r.summary.Duration = state.Duration(now.Sub(r.summary.StartedAt))
+	r.summary.ExitCode = exitCode
+	r.summary.Status = StatusSuccess
+
+	if err != nil {
+		r.summary.Status = StatusFailed
+		r.summary.Error = err.Error()
+		r.recordErrors(err)
+	}
+
+	return r.summary
+}
+
+// WriteJSON writes summaries to a JSON file as an array, regardless of the number of recipes
+func WriteJSON(summaries []Summary, path string) error {
+	if summaries == nil {
+		summaries = []Summary{}
+	}
+
+	bytes, err := json.MarshalIndent(summaries, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "while encoding summary")
+	}
+
+	err = ioutil.WriteFile(path, bytes, 0644)
+	if err != nil {
+		return errors.Wrapf(err, "while writing summary to %s", path)
+	}
+
+	return nil
+}
+
+func (r *Recorder) recordErrors(err error) {
+	var stepErrs []*installer.StepError
+
+	var stepErr *installer.StepError
+	var rollbackErr *installer.RollbackError
+	if errors.As(err, &rollbackErr) {
+		stepErrs = rollbackErr.Errors
+	} else if errors.As(err, &stepErr) {
+		stepErrs = []*installer.StepError{stepErr}
+	}
+
+	for _, stepErr := range stepErrs {
+		step, stage := r.findStep(stepErr.StageIndex, stepErr.StepIndex)
+		if step == nil {
+			continue
+		}
+
+		stage.Status = StatusFailed
+		step.Status = StatusFailed
+		step.Errors = append(step.Errors, StepError{
+			Message:  stepErr.Err.Error(),
+			Command:  stepErr.Command,
+			ExitCode: stepErr.ExitCode,
+			Stderr:   stepErr.Stderr,
+		})
+	}
+}
+
+func (r *Recorder) findStep(stageIndex, stepIndex int) (*Step, *Stage) {
+	for _, stage := range r.summary.Stages {
+		if stage.Index != stageIndex {
+			continue
+		}
+
+		for _, step := range stage.Steps {
+			if step.Index == stepIndex {
+				return step, stage
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func (r *Recorder) finishStage(now time.Time) {
+	if stage := r.currentStage(); stage != nil && r.stageStart != nil {
+		stage.Duration = state.Duration(now.Sub(*r.stageStart))
+	}
+	r.stageStart = nil
+}
+
+func (r *Recorder) finishStep(now time.Time) {
+	if step := r.currentStep(); step != nil && r.stepStart != nil {
+		step.Duration = state.Duration(now.Sub(*r.stepStart))
+	}
+	r.stepStart = nil
+}
+
+func (r *Recorder) currentStage() *Stage {
+	if len(r.summary.Stages) == 0 {
+		return nil
+	}
+
+	return r.summary.Stages[len(r.summary.Stages)-1]
+}
+
+func (r *Recorder) currentStep() *Step {
+	stage := r.currentStage()
+	if stage == nil || len(stage.Steps) == 0 {
+		return nil
+	}
+
+	return stage.Steps[len(stage.Steps)-1]
+}
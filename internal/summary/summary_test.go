@@ -0,0 +1,181 @@
+package summary_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	printerAutomock "github.com/pkosiec/terminer/internal/printer/automock"
+	"github.com/pkosiec/terminer/internal/summary"
+	"github.com/pkosiec/terminer/pkg/installer"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shared"
+	"github.com/pkosiec/terminer/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Run("Install", func(t *testing.T) {
+		p := fixPrinter()
+		r := summary.NewRecorder(p)
+		r.SetNowFn(fixClock())
+
+		r.SetContext(shared.OperationInstall, "Recipe", 2)
+		r.Recipe(recipe.UnitMetadata{Name: "Recipe"})
+		r.Stage(0, recipe.Stage{Metadata: recipe.UnitMetadata{Name: "Stage 1"}})
+		r.Step(0, 2, recipe.UnitMetadata{Name: "Step 1"})
+		r.Command("echo 'Foo'")
+		r.ExecOutput("Foo")
+		r.Step(1, 2, recipe.UnitMetadata{Name: "Step 2"})
+		r.StepSkipped("{{ .foo }}")
+		r.Stage(1, recipe.Stage{Metadata: recipe.UnitMetadata{Name: "Stage 2"}})
+		r.Step(0, 1, recipe.UnitMetadata{Name: "Step 1"})
+		r.Command("exit 1")
+		r.ExecError("Error")
+
+		s := r.Finish(&installer.StepError{
+			StageIndex: 1,
+			StepIndex:  0,
+			Command:    "exit 1",
+			ExitCode:   1,
+			Stderr:     "Error",
+			Err:        errors.New("exit code 1"),
+		}, 4)
+
+		assert.Equal(t, "Recipe", s.Recipe)
+		assert.Equal(t, shared.OperationInstall, s.Operation)
+		assert.Equal(t, summary.StatusFailed, s.Status)
+		assert.Equal(t, 4, s.ExitCode)
+		assert.Equal(t, state.Duration(6*time.Second), s.Duration)
+		require.Len(t, s.Stages, 2)
+
+		stage := s.Stages[0]
+		assert.Equal(t, summary.StatusSuccess, stage.Status)
+		assert.Equal(t, state.Duration(3*time.Second), stage.Duration)
+		require.Len(t, stage.Steps, 2)
+		assert.Equal(t, []string{"echo 'Foo'"}, stage.Steps[0].Commands)
+		assert.Equal(t, summary.StatusSuccess, stage.Steps[0].Status)
+		assert.Equal(t, state.Duration(time.Second), stage.Steps[0].Duration)
+		assert.Equal(t, summary.StatusSkipped, stage.Steps[1].Status)
+
+		stage = s.Stages[1]
+		assert.Equal(t, summary.StatusFailed, stage.Status)
+		require.Len(t, stage.Steps, 1)
+		assert.Equal(t, summary.StatusFailed, stage.Steps[0].Status)
+		assert.Equal(t, []summary.StepError{
+			{Message: "exit code 1", Command: "exit 1", ExitCode: 1, Stderr: "Error"},
+		}, stage.Steps[0].Errors)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		p := fixPrinter()
+		r := summary.NewRecorder(p)
+		r.SetNowFn(fixClock())
+
+		r.SetContext(shared.OperationRollback, "Recipe", 2)
+		r.Recipe(recipe.UnitMetadata{Name: "Recipe"})
+		r.Stage(0, recipe.Stage{Metadata: recipe.UnitMetadata{Name: "Stage 2"}})
+		r.Step(0, 1, recipe.UnitMetadata{Name: "Step 1"})
+		r.Stage(1, recipe.Stage{Metadata: recipe.UnitMetadata{Name: "Stage 1"}})
+		r.Step(0, 2, recipe.UnitMetadata{Name: "Step 2"})
+		r.Step(1, 2, recipe.UnitMetadata{Name: "Step 1"})
+
+		s := r.Finish(&installer.RollbackError{
+			Errors: []*installer.StepError{
+				{StageIndex: 0, StepIndex: 1, ExitCode: -1, Err: errors.New("Test")},
+			},
+		}, 5)
+
+		require.Len(t, s.Stages, 2)
+		assert.Equal(t, 1, s.Stages[0].Index)
+		assert.Equal(t, summary.StatusSuccess, s.Stages[0].Status)
+		assert.Equal(t, 0, s.Stages[1].Index)
+		assert.Equal(t, summary.StatusFailed, s.Stages[1].Status)
+		assert.Equal(t, 1, s.Stages[1].Steps[0].Index)
+		assert.Equal(t, summary.StatusFailed, s.Stages[1].Steps[0].Status)
+		assert.Equal(t, 0, s.Stages[1].Steps[1].Index)
+		assert.Equal(t, summary.StatusSuccess, s.Stages[1].Steps[1].Status)
+	})
+}
+
+func TestWriteJSON(t *testing.T) {
+	s := summary.Summary{
+		Recipe:   "Recipe",
+		Status:   summary.StatusSuccess,
+		Duration: state.Duration(1500 * time.Millisecond),
+	}
+
+	t.Run("Single recipe", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "summary.json")
+
+		err := summary.WriteJSON([]summary.Summary{s}, path)
+		require.NoError(t, err)
+
+		bytes, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+
+		var actual []map[string]interface{}
+		err = json.Unmarshal(bytes, &actual)
+		require.NoError(t, err)
+		require.Len(t, actual, 1)
+		assert.Equal(t, "Recipe", actual[0]["recipe"])
+		assert.Equal(t, "success", actual[0]["status"])
+		assert.Equal(t, 1.5, actual[0]["duration"])
+	})
+
+	t.Run("No recipes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "summary.json")
+
+		err := summary.WriteJSON(nil, path)
+		require.NoError(t, err)
+
+		bytes, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "[]", string(bytes))
+	})
+
+	t.Run("Multiple recipes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "summary.json")
+		failed := summary.Summary{Recipe: "Another recipe", Status: summary.StatusFailed}
+
+		err := summary.WriteJSON([]summary.Summary{s, failed}, path)
+		require.NoError(t, err)
+
+		bytes, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+
+		var actual []summary.Summary
+		err = json.Unmarshal(bytes, &actual)
+		require.NoError(t, err)
+		require.Len(t, actual, 2)
+		assert.Equal(t, "Recipe", actual[0].Recipe)
+		assert.Equal(t, summary.StatusFailed, actual[1].Status)
+	})
+}
+
+func fixPrinter() *printerAutomock.Printer {
+	p := &printerAutomock.Printer{}
+	for _, method := range []string{"Recipe", "StepSkipped", "Command", "ExecOutput", "ExecError"} {
+		p.On(method, mock.Anything).Return().Maybe()
+	}
+	p.On("SetContext", mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+	p.On("Stage", mock.Anything, mock.Anything).Return().Maybe()
+	p.On("Step", mock.Anything, mock.Anything, mock.Anything).Return().Maybe()
+
+	return p
+}
+
+// fixClock returns a clock, which moves forward by one second on every call
+func fixClock() func() time.Time {
+	current := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	return func() time.Time {
+		current = current.Add(time.Second)
+		return current
+	}
+}
@@ -0,0 +1,32 @@
+// Package testutil contains helpers shared by tests of CLI commands
+package testutil
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pkosiec/terminer/pkg/state"
+	"github.com/stretchr/testify/require"
+)
+
+// SetStateDir points the state directory to a new temporary directory until the test finishes, and returns its path.
+// Afterwards, the previous value of the environment variable is restored, or the variable is unset if it wasn't set.
+func SetStateDir(t *testing.T) string {
+	t.Helper()
+
+	stateDir := t.TempDir()
+	stateDirBak, isSet := os.LookupEnv(state.DirEnv)
+	t.Cleanup(func() {
+		if isSet {
+			_ = os.Setenv(state.DirEnv, stateDirBak)
+			return
+		}
+
+		_ = os.Unsetenv(state.DirEnv)
+	})
+
+	err := os.Setenv(state.DirEnv, stateDir)
+	require.NoError(t, err)
+
+	return stateDir
+}
@@ -0,0 +1,195 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/pkg/rundir"
+	"github.com/pkosiec/terminer/pkg/state"
+)
+
+const backupsDirName = "backups"
+const manifestFileName = "manifest.json"
+
+// Run is a set of file backups made during a single recipe operation
+type Run struct {
+	Recipe    string         `json:"recipe"`
+	ID        string         `json:"id"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Files     []state.Backup `json:"files"`
+
+	dir string
+}
+
+// Store keeps file backups in a state directory.
+// Backups of every run are kept in a separate directory with a manifest, which describes all copied files.
+type Store struct {
+	dir rundir.Dir
+	now func() time.Time
+}
+
+// NewStore creates a new Store in given state directory
+func NewStore(stateDir string) *Store {
+	return &Store{dir: rundir.Dir{Path: filepath.Join(stateDir, backupsDirName), Kind: "backups", Name: "Backup"}, now: time.Now}
+}
+
+// Create creates a new, empty run for a given recipe
+func (s *Store) Create(recipe string) (*Run, error) {
+	createdAt := s.now().UTC()
+	entry, err := s.dir.Create(recipe, createdAt, func(path string) error {
+		return os.Mkdir(path, 0700)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "while creating backups directory")
+	}
+
+	run := &Run{Recipe: entry.Recipe, ID: entry.ID, CreatedAt: createdAt, dir: entry.Path}
+	return run, run.writeManifest()
+}
+
+// List returns runs of a given recipe, or of all recipes if the recipe is empty.
+// Runs are sorted by recipe and then from the oldest to the newest.
+func (s *Store) List(recipe string) ([]Run, error) {
+	entries, err := s.dir.List(recipe)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]Run, 0, len(entries))
+	for _, entry := range entries {
+		run, err := readManifest(entry.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// Get returns a run of a given recipe. If the ID is empty, it returns the latest run.
+func (s *Store) Get(recipe, id string) (Run, error) {
+	entry, err := s.dir.Get(recipe, id)
+	if err != nil {
+		return Run{}, err
+	}
+
+	return readManifest(entry.Path)
+}
+
+// Snapshot copies a file to the run directory and records it in the run manifest.
+// If the file doesn't exist, the backup records it, so that the file is removed on restore.
+func (r *Run) Snapshot(stageIndex, stepIndex int, path string) (state.Backup, error) {
+	b := state.Backup{Stage: stageIndex, Step: stepIndex, Path: path}
+
+	info, err := os.Stat(path)
+	if err != nil && !os.IsNotExist(err) {
+		return state.Backup{}, errors.Wrapf(err, "while reading file %s", path)
+	}
+
+	if err == nil {
+		if info.IsDir() {
+			return state.Backup{}, fmt.Errorf("Cannot back up %s, as it is a directory", path)
+		}
+
+		b.Mode = info.Mode().Perm()
+		b.File = filepath.Join(r.dir, fmt.Sprintf("%d-%s", len(r.Files)+1, filepath.Base(path)))
+
+		err = copyFile(path, b.File, 0600)
+		if err != nil {
+			return state.Backup{}, errors.Wrapf(err, "while backing up file %s", path)
+		}
+	}
+
+	r.Files = append(r.Files, b)
+	return b, r.writeManifest()
+}
+
+// Restore restores the original file from a backup. If the original file didn't exist, it removes the file.
+func Restore(b state.Backup) error {
+	if b.File == "" {
+		err := os.Remove(b.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "while removing file %s", b.Path)
+		}
+
+		return nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(b.Path), 0755)
+	if err != nil {
+		return errors.Wrapf(err, "while creating directory of file %s", b.Path)
+	}
+
+	err = copyFile(b.File, b.Path, b.Mode)
+	if err != nil {
+		return errors.Wrapf(err, "while restoring file %s", b.Path)
+	}
+
+	// The mode of an existing file isn't changed while writing to it
+	err = os.Chmod(b.Path, b.Mode)
+	if err != nil {
+		return errors.Wrapf(err, "while restoring mode of file %s", b.Path)
+	}
+
+	return nil
+}
+
+func (r *Run) writeManifest() error {
+	bytes, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "while encoding backup manifest")
+	}
+
+	path := filepath.Join(r.dir, manifestFileName)
+	err = ioutil.WriteFile(path, bytes, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "while writing backup manifest %s", path)
+	}
+
+	return nil
+}
+
+func readManifest(dir string) (Run, error) {
+	path := filepath.Join(dir, manifestFileName)
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Run{}, errors.Wrapf(err, "while reading backup manifest %s", path)
+	}
+
+	var run Run
+	err = json.Unmarshal(bytes, &run)
+	if err != nil {
+		return Run{}, errors.Wrapf(err, "while loading backup manifest %s", path)
+	}
+
+	run.dir = dir
+	return run, nil
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
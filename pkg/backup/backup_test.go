@@ -0,0 +1,120 @@
+package backup_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkosiec/terminer/pkg/backup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore(t *testing.T) {
+	t.Run("Create, list and get", func(t *testing.T) {
+		store := backup.NewStore(t.TempDir())
+
+		first, err := store.Create("Zsh Starter")
+		require.NoError(t, err)
+		second, err := store.Create("Zsh Starter")
+		require.NoError(t, err)
+		_, err = store.Create("Fish Starter")
+		require.NoError(t, err)
+		assert.NotEqual(t, first.ID, second.ID)
+
+		runs, err := store.List("")
+		require.NoError(t, err)
+		require.Len(t, runs, 3)
+		assert.Equal(t, "fish-starter", runs[0].Recipe)
+		assert.Equal(t, first.ID, runs[1].ID)
+		assert.Equal(t, second.ID, runs[2].ID)
+
+		latest, err := store.Get("zsh-starter", "")
+		require.NoError(t, err)
+		assert.Equal(t, second.ID, latest.ID)
+
+		run, err := store.Get("Zsh Starter", first.ID)
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, run.ID)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		store := backup.NewStore(t.TempDir())
+
+		runs, err := store.List("")
+		require.NoError(t, err)
+		assert.Empty(t, runs)
+
+		_, err = store.Get("zsh-starter", "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "No backups found")
+	})
+}
+
+func TestRun_Snapshot(t *testing.T) {
+	t.Run("Existing file", func(t *testing.T) {
+		store := backup.NewStore(t.TempDir())
+		path := filepath.Join(t.TempDir(), ".zshrc")
+		err := ioutil.WriteFile(path, []byte("original\n"), 0640)
+		require.NoError(t, err)
+
+		run, err := store.Create("zsh-starter")
+		require.NoError(t, err)
+
+		b, err := run.Snapshot(1, 2, path)
+		require.NoError(t, err)
+		assert.Equal(t, 1, b.Stage)
+		assert.Equal(t, 2, b.Step)
+		assert.Equal(t, os.FileMode(0640), b.Mode)
+
+		err = ioutil.WriteFile(path, []byte("modified\n"), 0600)
+		require.NoError(t, err)
+
+		saved, err := store.Get("zsh-starter", run.ID)
+		require.NoError(t, err)
+		require.Len(t, saved.Files, 1)
+
+		err = backup.Restore(saved.Files[0])
+		require.NoError(t, err)
+
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "original\n", string(content))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		store := backup.NewStore(t.TempDir())
+		path := filepath.Join(t.TempDir(), ".zshrc")
+
+		run, err := store.Create("zsh-starter")
+		require.NoError(t, err)
+
+		b, err := run.Snapshot(0, 0, path)
+		require.NoError(t, err)
+		assert.Empty(t, b.File)
+
+		err = ioutil.WriteFile(path, []byte("created\n"), 0600)
+		require.NoError(t, err)
+
+		err = backup.Restore(b)
+		require.NoError(t, err)
+
+		_, err = os.Stat(path)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Directory", func(t *testing.T) {
+		store := backup.NewStore(t.TempDir())
+
+		run, err := store.Create("zsh-starter")
+		require.NoError(t, err)
+
+		_, err = run.Snapshot(0, 0, t.TempDir())
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,45 @@
+//go:build !windows
+// +build !windows
+
+package elevation
+
+import (
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// setCredential makes the command run with user and group IDs of a given user
+func setCredential(cmd *exec.Cmd, u *user.User) error {
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return err
+	}
+
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return err
+	}
+
+	groupIDs, err := u.GroupIds()
+	if err != nil {
+		return err
+	}
+
+	groups := make([]uint32, 0, len(groupIDs))
+	for _, id := range groupIDs {
+		group, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			return err
+		}
+
+		groups = append(groups, uint32(group))
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Credential: &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: groups},
+	}
+
+	return nil
+}
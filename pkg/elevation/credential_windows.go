@@ -0,0 +1,13 @@
+package elevation
+
+import (
+	"os/exec"
+	"os/user"
+
+	"github.com/pkg/errors"
+)
+
+// setCredential isn't supported on Windows, as processes can't be started with credentials of another user
+func setCredential(_ *exec.Cmd, _ *user.User) error {
+	return errors.New("Switching users isn't supported on Windows")
+}
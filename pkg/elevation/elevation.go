@@ -0,0 +1,340 @@
+package elevation
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Method is a way of running commands as root or as another user
+type Method string
+
+const (
+	// MethodAuto uses root if Terminer runs as root, and otherwise the first available of sudo, doas, pkexec and su
+	MethodAuto Method = "auto"
+	// MethodRoot runs commands directly, as Terminer already runs as root
+	MethodRoot Method = "root"
+	// MethodSudo runs commands with sudo
+	MethodSudo Method = "sudo"
+	// MethodDoas runs commands with doas
+	MethodDoas Method = "doas"
+	// MethodPkexec runs commands with pkexec
+	MethodPkexec Method = "pkexec"
+	// MethodSu runs commands with su
+	MethodSu Method = "su"
+)
+
+// AskpassEnv is an environment variable with a path of a program, which asks for the sudo password
+const AskpassEnv = "SUDO_ASKPASS"
+
+// DefaultKeepAliveInterval is an interval of refreshing cached sudo credentials during a run
+const DefaultKeepAliveInterval = time.Minute
+
+// detectionOrder is an order, in which elevation methods are detected
+var detectionOrder = []Method{MethodSudo, MethodDoas, MethodPkexec, MethodSu}
+
+// ParseMethod parses a name of an elevation method
+func ParseMethod(name string) (Method, error) {
+	method := Method(name)
+	switch method {
+	case MethodAuto, MethodRoot, MethodSudo, MethodDoas, MethodPkexec, MethodSu:
+		return method, nil
+	}
+
+	return "", fmt.Errorf("Invalid elevation method `%s`. Expected: %s, %s, %s, %s, %s or %s", name, MethodAuto, MethodRoot, MethodSudo, MethodDoas, MethodPkexec, MethodSu)
+}
+
+// Elevator runs commands as root or as another user with a configured elevation method.
+// The method is detected once, on first use.
+type Elevator struct {
+	method            Method
+	nonInteractive    bool
+	askpass           bool
+	keepAliveInterval time.Duration
+	stderr            io.Writer
+	lookPath          func(file string) (string, error)
+	geteuid           func() int
+
+	detectOnce    sync.Once
+	detected      Method
+	detectErr     error
+	keepAliveOnce sync.Once
+}
+
+// Option configures an Elevator
+type Option func(e *Elevator)
+
+// WithNonInteractive makes the Elevator fail instead of asking for a password.
+// Commands run with sudo and doas never prompt in this mode.
+func WithNonInteractive() Option {
+	return func(e *Elevator) {
+		e.nonInteractive = true
+	}
+}
+
+// WithKeepAliveInterval sets an interval of refreshing cached sudo credentials
+func WithKeepAliveInterval(interval time.Duration) Option {
+	return func(e *Elevator) {
+		e.keepAliveInterval = interval
+	}
+}
+
+// New creates a new instance of Elevator. The sudo password is asked with a program from SUDO_ASKPASS, if it is set.
+func New(method Method, opts ...Option) *Elevator {
+	e := &Elevator{
+		method:            method,
+		askpass:           os.Getenv(AskpassEnv) != "",
+		keepAliveInterval: DefaultKeepAliveInterval,
+		stderr:            os.Stderr,
+		lookPath:          exec.LookPath,
+		geteuid:           os.Geteuid,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// Method returns the elevation method, which is used to run commands
+func (e *Elevator) Method() (Method, error) {
+	e.detectOnce.Do(func() {
+		e.detected, e.detectErr = e.detect()
+	})
+
+	return e.detected, e.detectErr
+}
+
+func (e *Elevator) detect() (Method, error) {
+	switch e.method {
+	case MethodAuto, "":
+		if e.geteuid() == 0 {
+			return MethodRoot, nil
+		}
+
+		for _, method := range detectionOrder {
+			if e.isAvailable(method) {
+				return method, nil
+			}
+		}
+
+		return "", errors.New("No elevation method is available. Install sudo, doas, pkexec or su, or run Terminer as root")
+	case MethodRoot:
+		if e.geteuid() != 0 {
+			return "", fmt.Errorf("Elevation method `%s` requires running Terminer as root", MethodRoot)
+		}
+
+		return MethodRoot, nil
+	}
+
+	if !e.isAvailable(e.method) {
+		return "", fmt.Errorf("Elevation method `%s` isn't available", e.method)
+	}
+
+	return e.method, nil
+}
+
+func (e *Elevator) isAvailable(method Method) bool {
+	_, err := e.lookPath(string(method))
+	return err == nil
+}
+
+// Command returns a command, which runs given arguments as root with additional environment variables
+func (e *Elevator) Command(env []string, args ...string) (*exec.Cmd, error) {
+	method, err := e.Method()
+	if err != nil {
+		return nil, err
+	}
+
+	if method == MethodRoot {
+		return direct(env, args), nil
+	}
+
+	args = withEnv(env, args)
+	switch method {
+	case MethodSudo:
+		return exec.Command("sudo", append(e.sudoFlags(), args...)...), nil
+	case MethodDoas:
+		return exec.Command("doas", append(e.doasFlags(), args...)...), nil
+	case MethodPkexec:
+		return exec.Command("pkexec", args...), nil
+	}
+
+	return exec.Command("su", "-c", QuoteArgs(args)), nil
+}
+
+// UserCommand returns a command, which runs given arguments as a given user with additional environment variables.
+// If Terminer runs as root, the user is switched directly, so created files are owned by the user.
+func (e *Elevator) UserCommand(u *user.User, env []string, args ...string) (*exec.Cmd, error) {
+	method, err := e.Method()
+	if err != nil {
+		return nil, err
+	}
+
+	if method == MethodRoot {
+		cmd := direct(env, args)
+		err := setCredential(cmd, u)
+		if err != nil {
+			return nil, errors.Wrapf(err, "while switching to user `%s`", u.Username)
+		}
+
+		return cmd, nil
+	}
+
+	args = withEnv(env, args)
+	switch method {
+	case MethodSudo:
+		return exec.Command("sudo", append(append(e.sudoFlags(), "-u", u.Username, "--"), args...)...), nil
+	case MethodDoas:
+		return exec.Command("doas", append(append(e.doasFlags(), "-u", u.Username), args...)...), nil
+	case MethodPkexec:
+		return exec.Command("pkexec", append([]string{"--user", u.Username}, args...)...), nil
+	}
+
+	return exec.Command("su", u.Username, "-c", QuoteArgs(args)), nil
+}
+
+// Prime asks for credentials upfront with a given reason, such as a number of commands, which need elevated privileges.
+// For sudo, it caches the credentials and refreshes them until the context is done, so that later commands don't prompt.
+// In non-interactive mode, it fails if elevation requires a password.
+func (e *Elevator) Prime(ctx context.Context, reason string) error {
+	method, err := e.Method()
+	if err != nil {
+		return err
+	}
+
+	switch method {
+	case MethodRoot:
+		return nil
+	case MethodSudo:
+		if e.nonInteractive && !e.askpass {
+			if err := exec.CommandContext(ctx, "sudo", "-n", "true").Run(); err != nil {
+				return e.passwordError(reason, method)
+			}
+		} else {
+			// Percent signs are escape sequences of the sudo prompt
+			prompt := strings.ReplaceAll(reason, "%", "%%") + ". Password for %u: "
+			args := append(e.sudoFlags(), "-v", "-p", prompt)
+			if err := e.runAttached(ctx, "sudo", args...); err != nil {
+				return errors.Wrap(err, "while asking for sudo password")
+			}
+		}
+
+		e.keepAliveOnce.Do(func() {
+			go e.keepAlive(ctx)
+		})
+		return nil
+	case MethodDoas:
+		if e.nonInteractive {
+			if err := exec.CommandContext(ctx, "doas", "-n", "true").Run(); err != nil {
+				return e.passwordError(reason, method)
+			}
+
+			return nil
+		}
+
+		fmt.Fprintf(e.stderr, "%s.\n", reason)
+		if err := e.runAttached(ctx, "doas", "true"); err != nil {
+			return errors.Wrap(err, "while asking for doas password")
+		}
+
+		return nil
+	}
+
+	if e.nonInteractive {
+		return e.passwordError(reason, method)
+	}
+
+	fmt.Fprintf(e.stderr, "%s. %s may ask for a password for every command.\n", reason, method)
+	return nil
+}
+
+// keepAlive refreshes cached sudo credentials until the context is done
+func (e *Elevator) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(e.keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Refreshing never prompts, so it doesn't interfere with the output
+			_ = exec.CommandContext(ctx, "sudo", "-n", "-v").Run()
+		}
+	}
+}
+
+func (e *Elevator) runAttached(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = e.stderr
+	cmd.Stderr = e.stderr
+
+	return cmd.Run()
+}
+
+func (e *Elevator) passwordError(reason string, method Method) error {
+	hint := "Configure passwordless elevation or run Terminer as root"
+	if method == MethodSudo {
+		hint = fmt.Sprintf("Configure passwordless sudo or set %s", AskpassEnv)
+	}
+
+	return fmt.Errorf("%s, but %s requires a password, which can't be asked in non-interactive mode. %s", reason, method, hint)
+}
+
+func (e *Elevator) sudoFlags() []string {
+	if e.askpass {
+		return []string{"-A"}
+	}
+
+	if e.nonInteractive {
+		return []string{"-n"}
+	}
+
+	return nil
+}
+
+func (e *Elevator) doasFlags() []string {
+	if e.nonInteractive {
+		return []string{"-n"}
+	}
+
+	return nil
+}
+
+// QuoteArgs quotes arguments for a POSIX shell
+func QuoteArgs(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, fmt.Sprintf("'%s'", strings.ReplaceAll(arg, "'", `'\''`)))
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+func direct(env, args []string) *exec.Cmd {
+	cmd := exec.Command(args[0], args[1:]...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	return cmd
+}
+
+func withEnv(env, args []string) []string {
+	if len(env) == 0 {
+		return args
+	}
+
+	return append(append([]string{"env"}, env...), args...)
+}
@@ -0,0 +1,223 @@
+package elevation_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/user"
+	"testing"
+
+	"github.com/pkosiec/terminer/pkg/elevation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMethod(t *testing.T) {
+	method, err := elevation.ParseMethod("doas")
+	require.NoError(t, err)
+	assert.Equal(t, elevation.MethodDoas, method)
+
+	_, err = elevation.ParseMethod("runas")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid elevation method `runas`")
+}
+
+func TestElevator_Method(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Method        elevation.Method
+		EUID          int
+		Available     []string
+		Expected      elevation.Method
+		ExpectedError string
+	}{
+		{
+			Name:      "Auto as root",
+			Method:    elevation.MethodAuto,
+			EUID:      0,
+			Available: []string{"sudo"},
+			Expected:  elevation.MethodRoot,
+		},
+		{
+			Name:      "Auto prefers sudo",
+			Method:    elevation.MethodAuto,
+			EUID:      1000,
+			Available: []string{"su", "doas", "sudo"},
+			Expected:  elevation.MethodSudo,
+		},
+		{
+			Name:      "Auto falls back to doas",
+			Method:    elevation.MethodAuto,
+			EUID:      1000,
+			Available: []string{"su", "doas"},
+			Expected:  elevation.MethodDoas,
+		},
+		{
+			Name:          "Auto without any method",
+			Method:        elevation.MethodAuto,
+			EUID:          1000,
+			ExpectedError: "No elevation method is available",
+		},
+		{
+			Name:          "Root without running as root",
+			Method:        elevation.MethodRoot,
+			EUID:          1000,
+			ExpectedError: "requires running Terminer as root",
+		},
+		{
+			Name:      "Explicit method",
+			Method:    elevation.MethodPkexec,
+			EUID:      0,
+			Available: []string{"sudo", "pkexec"},
+			Expected:  elevation.MethodPkexec,
+		},
+		{
+			Name:          "Unavailable method",
+			Method:        elevation.MethodDoas,
+			EUID:          1000,
+			Available:     []string{"sudo"},
+			ExpectedError: "Elevation method `doas` isn't available",
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.Name, func(t *testing.T) {
+			e := elevation.NewWithSystem(tC.Method, tC.EUID, tC.Available, &bytes.Buffer{})
+
+			method, err := e.Method()
+
+			if tC.ExpectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tC.ExpectedError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tC.Expected, method)
+		})
+	}
+}
+
+func TestElevator_Command(t *testing.T) {
+	env := []string{"HOME=/home/service"}
+	u := &user.User{Username: "service", Uid: "1001", Gid: "1001"}
+
+	testCases := []struct {
+		Name         string
+		Method       elevation.Method
+		Options      []elevation.Option
+		Expected     []string
+		ExpectedUser []string
+	}{
+		{
+			Name:         "Sudo",
+			Method:       elevation.MethodSudo,
+			Expected:     []string{"sudo", "env", "HOME=/home/service", "id", "-u"},
+			ExpectedUser: []string{"sudo", "-u", "service", "--", "env", "HOME=/home/service", "id", "-u"},
+		},
+		{
+			Name:         "Non-interactive sudo",
+			Method:       elevation.MethodSudo,
+			Options:      []elevation.Option{elevation.WithNonInteractive()},
+			Expected:     []string{"sudo", "-n", "env", "HOME=/home/service", "id", "-u"},
+			ExpectedUser: []string{"sudo", "-n", "-u", "service", "--", "env", "HOME=/home/service", "id", "-u"},
+		},
+		{
+			Name:         "Non-interactive doas",
+			Method:       elevation.MethodDoas,
+			Options:      []elevation.Option{elevation.WithNonInteractive()},
+			Expected:     []string{"doas", "-n", "env", "HOME=/home/service", "id", "-u"},
+			ExpectedUser: []string{"doas", "-n", "-u", "service", "env", "HOME=/home/service", "id", "-u"},
+		},
+		{
+			Name:         "Pkexec",
+			Method:       elevation.MethodPkexec,
+			Expected:     []string{"pkexec", "env", "HOME=/home/service", "id", "-u"},
+			ExpectedUser: []string{"pkexec", "--user", "service", "env", "HOME=/home/service", "id", "-u"},
+		},
+		{
+			Name:         "Su",
+			Method:       elevation.MethodSu,
+			Expected:     []string{"su", "-c", "'env' 'HOME=/home/service' 'id' '-u'"},
+			ExpectedUser: []string{"su", "service", "-c", "'env' 'HOME=/home/service' 'id' '-u'"},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.Name, func(t *testing.T) {
+			e := elevation.NewWithSystem(tC.Method, 1000, []string{string(tC.Method)}, &bytes.Buffer{}, tC.Options...)
+
+			cmd, err := e.Command(env, "id", "-u")
+			require.NoError(t, err)
+			assert.Equal(t, tC.Expected, cmd.Args)
+
+			cmd, err = e.UserCommand(u, env, "id", "-u")
+			require.NoError(t, err)
+			assert.Equal(t, tC.ExpectedUser, cmd.Args)
+		})
+	}
+
+	t.Run("Askpass", func(t *testing.T) {
+		askpassBak, ok := os.LookupEnv(elevation.AskpassEnv)
+		require.NoError(t, os.Setenv(elevation.AskpassEnv, "/usr/bin/ssh-askpass"))
+		defer func() {
+			if ok {
+				os.Setenv(elevation.AskpassEnv, askpassBak)
+			} else {
+				os.Unsetenv(elevation.AskpassEnv)
+			}
+		}()
+
+		e := elevation.NewWithSystem(elevation.MethodSudo, 1000, []string{"sudo"}, &bytes.Buffer{}, elevation.WithNonInteractive())
+
+		cmd, err := e.Command(nil, "id", "-u")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"sudo", "-A", "id", "-u"}, cmd.Args)
+	})
+
+	t.Run("Root", func(t *testing.T) {
+		e := elevation.NewWithSystem(elevation.MethodRoot, 0, nil, &bytes.Buffer{})
+
+		cmd, err := e.Command(env, "id", "-u")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"id", "-u"}, cmd.Args)
+		assert.Contains(t, cmd.Env, "HOME=/home/service")
+	})
+}
+
+func TestElevator_Prime(t *testing.T) {
+	reason := "Recipe `Recipe` needs elevated privileges for 2 commands"
+
+	t.Run("Root", func(t *testing.T) {
+		stderr := &bytes.Buffer{}
+		e := elevation.NewWithSystem(elevation.MethodRoot, 0, nil, stderr, elevation.WithNonInteractive())
+
+		err := e.Prime(context.Background(), reason)
+		require.NoError(t, err)
+		assert.Empty(t, stderr.String())
+	})
+
+	t.Run("Non-interactive su", func(t *testing.T) {
+		e := elevation.NewWithSystem(elevation.MethodSu, 1000, []string{"su"}, &bytes.Buffer{}, elevation.WithNonInteractive())
+
+		err := e.Prime(context.Background(), reason)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), reason+", but su requires a password, which can't be asked in non-interactive mode")
+	})
+
+	t.Run("Interactive pkexec", func(t *testing.T) {
+		stderr := &bytes.Buffer{}
+		e := elevation.NewWithSystem(elevation.MethodPkexec, 1000, []string{"pkexec"}, stderr)
+
+		err := e.Prime(context.Background(), reason)
+		require.NoError(t, err)
+		assert.Equal(t, reason+". pkexec may ask for a password for every command.\n", stderr.String())
+	})
+
+	t.Run("Unavailable method", func(t *testing.T) {
+		e := elevation.NewWithSystem(elevation.MethodAuto, 1000, nil, &bytes.Buffer{})
+
+		err := e.Prime(context.Background(), reason)
+		require.Error(t, err)
+	})
+}
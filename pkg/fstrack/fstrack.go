@@ -0,0 +1,206 @@
+package fstrack
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkosiec/terminer/pkg/state"
+)
+
+// Entry contains metadata of a single file. Hash is a SHA-256 checksum of a regular file.
+type Entry struct {
+	Mode    os.FileMode
+	Size    int64
+	ModTime time.Time
+	Hash    string
+}
+
+// Snapshot contains entries of all files in tracked roots, by their paths
+type Snapshot map[string]Entry
+
+// Tracker takes snapshots of files in given roots
+type Tracker struct {
+	roots    []string
+	excludes []string
+}
+
+// Option configures a Tracker
+type Option func(t *Tracker)
+
+// WithExcludes sets paths, which are ignored together with their content
+func WithExcludes(paths ...string) Option {
+	return func(t *Tracker) {
+		for _, path := range paths {
+			t.excludes = append(t.excludes, filepath.Clean(path))
+		}
+	}
+}
+
+// New creates a new Tracker of given roots
+func New(roots []string, opts ...Option) *Tracker {
+	t := &Tracker{}
+	for _, root := range roots {
+		t.roots = append(t.roots, filepath.Clean(root))
+	}
+
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// Snapshot walks all roots and returns metadata of found files.
+// Hashes of files, which have the same size and modification time as in the previous snapshot, are reused.
+// Files, which cannot be read, are ignored.
+func (t *Tracker) Snapshot(previous Snapshot) Snapshot {
+	snapshot := Snapshot{}
+	for _, root := range t.roots {
+		_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				// The root doesn't exist or the file has been removed during the walk
+				return nil
+			}
+
+			if t.isExcluded(path) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			entry := Entry{Mode: info.Mode(), ModTime: info.ModTime()}
+			if info.Mode().IsRegular() {
+				entry.Size = info.Size()
+				entry.Hash = hashFile(path, entry, previous)
+			}
+
+			snapshot[path] = entry
+			return nil
+		})
+	}
+
+	return snapshot
+}
+
+// Diff returns files created, modified and deleted between snapshots, sorted by path.
+// Modification times of directories are ignored, as they change whenever their content changes.
+func Diff(stageIndex, stepIndex int, before, after Snapshot) []state.FileChange {
+	var changes []state.FileChange
+	for path, entry := range after {
+		change := state.FileChange{Stage: stageIndex, Step: stepIndex, Path: path, Dir: entry.Mode.IsDir()}
+
+		old, exists := before[path]
+		switch {
+		case !exists:
+			change.Type = state.ChangeCreated
+		case isModified(old, entry):
+			change.Type = state.ChangeModified
+		default:
+			continue
+		}
+
+		changes = append(changes, change)
+	}
+
+	for path, entry := range before {
+		if _, exists := after[path]; exists {
+			continue
+		}
+
+		changes = append(changes, state.FileChange{Stage: stageIndex, Step: stepIndex, Path: path, Type: state.ChangeDeleted, Dir: entry.Mode.IsDir()})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
+
+// SuggestRollback returns commands, which revert given changes as far as possible.
+// Created files and directories are removed. Modified and deleted files cannot be restored without a backup,
+// so they are only listed in comments.
+func SuggestRollback(changes []state.FileChange) []string {
+	sorted := make([]state.FileChange, len(changes))
+	copy(sorted, changes)
+
+	// Content of a directory is removed before the directory
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Path > sorted[j].Path
+	})
+
+	var commands []string
+	for _, change := range sorted {
+		switch {
+		case change.Type == state.ChangeCreated && change.Dir:
+			commands = append(commands, fmt.Sprintf("rmdir %s", quote(change.Path)))
+		case change.Type == state.ChangeCreated:
+			commands = append(commands, fmt.Sprintf("rm -f %s", quote(change.Path)))
+		case change.Dir:
+			// Directories are modified together with their content
+			continue
+		default:
+			commands = append(commands, fmt.Sprintf("# %s was %s. Back it up with `backup` to restore it on rollback", change.Path, change.Type))
+		}
+	}
+
+	return commands
+}
+
+func (t *Tracker) isExcluded(path string) bool {
+	for _, exclude := range t.excludes {
+		if path == exclude || strings.HasPrefix(path, exclude+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isModified(old, entry Entry) bool {
+	if old.Mode != entry.Mode {
+		return true
+	}
+
+	if entry.Mode.IsDir() {
+		return false
+	}
+
+	if entry.Mode.IsRegular() {
+		return old.Size != entry.Size || old.Hash != entry.Hash
+	}
+
+	return !old.ModTime.Equal(entry.ModTime)
+}
+
+func hashFile(path string, entry Entry, previous Snapshot) string {
+	if old, ok := previous[path]; ok && old.Hash != "" && old.Size == entry.Size && old.ModTime.Equal(entry.ModTime) {
+		return old.Hash
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func quote(arg string) string {
+	return fmt.Sprintf("'%s'", strings.ReplaceAll(arg, "'", `'\''`))
+}
@@ -0,0 +1,68 @@
+package fstrack_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkosiec/terminer/pkg/fstrack"
+	"github.com/pkosiec/terminer/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	root := t.TempDir()
+	excluded := filepath.Join(root, ".terminer")
+	writeFile(t, filepath.Join(root, ".zshrc"), "original\n")
+	writeFile(t, filepath.Join(root, ".bashrc"), "original\n")
+	writeFile(t, filepath.Join(root, ".profile"), "original\n")
+	writeFile(t, filepath.Join(excluded, "state.json"), "{}")
+
+	tracker := fstrack.New([]string{root, filepath.Join(root, "not-existing")}, fstrack.WithExcludes(excluded))
+
+	before := tracker.Snapshot(nil)
+	assert.NotContains(t, before, filepath.Join(excluded, "state.json"))
+
+	writeFile(t, filepath.Join(root, ".zshrc"), "modified\n")
+	writeFile(t, filepath.Join(root, ".zsh", "plugins.zsh"), "plugins\n")
+	writeFile(t, filepath.Join(excluded, "state.json"), `{"recipe": "zsh"}`)
+	require.NoError(t, os.Remove(filepath.Join(root, ".bashrc")))
+	require.NoError(t, os.Chmod(filepath.Join(root, ".profile"), 0700))
+
+	after := tracker.Snapshot(before)
+	changes := fstrack.Diff(1, 2, before, after)
+
+	assert.Equal(t, []state.FileChange{
+		{Stage: 1, Step: 2, Path: filepath.Join(root, ".bashrc"), Type: state.ChangeDeleted},
+		{Stage: 1, Step: 2, Path: filepath.Join(root, ".profile"), Type: state.ChangeModified},
+		{Stage: 1, Step: 2, Path: filepath.Join(root, ".zsh"), Type: state.ChangeCreated, Dir: true},
+		{Stage: 1, Step: 2, Path: filepath.Join(root, ".zsh", "plugins.zsh"), Type: state.ChangeCreated},
+		{Stage: 1, Step: 2, Path: filepath.Join(root, ".zshrc"), Type: state.ChangeModified},
+	}, changes)
+
+	assert.Empty(t, fstrack.Diff(0, 0, after, tracker.Snapshot(after)))
+}
+
+func TestSuggestRollback(t *testing.T) {
+	changes := []state.FileChange{
+		{Path: "/home/user/.zsh", Type: state.ChangeCreated, Dir: true},
+		{Path: "/home/user/.zsh/it's.zsh", Type: state.ChangeCreated},
+		{Path: "/home/user/.zshrc", Type: state.ChangeModified},
+		{Path: "/home/user/config", Type: state.ChangeModified, Dir: true},
+	}
+
+	commands := fstrack.SuggestRollback(changes)
+
+	assert.Equal(t, []string{
+		"# /home/user/.zshrc was modified. Back it up with `backup` to restore it on rollback",
+		`rm -f '/home/user/.zsh/it'\''s.zsh'`,
+		"rmdir '/home/user/.zsh'",
+	}, commands)
+}
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+}
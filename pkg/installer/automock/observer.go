@@ -0,0 +1,79 @@
+// Code generated by mockery v1.0.0
+package automock
+
+import (
+	"github.com/pkosiec/terminer/pkg/shared"
+	"github.com/stretchr/testify/mock"
+)
+import "github.com/pkosiec/terminer/pkg/recipe"
+import "time"
+
+// Observer is an autogenerated mock type for the Observer type
+type Observer struct {
+	mock.Mock
+}
+
+// Command provides a mock function with given fields: cmd
+func (_m *Observer) Command(cmd string) {
+	_m.Called(cmd)
+}
+
+// CommandFinished provides a mock function with given fields: duration
+func (_m *Observer) CommandFinished(duration time.Duration) {
+	_m.Called(duration)
+}
+
+// ExecError provides a mock function with given fields: output
+func (_m *Observer) ExecError(output string) {
+	_m.Called(output)
+}
+
+// ExecOutput provides a mock function with given fields: output
+func (_m *Observer) ExecOutput(output string) {
+	_m.Called(output)
+}
+
+// ExecTrace provides a mock function with given fields: trace
+func (_m *Observer) ExecTrace(trace string) {
+	_m.Called(trace)
+}
+
+// Interactive provides a mock function with given fields: active
+func (_m *Observer) Interactive(active bool) {
+	_m.Called(active)
+}
+
+// Recipe provides a mock function with given fields: r
+func (_m *Observer) Recipe(r recipe.UnitMetadata) {
+	_m.Called(r)
+}
+
+// SetContext provides a mock function with given fields: operation, recipeName, stagesCount
+func (_m *Observer) SetContext(operation shared.Operation, recipeName string, stagesCount int) {
+	_m.Called(operation, recipeName, stagesCount)
+}
+
+// Stage provides a mock function with given fields: stageIndex, s
+func (_m *Observer) Stage(stageIndex int, s recipe.Stage) {
+	_m.Called(stageIndex, s)
+}
+
+// StageFinished provides a mock function with given fields: duration
+func (_m *Observer) StageFinished(duration time.Duration) {
+	_m.Called(duration)
+}
+
+// Step provides a mock function with given fields: stepIndex, steps, s
+func (_m *Observer) Step(stepIndex int, steps int, s recipe.UnitMetadata) {
+	_m.Called(stepIndex, steps, s)
+}
+
+// StepFinished provides a mock function with given fields: duration, err
+func (_m *Observer) StepFinished(duration time.Duration, err error) {
+	_m.Called(duration, err)
+}
+
+// StepSkipped provides a mock function with given fields: condition
+func (_m *Observer) StepSkipped(condition string) {
+	_m.Called(condition)
+}
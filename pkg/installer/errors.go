@@ -0,0 +1,105 @@
+package installer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shell"
+)
+
+// StepError is returned when a recipe step fails.
+// StageIndex and StepIndex are indexes of the stage and step in the recipe, regardless of the operation order.
+// Command, ExitCode and Stderr describe the failed command. ExitCode is -1 if the step failed for a different reason than exit code.
+type StepError struct {
+	StageIndex int
+	StepIndex  int
+	Stage      string
+	Step       string
+	Command    string
+	ExitCode   int
+	Stderr     string
+	Err        error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("while executing command from Stage '%s', Step '%s': %s", e.Stage, e.Step, e.Err.Error())
+}
+
+// Unwrap returns the underlying error
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the underlying error
+func (e *StepError) Cause() error {
+	return e.Err
+}
+
+// RollbackError is returned when one or more steps fail during rollback
+type RollbackError struct {
+	Errors []*StepError
+}
+
+func (e *RollbackError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+
+	return fmt.Sprintf("%d error(s) occurred during rollback:\n%s", len(e.Errors), strings.Join(messages, "\n"))
+}
+
+// RestoreError is returned when one or more files backed up by a step can't be restored during rollback.
+// Every error contains a path of the file, which it concerns.
+type RestoreError struct {
+	Errors []error
+}
+
+func (e *RestoreError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+
+	return fmt.Sprintf("%d file(s) couldn't be restored:\n%s", len(e.Errors), strings.Join(messages, "\n"))
+}
+
+func newStepError(stageIndex, stepIndex int, stage recipe.Stage, step recipe.Step, err error) *StepError {
+	stepErr := &StepError{
+		StageIndex: stageIndex,
+		StepIndex:  stepIndex,
+		Stage:      stage.Metadata.Name,
+		Step:       step.Metadata.Name,
+		ExitCode:   -1,
+		Err:        err,
+	}
+
+	var exitErr *shell.ExitError
+	var mismatchErr *shell.OutputMismatchError
+	if errors.As(err, &exitErr) {
+		stepErr.Command = exitErr.Command
+		stepErr.ExitCode = exitErr.ExitCode
+		stepErr.Stderr = exitErr.Stderr
+	} else if errors.As(err, &mismatchErr) {
+		stepErr.Command = mismatchErr.Command
+	}
+
+	return stepErr
+}
+
+// newStepErrors returns a separate StepError for every failed command of the step
+func newStepErrors(stageIndex, stepIndex int, stage recipe.Stage, step recipe.Step, err error) []*StepError {
+	var multiErr *shell.MultiError
+	if !errors.As(err, &multiErr) {
+		return []*StepError{newStepError(stageIndex, stepIndex, stage, step, err)}
+	}
+
+	var stepErrs []*StepError
+	for _, singleErr := range multiErr.Errors {
+		stepErrs = append(stepErrs, newStepError(stageIndex, stepIndex, stage, step, singleErr))
+	}
+
+	return stepErrs
+}
@@ -1,22 +1,165 @@
 package installer
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
 	"github.com/pkg/errors"
-	"github.com/pkosiec/terminer/internal/printer"
+	"github.com/pkosiec/terminer/pkg/backup"
+	"github.com/pkosiec/terminer/pkg/elevation"
+	"github.com/pkosiec/terminer/pkg/fstrack"
+	"github.com/pkosiec/terminer/pkg/path"
 	"github.com/pkosiec/terminer/pkg/recipe"
 	"github.com/pkosiec/terminer/pkg/shared"
 	"github.com/pkosiec/terminer/pkg/shell"
+	"github.com/pkosiec/terminer/pkg/state"
 )
 
+// PrefixEnv is an environment variable, which contains the root prefix of the installation
+const PrefixEnv = "TERMINER_PREFIX"
+
 // Installer provides an ability to install recipes
 type Installer struct {
-	r       *recipe.Recipe
-	sh      shell.Shell
-	printer printer.Printer
+	r          *recipe.Recipe
+	sh         shell.Shell
+	observer   Observer
+	store      state.Store
+	ctx        context.Context
+	parameters map[string]string
+	source     *state.Source
+	target     *state.Target
+	backups    *backup.Store
+	tracker    *fstrack.Tracker
+	elevator   *elevation.Elevator
+	confirm    ConfirmFn
+	limits     shell.OutputLimits
+	failFast   bool
+	dryRun     bool
+	ownShell   bool
+}
+
+// Option configures an Installer
+type Option func(installer *Installer)
+
+// WithStateStore sets a store, which persists recipe state, such as registered variables, between operations
+func WithStateStore(store state.Store) Option {
+	return func(installer *Installer) {
+		installer.store = store
+	}
+}
+
+// WithContext sets a context, which stops the operation before the next step when it is done
+func WithContext(ctx context.Context) Option {
+	return func(installer *Installer) {
+		installer.ctx = ctx
+	}
+}
+
+// WithFailFast makes rollback stop on the first failed command, instead of reverting all remaining steps
+func WithFailFast() Option {
+	return func(installer *Installer) {
+		installer.failFast = true
+	}
+}
+
+// WithObserver sets an Observer, which receives progress events and command output
+func WithObserver(observer Observer) Option {
+	return func(installer *Installer) {
+		installer.observer = observer
+	}
+}
+
+// WithShell sets a Shell, which executes commands. By default, commands are executed in a system shell,
+// and their output is passed to the Observer.
+func WithShell(sh shell.Shell) Option {
+	return func(installer *Installer) {
+		installer.sh = sh
+	}
+}
+
+// WithDryRun makes the Installer only report rendered commands without executing them.
+// Registered variables are replaced with placeholders and the recipe state is not modified.
+// It can't be used together with WithShell.
+func WithDryRun() Option {
+	return func(installer *Installer) {
+		installer.dryRun = true
+	}
+}
+
+// WithParameters sets initial variables of the installation, which can be used in commands and step conditions.
+// Variables registered by commands override parameters with the same name.
+func WithParameters(parameters map[string]string) Option {
+	return func(installer *Installer) {
+		installer.parameters = parameters
+	}
+}
+
+// WithSource sets a source of the recipe, which is saved in the recipe state
+func WithSource(source state.Source) Option {
+	return func(installer *Installer) {
+		installer.source = &source
+	}
+}
+
+// WithTarget sets a home directory, a root prefix and a user of the installation. Commands receive them
+// in HOME and TERMINER_PREFIX environment variables, and backup paths are resolved against them.
+// Commands, which define neither root nor user, run as the target user.
+// The target is saved in the recipe state, so rollback uses the saved target. Non-empty fields of a target set
+// for rollback override the saved ones.
+func WithTarget(target state.Target) Option {
+	return func(installer *Installer) {
+		installer.target = &target
+	}
+}
+
+// WithBackupStore sets a store, which keeps copies of files listed in step backups
+func WithBackupStore(store *backup.Store) Option {
+	return func(installer *Installer) {
+		installer.backups = store
+	}
+}
+
+// WithChangeTracking makes the Installer snapshot files before the first executed step and after every executed step,
+// and save files changed by the step in the recipe state
+func WithChangeTracking(tracker *fstrack.Tracker) Option {
+	return func(installer *Installer) {
+		installer.tracker = tracker
+	}
+}
+
+// WithElevator sets an elevator, which runs commands as root or as another user.
+// Before an operation, the Installer asks for credentials upfront, if any command needs elevation.
+func WithElevator(elevator *elevation.Elevator) Option {
+	return func(installer *Installer) {
+		installer.elevator = elevator
+	}
+}
+
+// ConfirmFn asks user to confirm commands of a recipe, which run with elevated privileges.
+// It returns an error if the commands aren't confirmed.
+type ConfirmFn func(recipeName string, commands []recipe.PrivilegedCommand) error
+
+// WithConfirmation sets a function, which asks user to confirm commands with elevated privileges before an operation.
+// It isn't called if the recipe has no such commands, or in dry-run mode.
+func WithConfirmation(confirm ConfirmFn) Option {
+	return func(installer *Installer) {
+		installer.confirm = confirm
+	}
+}
+
+// WithOutputLimits sets limits of printed output of every command, unless commands define their own limits.
+// By default, shell.DefaultOutputLimits are used.
+func WithOutputLimits(limits shell.OutputLimits) Option {
+	return func(installer *Installer) {
+		installer.limits = limits
+	}
 }
 
 // New creates a new instance of Installer.
-func New(r *recipe.Recipe, p printer.Printer) (*Installer, error) {
+func New(r *recipe.Recipe, opts ...Option) (*Installer, error) {
 	if r == nil {
 		return nil, errors.New("Recipe is empty")
 	}
@@ -25,74 +168,619 @@ func New(r *recipe.Recipe, p printer.Printer) (*Installer, error) {
 		return nil, err
 	}
 
-	return &Installer{
-		r:       r,
-		sh:      shell.New(p.Command, p.ExecOutput, p.ExecError),
-		printer: p,
-	}, nil
+	installer := &Installer{
+		r:        r,
+		observer: NopObserver{},
+		ctx:      context.Background(),
+		limits:   shell.DefaultOutputLimits,
+	}
+
+	for _, opt := range opts {
+		opt(installer)
+	}
+
+	if installer.elevator == nil {
+		installer.elevator = elevation.New(elevation.MethodAuto)
+	}
+
+	if installer.dryRun && installer.sh != nil {
+		return nil, errors.New("Both shell and dry run defined. Use only one of them")
+	}
+
+	if installer.sh == nil {
+		installer.sh = installer.newShell()
+		installer.ownShell = true
+	}
+
+	return installer, nil
+}
+
+// newShell creates a shell, which runs commands for the target of the installation
+func (installer *Installer) newShell() shell.Shell {
+	o := installer.observer
+	if installer.dryRun {
+		return shell.NewDryRun(o.Command)
+	}
+
+	opts := []shell.Option{
+		shell.WithTrace(o.ExecTrace),
+		shell.WithTiming(o.CommandFinished),
+		shell.WithEnv(installer.env),
+		shell.WithElevator(installer.elevator),
+		shell.WithInteractive(o.Interactive),
+		shell.WithOutputLimits(installer.limits),
+	}
+	if installer.target != nil && installer.target.User != "" {
+		opts = append(opts, shell.WithUser(installer.target.User))
+	}
+
+	return shell.New(o.Command, o.ExecOutput, o.ExecError, opts...)
 }
 
 // Install installs a recipe by executing all steps in all stages
 func (installer *Installer) Install() error {
 	stagesCount := len(installer.r.Stages)
-	installer.printer.SetContext(shared.OperationInstall, stagesCount)
+	installer.observer.SetContext(shared.OperationInstall, installer.r.Metadata.Name, stagesCount)
+
+	err := installer.elevate(false)
+	if err != nil {
+		return err
+	}
 
 	stages := installer.r.Stages
+	vars := Variables{}
+	for name, value := range installer.parameters {
+		vars[name] = value
+	}
+
+	previous, err := installer.previousBackups()
+	if err != nil {
+		return err
+	}
+
+	t := newTimings()
+	var backups []state.Backup
+	var changes []state.FileChange
+	var run *backup.Run
+	var snapshot fstrack.Snapshot
 
-	installer.printer.Recipe(installer.r.Metadata)
+	installer.observer.Recipe(installer.r.Metadata.UnitMetadata)
 
 	for stageIndex, stage := range stages {
-		installer.printer.Stage(stageIndex, stage)
+		installer.observer.Stage(stageIndex, stage)
+		stageStart := time.Now()
 
 		stepsLen := len(stage.Steps)
 		for stepIndex, step := range stage.Steps {
-			installer.printer.Step(stepIndex, stepsLen, step.Metadata)
+			if err := installer.ctx.Err(); err != nil {
+				installer.observer.StageFinished(time.Since(stageStart))
+				return installer.saveState(state.StatusFailed, vars, t, keepBackups(backups, previous), changes, errors.Wrap(err, "while installing recipe"))
+			}
+
+			installer.observer.Step(stepIndex, stepsLen, step.Metadata)
 
-			err := installer.sh.Exec(step.Execute, true)
+			var before fstrack.Snapshot
+			hook := stepHook{
+				before: func() error {
+					var err error
+					run, err = installer.backupFiles(run, stageIndex, stepIndex, step, vars, previous, &backups)
+					if err != nil {
+						return err
+					}
+
+					// Files are changed only by executed steps, so the snapshot taken after the previous one is reused
+					if snapshot == nil {
+						snapshot = installer.snapshot(nil)
+					}
+					before = snapshot
+					return nil
+				},
+				after: func() error {
+					if before != nil {
+						snapshot = installer.snapshot(before)
+						changes = append(changes, fstrack.Diff(stageIndex, stepIndex, before, snapshot)...)
+					}
+
+					return nil
+				},
+			}
+
+			err := installer.executeStep(stage, step, step.Execute, vars, t, true, hook)
 			if err != nil {
-				return errors.Wrapf(err, "while executing command from Stage '%s', Step '%s'", stage.Metadata.Name, step.Metadata.Name)
+				installer.observer.StageFinished(time.Since(stageStart))
+				stepErr := newStepError(stageIndex, stepIndex, stage, step, err)
+				return installer.saveState(state.StatusFailed, vars, t, keepBackups(backups, previous), changes, stepErr)
 			}
 		}
+
+		installer.observer.StageFinished(time.Since(stageStart))
 	}
 
-	return nil
+	return installer.saveState(state.StatusInstalled, vars, t, keepBackups(backups, previous), changes, nil)
 }
 
 // Rollback reverts a recipe by executing all steps in all stages in reverse order
 func (installer *Installer) Rollback() error {
 	stagesCount := len(installer.r.Stages)
-	installer.printer.SetContext(shared.OperationRollback, stagesCount)
+	installer.observer.SetContext(shared.OperationRollback, installer.r.Metadata.Name, stagesCount)
 
 	stages := installer.r.Stages
 	stagesLen := len(stages)
 
-	hasErrorOccurred := false
+	record, err := installer.loadRecord()
+	if err != nil {
+		return err
+	}
+
+	vars := Variables{}
+	var backups []state.Backup
+	if record != nil {
+		for name, value := range record.Variables {
+			vars[name] = value
+		}
+		backups = record.Backups
+
+		if record.Target != nil {
+			installer.target = overrideTarget(*record.Target, installer.target)
+			if installer.ownShell {
+				installer.sh = installer.newShell()
+			}
+		}
+	}
+
+	err = installer.elevate(true)
+	if err != nil {
+		return err
+	}
+
+	var stepErrs []*StepError
 
-	installer.printer.Recipe(installer.r.Metadata)
+	installer.observer.Recipe(installer.r.Metadata.UnitMetadata)
 
 	for i := stagesLen; i > 0; i-- {
 		stage := stages[i-1]
 		stageIndex := stagesLen - i
 
-		installer.printer.Stage(stageIndex, stage)
+		installer.observer.Stage(stageIndex, stage)
+		stageStart := time.Now()
 
 		stepsLen := len(stage.Steps)
 		for j := stepsLen; j > 0; j-- {
 			step := stage.Steps[j-1]
 			stepIndex := stepsLen - j
 
-			installer.printer.Step(stepIndex, stepsLen, step.Metadata)
+			if err := installer.ctx.Err(); err != nil {
+				installer.observer.StageFinished(time.Since(stageStart))
+				return errors.Wrap(err, "while reverting recipe")
+			}
+
+			installer.observer.Step(stepIndex, stepsLen, step.Metadata)
+
+			stageIdx, stepIdx := i-1, j-1
+			hook := stepHook{
+				after: func() error {
+					return installer.restoreFiles(backups, stageIdx, stepIdx)
+				},
+			}
+
+			err := installer.executeStep(stage, step, step.Rollback, vars, nil, installer.failFast, hook)
+			if err != nil {
+				stepErrs = append(stepErrs, newStepErrors(i-1, j-1, stage, step, err)...)
+				if installer.failFast {
+					installer.observer.StageFinished(time.Since(stageStart))
+					return &RollbackError{Errors: stepErrs}
+				}
+			}
+		}
+
+		installer.observer.StageFinished(time.Since(stageStart))
+	}
+
+	if len(stepErrs) > 0 {
+		return &RollbackError{Errors: stepErrs}
+	}
+
+	return installer.deleteState()
+}
+
+func (installer *Installer) executeStep(stage recipe.Stage, step recipe.Step, command shell.Command, vars Variables, t *timings, stopOnError bool, hook stepHook) error {
+	start := time.Now()
+	skipped, err := installer.runStep(step, command, vars, stopOnError, hook)
+	duration := time.Since(start)
+
+	if skipped {
+		t.add(stage, step, state.StepSkipped, 0)
+		installer.observer.StepSkipped(step.When)
+		return nil
+	}
+
+	status := state.StepApplied
+	if err != nil {
+		status = state.StepFailed
+	}
+	t.add(stage, step, status, duration)
+
+	installer.observer.StepFinished(duration, err)
+	return err
+}
+
+func (installer *Installer) runStep(step recipe.Step, command shell.Command, vars Variables, stopOnError bool, hook stepHook) (bool, error) {
+	if command.IsEmpty() && len(step.Backup) == 0 {
+		return false, nil
+	}
+
+	shouldRun, err := vars.evaluateCondition(step.When)
+	if err != nil {
+		installer.observer.ExecError(err.Error())
+		return false, err
+	}
+
+	if !shouldRun {
+		return true, nil
+	}
+
+	if hook.before != nil {
+		if err := hook.before(); err != nil {
+			installer.observer.ExecError(err.Error())
+			return false, err
+		}
+	}
+
+	if !command.IsEmpty() {
+		if installer.usesTemplates() {
+			command, err = vars.renderCommand(command)
+			if err != nil {
+				installer.observer.ExecError(err.Error())
+				return false, err
+			}
+		}
+
+		var output string
+		output, err = installer.sh.Exec(command, stopOnError)
+		vars.register(command, output)
+	}
+
+	if hook.after != nil {
+		if afterErr := hook.after(); afterErr != nil {
+			installer.observer.ExecError(afterErr.Error())
+			if err == nil {
+				err = afterErr
+			}
+		}
+	}
+
+	return false, err
+}
+
+// stepHook contains additional actions of a step, which are run only if the step isn't skipped
+type stepHook struct {
+	before func() error
+	after  func() error
+}
+
+// previousBackups returns backups saved by the previous installation of the recipe by file paths.
+// They contain original files, which were changed by the previous installation.
+func (installer *Installer) previousBackups() (map[string]state.Backup, error) {
+	previous := make(map[string]state.Backup)
+	if installer.dryRun {
+		return previous, nil
+	}
+
+	record, err := installer.loadRecord()
+	if err != nil || record == nil {
+		return previous, err
+	}
+
+	for _, b := range record.Backups {
+		// The earliest backup of a path is kept
+		if _, ok := previous[b.Path]; !ok {
+			previous[b.Path] = b
+		}
+	}
+
+	return previous, nil
+}
+
+// keepBackups appends previous backups, which weren't reused by the installation, so that original files
+// are still restored on rollback
+func keepBackups(backups []state.Backup, previous map[string]state.Backup) []state.Backup {
+	paths := make([]string, 0, len(previous))
+	for path := range previous {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		backups = append(backups, previous[path])
+	}
+
+	return backups
+}
+
+// backupFiles copies files listed in the step backups into the run, which is created on the first backup.
+// Copied files are appended to backups. If a file was backed up by the previous installation, its backup
+// is moved to the step instead, as the file already contains changes of the previous installation.
+func (installer *Installer) backupFiles(run *backup.Run, stageIndex, stepIndex int, step recipe.Step, vars Variables, previous map[string]state.Backup, backups *[]state.Backup) (*backup.Run, error) {
+	if len(step.Backup) == 0 || installer.dryRun {
+		return run, nil
+	}
+
+	if installer.backups == nil {
+		return run, errors.New("Backup store isn't configured")
+	}
+
+	for _, p := range step.Backup {
+		rendered := p
+		if installer.usesTemplates() {
+			var err error
+			rendered, err = vars.render("backup", p)
+			if err != nil {
+				return run, err
+			}
+		}
+
+		filePath, err := installer.resolvePath(rendered)
+		if err != nil {
+			return run, err
+		}
+
+		if b, ok := previous[filePath]; ok {
+			delete(previous, filePath)
+			b.Stage, b.Step = stageIndex, stepIndex
+			*backups = append(*backups, b)
+			installer.observer.ExecOutput(fmt.Sprintf("Kept previous backup of %s", filePath))
+			continue
+		}
 
-			err := installer.sh.Exec(step.Rollback, false)
+		if run == nil {
+			run, err = installer.backups.Create(installer.r.Metadata.Name)
 			if err != nil {
-				hasErrorOccurred = true
+				return nil, errors.Wrap(err, "while creating backup")
 			}
 		}
+
+		b, err := run.Snapshot(stageIndex, stepIndex, filePath)
+		if err != nil {
+			return run, err
+		}
+
+		*backups = append(*backups, b)
+		installer.observer.ExecOutput(fmt.Sprintf("Backed up %s", filePath))
+	}
+
+	return run, nil
+}
+
+// restoreFiles restores files backed up by a given step, even if its rollback command failed
+func (installer *Installer) restoreFiles(backups []state.Backup, stageIndex, stepIndex int) error {
+	if installer.dryRun {
+		return nil
+	}
+
+	var errs []error
+	for _, b := range backups {
+		if b.Stage != stageIndex || b.Step != stepIndex {
+			continue
+		}
+
+		if err := backup.Restore(b); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		installer.observer.ExecOutput(fmt.Sprintf("Restored %s", b.Path))
+	}
+
+	if len(errs) > 0 {
+		return &RestoreError{Errors: errs}
+	}
+
+	return nil
+}
+
+// elevate asks user to confirm commands, which run with elevated privileges, and asks for credentials upfront,
+// if any command of the operation runs as root or as another user
+func (installer *Installer) elevate(rollback bool) error {
+	if installer.dryRun {
+		return nil
+	}
+
+	var defaultUser string
+	if installer.target != nil {
+		defaultUser = installer.target.User
+	}
+
+	commands := installer.r.PrivilegedCommands(rollback, defaultUser)
+	if len(commands) == 0 {
+		return nil
+	}
+
+	if installer.confirm != nil {
+		err := installer.confirm(installer.r.Metadata.Name, commands)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !installer.ownShell {
+		return nil
+	}
+
+	count := 0
+	for _, command := range commands {
+		if command.User != "" {
+			count++
+		}
+	}
+
+	if count == 0 {
+		return nil
+	}
+
+	noun := "commands"
+	if count == 1 {
+		noun = "command"
+	}
+
+	reason := fmt.Sprintf("Recipe `%s` needs elevated privileges for %d %s", installer.r.Metadata.Name, count, noun)
+	return installer.elevator.Prime(installer.ctx, reason)
+}
+
+// env returns environment variables, which describe the target of the installation
+func (installer *Installer) env() []string {
+	if installer.target == nil {
+		return nil
+	}
+
+	var env []string
+	if installer.target.Home != "" {
+		env = append(env, fmt.Sprintf("HOME=%s", installer.target.Home))
+	}
+	if installer.target.Prefix != "" {
+		env = append(env, fmt.Sprintf("%s=%s", PrefixEnv, installer.target.Prefix))
+	}
+
+	return env
+}
+
+// resolvePath resolves a path from the recipe against the target of the installation
+func (installer *Installer) resolvePath(p string) (string, error) {
+	if installer.target == nil {
+		return path.ExpandHome(p)
+	}
+
+	home := installer.target.Home
+	if home == "" && installer.target.User != "" {
+		var err error
+		home, err = shell.HomeDir(installer.target.User)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return path.Resolve(p, home, installer.target.Prefix)
+}
+
+// overrideTarget returns the saved target with its fields overridden by non-empty fields of the given one
+func overrideTarget(saved state.Target, target *state.Target) *state.Target {
+	if target == nil {
+		return &saved
+	}
+
+	if target.Home != "" {
+		saved.Home = target.Home
+	}
+	if target.Prefix != "" {
+		saved.Prefix = target.Prefix
 	}
+	if target.User != "" {
+		saved.User = target.User
+	}
+
+	return &saved
+}
 
-	if hasErrorOccurred {
-		return errors.New("Error(s) received during steps execution. See the logs for details")
+// snapshot returns a snapshot of tracked files, or nil if changes aren't tracked
+func (installer *Installer) snapshot(previous fstrack.Snapshot) fstrack.Snapshot {
+	if installer.tracker == nil || installer.dryRun {
+		return nil
+	}
+
+	return installer.tracker.Snapshot(previous)
+}
+
+func (installer *Installer) loadRecord() (*state.Record, error) {
+	if installer.store == nil {
+		return nil, nil
+	}
+
+	record, err := installer.store.Get(installer.r.Metadata.Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "while loading recipe state")
+	}
+
+	return record, nil
+}
+
+// saveState persists the recipe state and returns given operation error, if there is any
+func (installer *Installer) saveState(status state.Status, vars Variables, t *timings, backups []state.Backup, changes []state.FileChange, operationErr error) error {
+	if installer.store == nil || installer.dryRun {
+		return operationErr
+	}
+
+	definition, err := json.Marshal(installer.r)
+	if err != nil {
+		return stateError(errors.Wrap(err, "while encoding recipe"), operationErr)
+	}
+
+	checksum, err := installer.r.Checksum()
+	if err != nil {
+		return stateError(err, operationErr)
+	}
+
+	err = installer.store.Save(state.Record{
+		Recipe:     installer.r.Metadata.Name,
+		Status:     status,
+		Source:     installer.source,
+		Target:     installer.target,
+		Checksum:   checksum,
+		Parameters: installer.parameters,
+		Variables:  vars,
+		Duration:   state.Duration(time.Since(t.start)),
+		Steps:      t.steps,
+		Backups:    backups,
+		Changes:    changes,
+		Definition: definition,
+	})
+	if err != nil {
+		return stateError(errors.Wrap(err, "while saving recipe state"), operationErr)
+	}
+
+	return operationErr
+}
+
+// stateError returns the operation error, if there is any, as it is more important than the state error
+func stateError(err, operationErr error) error {
+	if operationErr != nil {
+		return operationErr
+	}
+
+	return err
+}
+
+func (installer *Installer) deleteState() error {
+	if installer.store == nil || installer.dryRun {
+		return nil
+	}
+
+	err := installer.store.Delete(installer.r.Metadata.Name)
+	if err != nil {
+		return errors.Wrap(err, "while removing recipe state")
 	}
 
 	return nil
 }
+
+// timings collects statuses and durations of steps executed during a single operation.
+// Nil timings ignore all steps.
+type timings struct {
+	start time.Time
+	steps []state.StepTiming
+}
+
+func newTimings() *timings {
+	return &timings{start: time.Now()}
+}
+
+func (t *timings) add(stage recipe.Stage, step recipe.Step, status state.StepStatus, duration time.Duration) {
+	if t == nil {
+		return
+	}
+
+	t.steps = append(t.steps, state.StepTiming{
+		Stage:    stage.Metadata.Name,
+		Step:     step.Metadata.Name,
+		Status:   status,
+		Duration: state.Duration(duration),
+	})
+}
@@ -1,14 +1,27 @@
 package installer_test
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+
 	"github.com/pkg/errors"
-	printerAutomock "github.com/pkosiec/terminer/internal/printer/automock"
+	"github.com/pkosiec/terminer/pkg/backup"
+	"github.com/pkosiec/terminer/pkg/elevation"
+	"github.com/pkosiec/terminer/pkg/fstrack"
 	"github.com/pkosiec/terminer/pkg/installer"
+	observerAutomock "github.com/pkosiec/terminer/pkg/installer/automock"
 	"github.com/pkosiec/terminer/pkg/recipe"
 	"github.com/pkosiec/terminer/pkg/shared"
 	"github.com/pkosiec/terminer/pkg/shell"
 	"github.com/pkosiec/terminer/pkg/shell/automock"
+	"github.com/pkosiec/terminer/pkg/state"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"runtime"
 	"testing"
@@ -18,8 +31,8 @@ func TestNew(t *testing.T) {
 	t.Run("Empty recipe", func(t *testing.T) {
 		var r *recipe.Recipe
 
-		p := &printerAutomock.Printer{}
-		_, err := installer.New(r, p)
+		p := &observerAutomock.Observer{}
+		_, err := installer.New(r, installer.WithObserver(p))
 
 		require.Error(t, err)
 	})
@@ -27,18 +40,27 @@ func TestNew(t *testing.T) {
 	t.Run("Invalid recipe", func(t *testing.T) {
 		r := fixRecipe("testos")
 
-		p := &printerAutomock.Printer{}
-		_, err := installer.New(r, p)
+		p := &observerAutomock.Observer{}
+		_, err := installer.New(r, installer.WithObserver(p))
 
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "Invalid operating system")
 	})
 
+	t.Run("Shell in dry run", func(t *testing.T) {
+		r := fixRecipe(runtime.GOOS)
+
+		_, err := installer.New(r, installer.WithShell(&automock.Shell{}), installer.WithDryRun())
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Both shell and dry run")
+	})
+
 	t.Run("Valid recipe", func(t *testing.T) {
 		r := fixRecipe(runtime.GOOS)
 
-		p := &printerAutomock.Printer{}
-		_, err := installer.New(r, p)
+		p := &observerAutomock.Observer{}
+		_, err := installer.New(r, installer.WithObserver(p))
 
 		require.NoError(t, err)
 	})
@@ -48,9 +70,11 @@ func TestInstaller_Install(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		r := fixRecipe(runtime.GOOS)
 
-		p := &printerAutomock.Printer{}
-		p.On("SetContext", shared.OperationInstall, 2).Return().Once()
-		p.On("Recipe", r.Metadata).Return().Once()
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", shared.OperationInstall, mock.Anything, 2).Return().Once()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return().Once()
+		p.On("StageFinished", mock.Anything).Return().Times(2)
+		p.On("StepFinished", mock.Anything, nil).Return().Times(4)
 		defer p.AssertExpectations(t)
 
 		shImpl := &automock.Shell{}
@@ -61,15 +85,13 @@ func TestInstaller_Install(t *testing.T) {
 
 			for stepIdx, step := range stage.Steps {
 				p.On("Step", stepIdx, len(stage.Steps), step.Metadata).Return().Once()
-				shImpl.On("Exec", fixCommand(step.Execute.Run), true).Return(nil).Once()
+				shImpl.On("Exec", fixCommand(step.Execute.Run), true).Return("", nil).Once()
 			}
 		}
 
-		i, err := installer.New(r, p)
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl))
 		require.NoError(t, err)
 
-		i.SetShell(shImpl)
-
 		err = i.Install()
 		require.NoError(t, err)
 	})
@@ -79,9 +101,11 @@ func TestInstaller_Install(t *testing.T) {
 		testErr := errors.New("Test Err")
 		r := fixRecipe(runtime.GOOS)
 
-		p := &printerAutomock.Printer{}
-		p.On("SetContext", shared.OperationInstall, 2).Return().Once()
-		p.On("Recipe", r.Metadata).Return().Once()
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", shared.OperationInstall, mock.Anything, 2).Return().Once()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return().Once()
+		p.On("StageFinished", mock.Anything).Return().Once()
+		p.On("StepFinished", mock.Anything, testErr).Return().Once()
 		defer p.AssertExpectations(t)
 
 		stage := r.Stages[0]
@@ -90,17 +114,54 @@ func TestInstaller_Install(t *testing.T) {
 		p.On("Step", 0, len(stage.Steps), step.Metadata).Return().Once()
 
 		shImpl := &automock.Shell{}
-		shImpl.On("Exec", fixCommand([]string{"echo \"C1/1\""}), true).Return(testErr).Once()
+		shImpl.On("Exec", fixCommand([]string{"echo \"C1/1\""}), true).Return("", testErr).Once()
 		defer shImpl.AssertExpectations(t)
 
-		i, err := installer.New(r, p)
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl))
 		require.NoError(t, err)
 
-		i.SetShell(shImpl)
-
 		err = i.Install()
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), testErr.Error())
+
+		var stepErr *installer.StepError
+		require.True(t, errors.As(err, &stepErr))
+		assert.Equal(t, 0, stepErr.StageIndex)
+		assert.Equal(t, 0, stepErr.StepIndex)
+		assert.Equal(t, "Stage 1", stepErr.Stage)
+		assert.Equal(t, "Step 1", stepErr.Step)
+		assert.Equal(t, -1, stepErr.ExitCode)
+		assert.Equal(t, testErr, errors.Cause(err))
+	})
+
+	t.Run("Exit code", func(t *testing.T) {
+		r := fixRecipe(runtime.GOOS)
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", shared.OperationInstall, mock.Anything, 2).Return().Once()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return().Once()
+		p.On("StageFinished", mock.Anything).Return().Once()
+		p.On("StepFinished", mock.Anything, mock.Anything).Return().Once()
+		p.On("Stage", 0, r.Stages[0]).Return().Once()
+		p.On("Step", 0, len(r.Stages[0].Steps), r.Stages[0].Steps[0].Metadata).Return().Once()
+		defer p.AssertExpectations(t)
+
+		exitErr := &shell.ExitError{Command: "echo \"C1/1\"", ExitCode: 3, Stderr: "Error"}
+		shImpl := &automock.Shell{}
+		shImpl.On("Exec", fixCommand([]string{"echo \"C1/1\""}), true).Return("", errors.Wrap(exitErr, "while executing")).Once()
+		defer shImpl.AssertExpectations(t)
+
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl))
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.Error(t, err)
+
+		var stepErr *installer.StepError
+		require.True(t, errors.As(err, &stepErr))
+		assert.Equal(t, "echo \"C1/1\"", stepErr.Command)
+		assert.Equal(t, 3, stepErr.ExitCode)
+		assert.Equal(t, "Error", stepErr.Stderr)
 	})
 }
 
@@ -108,9 +169,11 @@ func TestInstaller_Rollback(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		r := fixRecipe(runtime.GOOS)
 
-		p := &printerAutomock.Printer{}
-		p.On("SetContext", shared.OperationRollback, 2).Return().Once()
-		p.On("Recipe", r.Metadata).Return().Once()
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", shared.OperationRollback, mock.Anything, 2).Return().Once()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return().Once()
+		p.On("StageFinished", mock.Anything).Return().Times(2)
+		p.On("StepFinished", mock.Anything, nil).Return().Times(4)
 		defer p.AssertExpectations(t)
 
 		stage := r.Stages[1]
@@ -128,15 +191,13 @@ func TestInstaller_Rollback(t *testing.T) {
 
 		for _, stage := range r.Stages {
 			for _, step := range stage.Steps {
-				shImpl.On("Exec", fixCommand(step.Rollback.Run), false).Return(nil).Once()
+				shImpl.On("Exec", fixCommand(step.Rollback.Run), false).Return("", nil).Once()
 			}
 		}
 
-		i, err := installer.New(r, p)
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl))
 		require.NoError(t, err)
 
-		i.SetShell(shImpl)
-
 		err = i.Rollback()
 		require.NoError(t, err)
 	})
@@ -146,9 +207,11 @@ func TestInstaller_Rollback(t *testing.T) {
 		testErr := errors.New("Test Err")
 		r := fixRecipe(runtime.GOOS)
 
-		p := &printerAutomock.Printer{}
-		p.On("SetContext", shared.OperationRollback, 2).Return().Once()
-		p.On("Recipe", r.Metadata).Return().Once()
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", shared.OperationRollback, mock.Anything, 2).Return().Once()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return().Once()
+		p.On("StageFinished", mock.Anything).Return().Times(2)
+		p.On("StepFinished", mock.Anything, testErr).Return().Times(4)
 
 		stage := r.Stages[1]
 		p.On("Stage", 0, stage).Return().Once()
@@ -164,23 +227,873 @@ func TestInstaller_Rollback(t *testing.T) {
 		shImpl := &automock.Shell{}
 		defer shImpl.AssertExpectations(t)
 
-		i, err := installer.New(r, p)
-		require.NoError(t, err)
-
 		for _, stage := range r.Stages {
 			for _, step := range stage.Steps {
-				shImpl.On("Exec", fixCommand(step.Rollback.Run), false).Return(testErr).Once()
+				shImpl.On("Exec", fixCommand(step.Rollback.Run), false).Return("", testErr).Once()
 			}
 		}
 
-		i.SetShell(shImpl)
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl))
+		require.NoError(t, err)
+
+		err = i.Rollback()
+		require.Error(t, err)
+
+		var rollbackErr *installer.RollbackError
+		require.True(t, errors.As(err, &rollbackErr))
+		require.Len(t, rollbackErr.Errors, 4)
+		assert.Equal(t, 1, rollbackErr.Errors[0].StageIndex)
+		assert.Equal(t, 1, rollbackErr.Errors[0].StepIndex)
+		assert.Equal(t, "Stage 2", rollbackErr.Errors[0].Stage)
+		assert.Equal(t, 0, rollbackErr.Errors[3].StageIndex)
+		assert.Equal(t, 0, rollbackErr.Errors[3].StepIndex)
+		assert.Contains(t, err.Error(), "4 error(s) occurred during rollback")
+	})
+
+	t.Run("Multiple command errors", func(t *testing.T) {
+		r := fixRecipe(runtime.GOOS)
+		r.Stages = r.Stages[:1]
+		r.Stages[0].Steps = r.Stages[0].Steps[:1]
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", shared.OperationRollback, mock.Anything, 1).Return().Once()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return().Once()
+		p.On("StageFinished", mock.Anything).Return().Once()
+		p.On("StepFinished", mock.Anything, mock.Anything).Return().Once()
+		p.On("Stage", 0, r.Stages[0]).Return().Once()
+		p.On("Step", 0, 1, r.Stages[0].Steps[0].Metadata).Return().Once()
+		defer p.AssertExpectations(t)
+
+		shImpl := &automock.Shell{}
+		shImpl.On("Exec", fixCommand(r.Stages[0].Steps[0].Rollback.Run), false).Return("", &shell.MultiError{
+			Errors: []error{
+				errors.Wrap(&shell.ExitError{Command: "foo", ExitCode: 1, Stderr: "foo failed"}, "while executing foo"),
+				errors.Wrap(&shell.ExitError{Command: "bar", ExitCode: 2}, "while executing bar"),
+			},
+		}).Once()
+		defer shImpl.AssertExpectations(t)
+
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl))
+		require.NoError(t, err)
+
+		err = i.Rollback()
+		require.Error(t, err)
+
+		var rollbackErr *installer.RollbackError
+		require.True(t, errors.As(err, &rollbackErr))
+		require.Len(t, rollbackErr.Errors, 2)
+		assert.Equal(t, "foo", rollbackErr.Errors[0].Command)
+		assert.Equal(t, 1, rollbackErr.Errors[0].ExitCode)
+		assert.Equal(t, "foo failed", rollbackErr.Errors[0].Stderr)
+		assert.Equal(t, "bar", rollbackErr.Errors[1].Command)
+		assert.Equal(t, 2, rollbackErr.Errors[1].ExitCode)
+	})
+}
+
+func TestInstaller_Options(t *testing.T) {
+	t.Run("Cancelled context", func(t *testing.T) {
+		r := fixRecipe(runtime.GOOS)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", shared.OperationInstall, mock.Anything, 2).Return().Once()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return().Once()
+		p.On("StageFinished", mock.Anything).Return().Once()
+		p.On("Stage", 0, r.Stages[0]).Return().Once()
+		defer p.AssertExpectations(t)
+
+		shImpl := &automock.Shell{}
+		defer shImpl.AssertExpectations(t)
+
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl), installer.WithContext(ctx))
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.Error(t, err)
+		assert.Equal(t, context.Canceled, errors.Cause(err))
+	})
+
+	t.Run("Fail fast rollback", func(t *testing.T) {
+		testErr := errors.New("Test Err")
+		r := fixRecipe(runtime.GOOS)
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", shared.OperationRollback, mock.Anything, 2).Return().Once()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return().Once()
+		p.On("StageFinished", mock.Anything).Return().Once()
+		p.On("StepFinished", mock.Anything, testErr).Return().Once()
+		p.On("Stage", 0, r.Stages[1]).Return().Once()
+		p.On("Step", 0, 2, r.Stages[1].Steps[1].Metadata).Return().Once()
+		defer p.AssertExpectations(t)
+
+		shImpl := &automock.Shell{}
+		shImpl.On("Exec", fixCommand(r.Stages[1].Steps[1].Rollback.Run), true).Return("", testErr).Once()
+		defer shImpl.AssertExpectations(t)
+
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl), installer.WithFailFast())
+		require.NoError(t, err)
 
 		err = i.Rollback()
 		require.Error(t, err)
-		assert.Contains(t, err.Error(), "Error(s) received during steps execution")
+
+		var rollbackErr *installer.RollbackError
+		require.True(t, errors.As(err, &rollbackErr))
+		assert.Len(t, rollbackErr.Errors, 1)
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		r := fixVariablesRecipe()
+		store := state.NewFileStore(t.TempDir())
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", shared.OperationInstall, mock.Anything, 1).Return().Once()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return().Once()
+		p.On("Stage", 0, r.Stages[0]).Return().Once()
+		p.On("StageFinished", mock.Anything).Return().Once()
+		p.On("Step", mock.Anything, 3, mock.Anything).Return().Times(3)
+		p.On("Command", "command -v zsh").Return().Once()
+		p.On("Command", "chsh -s <zsh_path>").Return().Once()
+		p.On("StepFinished", mock.Anything, nil).Return().Twice()
+		p.On("StepSkipped", "{{ .not_found }}").Return().Once()
+		defer p.AssertExpectations(t)
+
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithStateStore(store), installer.WithDryRun())
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.NoError(t, err)
+
+		record, err := store.Get(r.Metadata.Name)
+		require.NoError(t, err)
+		assert.Nil(t, record)
+	})
+
+	t.Run("Default observer", func(t *testing.T) {
+		r := fixVariablesRecipe()
+		r.Stages[0].Steps = r.Stages[0].Steps[2:]
+
+		i, err := installer.New(r, installer.WithDryRun())
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.NoError(t, err)
 	})
 }
 
+func TestInstaller_Variables(t *testing.T) {
+	t.Run("Register, render and persist", func(t *testing.T) {
+		r := fixVariablesRecipe()
+		store := state.NewFileStore(t.TempDir())
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", mock.Anything, mock.Anything, 1).Return()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return()
+		p.On("StageFinished", mock.Anything).Return()
+		p.On("StepFinished", mock.Anything, nil).Return().Times(5)
+		p.On("Stage", 0, r.Stages[0]).Return()
+		p.On("Step", mock.Anything, 3, mock.Anything).Return()
+		p.On("StepSkipped", "{{ .not_found }}").Return().Once()
+		defer p.AssertExpectations(t)
+
+		shImpl := &automock.Shell{}
+		shImpl.On("Exec", shell.Command{Run: []string{"command -v zsh"}, Register: "zsh_path"}, true).Return("/bin/zsh", nil).Once()
+		shImpl.On("Exec", shell.Command{Run: []string{"chsh -s /bin/zsh"}}, true).Return("", nil).Once()
+		shImpl.On("Exec", shell.Command{Run: []string{"chsh -s /bin/bash # was /bin/zsh"}}, false).Return("", nil).Once()
+		defer shImpl.AssertExpectations(t)
+
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl), installer.WithStateStore(store))
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.NoError(t, err)
+
+		record, err := store.Get(r.Metadata.Name)
+		require.NoError(t, err)
+		require.NotNil(t, record)
+		assert.Equal(t, state.StatusInstalled, record.Status)
+		assert.Equal(t, map[string]string{"zsh_path": "/bin/zsh"}, record.Variables)
+		require.Len(t, record.Steps, 3)
+		assert.Equal(t, state.StepSkipped, record.Steps[2].Status)
+
+		err = i.Rollback()
+		require.NoError(t, err)
+
+		record, err = store.Get(r.Metadata.Name)
+		require.NoError(t, err)
+		assert.Nil(t, record)
+	})
+
+	t.Run("Save state on failure", func(t *testing.T) {
+		testErr := errors.New("Test Err")
+		r := fixVariablesRecipe()
+		store := state.NewFileStore(t.TempDir())
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", mock.Anything, mock.Anything, 1).Return()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return()
+		p.On("StageFinished", mock.Anything).Return()
+		p.On("StepFinished", mock.Anything, mock.Anything).Return().Twice()
+		p.On("Stage", 0, r.Stages[0]).Return()
+		p.On("Step", mock.Anything, 3, mock.Anything).Return()
+
+		shImpl := &automock.Shell{}
+		shImpl.On("Exec", shell.Command{Run: []string{"command -v zsh"}, Register: "zsh_path"}, true).Return("/bin/zsh", nil).Once()
+		shImpl.On("Exec", shell.Command{Run: []string{"chsh -s /bin/zsh"}}, true).Return("", testErr).Once()
+		defer shImpl.AssertExpectations(t)
+
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl), installer.WithStateStore(store))
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.Error(t, err)
+
+		record, err := store.Get(r.Metadata.Name)
+		require.NoError(t, err)
+		require.NotNil(t, record)
+		assert.Equal(t, state.StatusFailed, record.Status)
+		assert.Equal(t, map[string]string{"zsh_path": "/bin/zsh"}, record.Variables)
+
+		require.Len(t, record.Steps, 2)
+		assert.Equal(t, state.StepApplied, record.Steps[0].Status)
+		assert.Equal(t, r.Stages[0].Steps[0].Metadata.Name, record.Steps[0].Step)
+		assert.Equal(t, state.StepFailed, record.Steps[1].Status)
+	})
+
+	t.Run("Missing variable", func(t *testing.T) {
+		r := fixVariablesRecipe()
+		r.Stages[0].Steps[1].Execute.Run = []string{"chsh -s {{ .shell_path }}"}
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", mock.Anything, mock.Anything, 1).Return()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return()
+		p.On("StageFinished", mock.Anything).Return()
+		p.On("StepFinished", mock.Anything, mock.Anything).Return().Twice()
+		p.On("Stage", 0, r.Stages[0]).Return()
+		p.On("Step", mock.Anything, 3, mock.Anything).Return()
+		p.On("ExecError", mock.Anything).Return().Once()
+		defer p.AssertExpectations(t)
+
+		shImpl := &automock.Shell{}
+		shImpl.On("Exec", shell.Command{Run: []string{"command -v zsh"}, Register: "zsh_path"}, true).Return("/bin/zsh", nil).Once()
+		defer shImpl.AssertExpectations(t)
+
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl))
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "shell_path")
+	})
+
+	t.Run("Literal braces without templates", func(t *testing.T) {
+		for _, register := range []bool{false, true} {
+			t.Run(fmt.Sprintf("Register %t", register), func(t *testing.T) {
+				r := fixRecipe(runtime.GOOS)
+				r.Stages = r.Stages[:1]
+				r.Stages[0].Steps[0].Execute.Run = []string{"docker inspect -f '{{.Id}}' foo"}
+				r.Stages[0].Steps[1].Execute = shell.Command{Run: []string{"command -v zsh"}}
+				if register {
+					r.Stages[0].Steps[1].Execute.Register = "zsh_path"
+				}
+
+				p := &observerAutomock.Observer{}
+				p.On("SetContext", mock.Anything, mock.Anything, 1).Return()
+				p.On("Recipe", r.Metadata.UnitMetadata).Return()
+				p.On("Stage", 0, r.Stages[0]).Return()
+				p.On("StageFinished", mock.Anything).Return()
+				p.On("Step", mock.Anything, 2, mock.Anything).Return()
+				p.On("StepFinished", mock.Anything, nil).Return().Twice()
+				defer p.AssertExpectations(t)
+
+				shImpl := &automock.Shell{}
+				shImpl.On("Exec", shell.Command{Run: []string{"docker inspect -f '{{.Id}}' foo"}}, true).Return("", nil).Once()
+				shImpl.On("Exec", r.Stages[0].Steps[1].Execute, true).Return("/bin/zsh", nil).Once()
+				defer shImpl.AssertExpectations(t)
+
+				i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl))
+				require.NoError(t, err)
+
+				err = i.Install()
+				require.NoError(t, err)
+			})
+		}
+	})
+
+	t.Run("Parameters", func(t *testing.T) {
+		r := fixVariablesRecipe()
+		r.Stages[0].Steps[1].Execute.Run = []string{"chsh -s {{ .shell_path }}"}
+		store := state.NewFileStore(t.TempDir())
+		parameters := map[string]string{"shell_path": "/bin/fish"}
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", mock.Anything, mock.Anything, 1).Return()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return()
+		p.On("StageFinished", mock.Anything).Return()
+		p.On("StepFinished", mock.Anything, nil).Return().Twice()
+		p.On("Stage", 0, r.Stages[0]).Return()
+		p.On("Step", mock.Anything, 3, mock.Anything).Return()
+		p.On("StepSkipped", "{{ .not_found }}").Return().Once()
+		defer p.AssertExpectations(t)
+
+		shImpl := &automock.Shell{}
+		shImpl.On("Exec", shell.Command{Run: []string{"command -v zsh"}, Register: "zsh_path"}, true).Return("/bin/zsh", nil).Once()
+		shImpl.On("Exec", shell.Command{Run: []string{"chsh -s /bin/fish"}}, true).Return("", nil).Once()
+		defer shImpl.AssertExpectations(t)
+
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl), installer.WithStateStore(store), installer.WithParameters(parameters))
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.NoError(t, err)
+
+		record, err := store.Get(r.Metadata.Name)
+		require.NoError(t, err)
+		require.NotNil(t, record)
+		assert.Equal(t, parameters, record.Parameters)
+		assert.Equal(t, "/bin/fish", record.Variables["shell_path"])
+
+		checksum, err := r.Checksum()
+		require.NoError(t, err)
+		assert.Equal(t, checksum, record.Checksum)
+
+		var definition recipe.Recipe
+		err = json.Unmarshal(record.Definition, &definition)
+		require.NoError(t, err)
+		assert.Equal(t, r.Metadata.Name, definition.Metadata.Name)
+	})
+}
+
+func TestInstaller_Backups(t *testing.T) {
+	t.Run("Back up and restore", func(t *testing.T) {
+		dir := t.TempDir()
+		rcPath := filepath.Join(dir, ".zshrc")
+		newPath := filepath.Join(dir, ".zprofile")
+		err := ioutil.WriteFile(rcPath, []byte("original\n"), 0640)
+		require.NoError(t, err)
+
+		r := fixBackupRecipe()
+		stateDir := t.TempDir()
+		store := state.NewFileStore(stateDir)
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", mock.Anything, mock.Anything, 1).Return()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return()
+		p.On("Stage", 0, r.Stages[0]).Return()
+		p.On("StageFinished", mock.Anything).Return()
+		p.On("Step", 0, 1, r.Stages[0].Steps[0].Metadata).Return()
+		p.On("StepFinished", mock.Anything, nil).Return().Twice()
+		p.On("ExecOutput", "Backed up "+rcPath).Return().Once()
+		p.On("ExecOutput", "Backed up "+newPath).Return().Once()
+		p.On("ExecOutput", "Restored "+rcPath).Return().Once()
+		p.On("ExecOutput", "Restored "+newPath).Return().Once()
+		defer p.AssertExpectations(t)
+
+		shImpl := &automock.Shell{}
+		shImpl.On("Exec", fixCommand(r.Stages[0].Steps[0].Execute.Run), true).Return("", nil).Run(func(args mock.Arguments) {
+			require.NoError(t, ioutil.WriteFile(rcPath, []byte("modified\n"), 0600))
+			require.NoError(t, ioutil.WriteFile(newPath, []byte("created\n"), 0600))
+		}).Once()
+		defer shImpl.AssertExpectations(t)
+
+		i, err := installer.New(r,
+			installer.WithObserver(p),
+			installer.WithShell(shImpl),
+			installer.WithStateStore(store),
+			installer.WithBackupStore(backup.NewStore(stateDir)),
+			installer.WithParameters(map[string]string{"dir": dir}),
+		)
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.NoError(t, err)
+
+		record, err := store.Get(r.Metadata.Name)
+		require.NoError(t, err)
+		require.NotNil(t, record)
+		require.Len(t, record.Backups, 2)
+		assert.Equal(t, rcPath, record.Backups[0].Path)
+		assert.Equal(t, newPath, record.Backups[1].Path)
+		assert.Empty(t, record.Backups[1].File)
+
+		err = i.Rollback()
+		require.NoError(t, err)
+
+		content, err := ioutil.ReadFile(rcPath)
+		require.NoError(t, err)
+		assert.Equal(t, "original\n", string(content))
+
+		info, err := os.Stat(rcPath)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+		_, err = os.Stat(newPath)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Restore failure", func(t *testing.T) {
+		dir := t.TempDir()
+		rcPath := filepath.Join(dir, ".zshrc")
+		err := ioutil.WriteFile(rcPath, []byte("original\n"), 0640)
+		require.NoError(t, err)
+
+		r := fixBackupRecipe()
+		r.Stages[0].Steps[0].Backup = []string{"{{ .dir }}/.zshrc"}
+		stateDir := t.TempDir()
+		store := state.NewFileStore(stateDir)
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", mock.Anything, mock.Anything, 1).Return()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return()
+		p.On("Stage", 0, r.Stages[0]).Return()
+		p.On("StageFinished", mock.Anything).Return()
+		p.On("Step", 0, 1, r.Stages[0].Steps[0].Metadata).Return()
+		p.On("StepFinished", mock.Anything, mock.Anything).Return().Twice()
+		p.On("ExecOutput", "Backed up "+rcPath).Return().Once()
+		p.On("ExecError", mock.Anything).Return().Once()
+		defer p.AssertExpectations(t)
+
+		shImpl := &automock.Shell{}
+		shImpl.On("Exec", fixCommand(r.Stages[0].Steps[0].Execute.Run), true).Return("", nil).Once()
+		defer shImpl.AssertExpectations(t)
+
+		i, err := installer.New(r,
+			installer.WithObserver(p),
+			installer.WithShell(shImpl),
+			installer.WithStateStore(store),
+			installer.WithBackupStore(backup.NewStore(stateDir)),
+			installer.WithParameters(map[string]string{"dir": dir}),
+		)
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.NoError(t, err)
+
+		record, err := store.Get(r.Metadata.Name)
+		require.NoError(t, err)
+		require.NotNil(t, record)
+		require.Len(t, record.Backups, 1)
+		err = os.Remove(record.Backups[0].File)
+		require.NoError(t, err)
+
+		err = i.Rollback()
+		require.Error(t, err)
+
+		var rollbackErr *installer.RollbackError
+		require.True(t, errors.As(err, &rollbackErr))
+		require.Len(t, rollbackErr.Errors, 1)
+
+		var restoreErr *installer.RestoreError
+		require.True(t, errors.As(rollbackErr.Errors[0], &restoreErr))
+		require.Len(t, restoreErr.Errors, 1)
+		assert.Contains(t, restoreErr.Errors[0].Error(), rcPath)
+
+		var multiErr *shell.MultiError
+		assert.False(t, errors.As(rollbackErr.Errors[0], &multiErr))
+	})
+
+	t.Run("Install twice", func(t *testing.T) {
+		dir := t.TempDir()
+		rcPath := filepath.Join(dir, ".zshrc")
+		newPath := filepath.Join(dir, ".zprofile")
+		err := ioutil.WriteFile(rcPath, []byte("original\n"), 0640)
+		require.NoError(t, err)
+
+		r := fixBackupRecipe()
+		stateDir := t.TempDir()
+		store := state.NewFileStore(stateDir)
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", mock.Anything, mock.Anything, 1).Return()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return()
+		p.On("Stage", 0, r.Stages[0]).Return()
+		p.On("StageFinished", mock.Anything).Return()
+		p.On("Step", 0, 1, r.Stages[0].Steps[0].Metadata).Return()
+		p.On("StepFinished", mock.Anything, nil).Return().Times(3)
+		p.On("ExecOutput", "Backed up "+rcPath).Return().Once()
+		p.On("ExecOutput", "Backed up "+newPath).Return().Once()
+		p.On("ExecOutput", "Kept previous backup of "+rcPath).Return().Once()
+		p.On("ExecOutput", "Kept previous backup of "+newPath).Return().Once()
+		p.On("ExecOutput", "Restored "+rcPath).Return().Once()
+		p.On("ExecOutput", "Restored "+newPath).Return().Once()
+		defer p.AssertExpectations(t)
+
+		shImpl := &automock.Shell{}
+		shImpl.On("Exec", fixCommand(r.Stages[0].Steps[0].Execute.Run), true).Return("", nil).Run(func(args mock.Arguments) {
+			require.NoError(t, ioutil.WriteFile(rcPath, []byte("modified\n"), 0600))
+			require.NoError(t, ioutil.WriteFile(newPath, []byte("created\n"), 0600))
+		}).Twice()
+		defer shImpl.AssertExpectations(t)
+
+		i, err := installer.New(r,
+			installer.WithObserver(p),
+			installer.WithShell(shImpl),
+			installer.WithStateStore(store),
+			installer.WithBackupStore(backup.NewStore(stateDir)),
+			installer.WithParameters(map[string]string{"dir": dir}),
+		)
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.NoError(t, err)
+		err = i.Install()
+		require.NoError(t, err)
+
+		record, err := store.Get(r.Metadata.Name)
+		require.NoError(t, err)
+		require.NotNil(t, record)
+		require.Len(t, record.Backups, 2)
+
+		err = i.Rollback()
+		require.NoError(t, err)
+
+		content, err := ioutil.ReadFile(rcPath)
+		require.NoError(t, err)
+		assert.Equal(t, "original\n", string(content))
+
+		_, err = os.Stat(newPath)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Missing backup store", func(t *testing.T) {
+		r := fixBackupRecipe()
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", mock.Anything, mock.Anything, 1).Return()
+		p.On("Recipe", r.Metadata.UnitMetadata).Return()
+		p.On("Stage", 0, r.Stages[0]).Return()
+		p.On("StageFinished", mock.Anything).Return()
+		p.On("Step", 0, 1, r.Stages[0].Steps[0].Metadata).Return()
+		p.On("StepFinished", mock.Anything, mock.Anything).Return().Once()
+		p.On("ExecError", mock.Anything).Return().Once()
+		defer p.AssertExpectations(t)
+
+		shImpl := &automock.Shell{}
+		defer shImpl.AssertExpectations(t)
+
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl), installer.WithParameters(map[string]string{"dir": t.TempDir()}))
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Backup store isn't configured")
+	})
+}
+
+func TestInstaller_Target(t *testing.T) {
+	home := t.TempDir()
+	rcPath := filepath.Join(home, ".zshrc")
+	err := ioutil.WriteFile(rcPath, []byte("original\n"), 0600)
+	require.NoError(t, err)
+
+	r := fixBackupRecipe()
+	r.Stages[0].Steps[0].Backup = []string{"~/.zshrc"}
+	stateDir := t.TempDir()
+	store := state.NewFileStore(stateDir)
+	target := state.Target{Home: home, Prefix: "/image"}
+
+	p := &observerAutomock.Observer{}
+	p.On("SetContext", mock.Anything, mock.Anything, 1).Return()
+	p.On("Recipe", r.Metadata.UnitMetadata).Return()
+	p.On("Stage", 0, r.Stages[0]).Return()
+	p.On("StageFinished", mock.Anything).Return()
+	p.On("Step", 0, 1, r.Stages[0].Steps[0].Metadata).Return()
+	p.On("StepFinished", mock.Anything, nil).Return().Twice()
+	p.On("ExecOutput", "Backed up "+rcPath).Return().Once()
+	p.On("ExecOutput", "Restored "+rcPath).Return().Once()
+	defer p.AssertExpectations(t)
+
+	shImpl := &automock.Shell{}
+	shImpl.On("Exec", fixCommand(r.Stages[0].Steps[0].Execute.Run), true).Return("", nil).Run(func(args mock.Arguments) {
+		require.NoError(t, ioutil.WriteFile(rcPath, []byte("modified\n"), 0600))
+	}).Once()
+	defer shImpl.AssertExpectations(t)
+
+	opts := []installer.Option{
+		installer.WithObserver(p),
+		installer.WithShell(shImpl),
+		installer.WithStateStore(store),
+		installer.WithBackupStore(backup.NewStore(stateDir)),
+	}
+
+	i, err := installer.New(r, append(opts, installer.WithTarget(target))...)
+	require.NoError(t, err)
+
+	err = i.Install()
+	require.NoError(t, err)
+
+	record, err := store.Get(r.Metadata.Name)
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, &target, record.Target)
+
+	// Rollback uses the target saved in the state
+	i, err = installer.New(r, opts...)
+	require.NoError(t, err)
+
+	err = i.Rollback()
+	require.NoError(t, err)
+
+	content, err := ioutil.ReadFile(rcPath)
+	require.NoError(t, err)
+	assert.Equal(t, "original\n", string(content))
+}
+
+func TestInstaller_RollbackTargetOverride(t *testing.T) {
+	current, err := user.Current()
+	require.NoError(t, err)
+
+	home := t.TempDir()
+	outPath := filepath.Join(t.TempDir(), "target")
+	r := fixBackupRecipe()
+	r.Stages[0].Steps[0].Backup = nil
+	r.Stages[0].Steps[0].Rollback = shell.Command{Run: []string{fmt.Sprintf(`echo "$HOME $%s" > %s`, installer.PrefixEnv, outPath)}}
+
+	store := state.NewFileStore(t.TempDir())
+	err = store.Save(state.Record{
+		Recipe: r.Metadata.Name,
+		Status: state.StatusInstalled,
+		Target: &state.Target{Home: home, Prefix: "/image", User: "nobody"},
+	})
+	require.NoError(t, err)
+
+	p := &observerAutomock.Observer{}
+	p.On("SetContext", shared.OperationRollback, mock.Anything, 1).Return()
+	p.On("Recipe", r.Metadata.UnitMetadata).Return()
+	p.On("Stage", 0, r.Stages[0]).Return()
+	p.On("StageFinished", mock.Anything).Return()
+	p.On("Step", 0, 1, r.Stages[0].Steps[0].Metadata).Return()
+	p.On("StepFinished", mock.Anything, nil).Return().Once()
+	p.On("Command", mock.Anything).Return().Maybe()
+	p.On("ExecTrace", mock.Anything).Return().Maybe()
+	p.On("CommandFinished", mock.Anything).Return().Maybe()
+	defer p.AssertExpectations(t)
+
+	// Only the user is given, so the home directory and the prefix are taken from the state.
+	// Commands run as the current user instead of the saved one, so they don't need elevation.
+	i, err := installer.New(r,
+		installer.WithObserver(p),
+		installer.WithStateStore(store),
+		installer.WithTarget(state.Target{User: current.Username}),
+	)
+	require.NoError(t, err)
+
+	err = i.Rollback()
+	require.NoError(t, err)
+
+	content, err := ioutil.ReadFile(outPath)
+	require.NoError(t, err)
+	assert.Equal(t, home+" /image\n", string(content))
+}
+
+func TestInstaller_ChangeTracking(t *testing.T) {
+	root := t.TempDir()
+	rcPath := filepath.Join(root, ".zshrc")
+	profilePath := filepath.Join(root, ".zprofile")
+	err := ioutil.WriteFile(rcPath, []byte("original\n"), 0600)
+	require.NoError(t, err)
+
+	r := fixBackupRecipe()
+	r.Stages[0].Steps[0].Backup = nil
+	r.Stages[0].Steps = append(r.Stages[0].Steps, recipe.Step{
+		Metadata: recipe.UnitMetadata{Name: "Configure profile"},
+		Execute:  shell.Command{Run: []string{"touch ~/.zprofile"}},
+	})
+	store := state.NewFileStore(t.TempDir())
+
+	p := &observerAutomock.Observer{}
+	p.On("SetContext", mock.Anything, mock.Anything, 1).Return()
+	p.On("Recipe", r.Metadata.UnitMetadata).Return()
+	p.On("Stage", 0, r.Stages[0]).Return()
+	p.On("StageFinished", mock.Anything).Return()
+	p.On("Step", 0, 2, r.Stages[0].Steps[0].Metadata).Return()
+	p.On("Step", 1, 2, r.Stages[0].Steps[1].Metadata).Return()
+	p.On("StepFinished", mock.Anything, nil).Return().Twice()
+	defer p.AssertExpectations(t)
+
+	shImpl := &automock.Shell{}
+	shImpl.On("Exec", fixCommand(r.Stages[0].Steps[0].Execute.Run), true).Return("", nil).Run(func(args mock.Arguments) {
+		require.NoError(t, ioutil.WriteFile(rcPath, []byte("modified\n"), 0600))
+	}).Once()
+	shImpl.On("Exec", fixCommand(r.Stages[0].Steps[1].Execute.Run), true).Return("", nil).Run(func(args mock.Arguments) {
+		require.NoError(t, ioutil.WriteFile(profilePath, nil, 0600))
+	}).Once()
+	defer shImpl.AssertExpectations(t)
+
+	i, err := installer.New(r,
+		installer.WithObserver(p),
+		installer.WithShell(shImpl),
+		installer.WithStateStore(store),
+		installer.WithChangeTracking(fstrack.New([]string{root})),
+	)
+	require.NoError(t, err)
+
+	err = i.Install()
+	require.NoError(t, err)
+
+	record, err := store.Get(r.Metadata.Name)
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, []state.FileChange{
+		{Stage: 0, Step: 0, Path: rcPath, Type: state.ChangeModified},
+		{Stage: 0, Step: 1, Path: profilePath, Type: state.ChangeCreated},
+	}, record.Changes)
+}
+
+func TestInstaller_Elevation(t *testing.T) {
+	t.Run("Root command", func(t *testing.T) {
+		markerPath := filepath.Join(t.TempDir(), "marker")
+		r := fixBackupRecipe()
+		r.Stages[0].Steps[0].Backup = nil
+		r.Stages[0].Steps[0].Execute = shell.Command{Run: []string{"touch " + markerPath}, Root: true}
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", shared.OperationInstall, mock.Anything, 1).Return()
+		defer p.AssertExpectations(t)
+
+		elevator := elevation.New(elevation.MethodSu, elevation.WithNonInteractive())
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithElevator(elevator))
+		require.NoError(t, err)
+
+		// Elevation fails before any step is executed
+		err = i.Install()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Recipe `Backups` needs elevated privileges for 1 command")
+
+		_, err = os.Stat(markerPath)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Command of another user", func(t *testing.T) {
+		r := fixBackupRecipe()
+		r.Stages[0].Steps[0].Backup = nil
+		r.Stages[0].Steps[0].Execute = shell.Command{Run: []string{"echo 'Foo'", "echo 'Bar'"}, User: "someone"}
+
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", shared.OperationInstall, mock.Anything, 1).Return()
+		defer p.AssertExpectations(t)
+
+		elevator := elevation.New(elevation.MethodSu, elevation.WithNonInteractive())
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithElevator(elevator))
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Recipe `Backups` needs elevated privileges for 2 commands")
+		assert.NotContains(t, err.Error(), "root for")
+	})
+}
+
+func TestInstaller_Confirmation(t *testing.T) {
+	r := fixBackupRecipe()
+	r.Stages[0].Steps[0].Backup = nil
+	r.Stages[0].Steps[0].Execute = shell.Command{Run: []string{"echo 'Foo'", "sudo chsh -s /bin/zsh"}}
+
+	t.Run("Not confirmed", func(t *testing.T) {
+		p := &observerAutomock.Observer{}
+		p.On("SetContext", shared.OperationInstall, mock.Anything, 1).Return()
+		defer p.AssertExpectations(t)
+
+		shImpl := &automock.Shell{}
+		defer shImpl.AssertExpectations(t)
+
+		var confirmed []recipe.PrivilegedCommand
+		confirm := func(recipeName string, commands []recipe.PrivilegedCommand) error {
+			assert.Equal(t, r.Metadata.Name, recipeName)
+			confirmed = commands
+			return errors.New("Not confirmed")
+		}
+
+		i, err := installer.New(r, installer.WithObserver(p), installer.WithShell(shImpl), installer.WithConfirmation(confirm))
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.EqualError(t, err, "Not confirmed")
+		require.Len(t, confirmed, 1)
+		assert.Equal(t, "sudo chsh -s /bin/zsh", confirmed[0].Command)
+	})
+
+	t.Run("Dry run", func(t *testing.T) {
+		confirm := func(string, []recipe.PrivilegedCommand) error {
+			return errors.New("Should not be called")
+		}
+
+		i, err := installer.New(r, installer.WithDryRun(), installer.WithConfirmation(confirm))
+		require.NoError(t, err)
+
+		err = i.Install()
+		require.NoError(t, err)
+	})
+}
+
+func fixVariablesRecipe() *recipe.Recipe {
+	return &recipe.Recipe{
+		OS: runtime.GOOS,
+		Metadata: recipe.RecipeMetadata{UnitMetadata: recipe.UnitMetadata{
+			Name: "Variables",
+		}},
+		Templates: true,
+		Stages: []recipe.Stage{
+			{
+				Metadata: recipe.UnitMetadata{
+					Name: "Stage 1",
+				},
+				Steps: []recipe.Step{
+					{
+						Execute: shell.Command{
+							Run:      []string{"command -v zsh"},
+							Register: "zsh_path",
+						},
+					},
+					{
+						When: "{{ .zsh_path }}",
+						Execute: shell.Command{
+							Run: []string{"chsh -s {{ .zsh_path }}"},
+						},
+						Rollback: shell.Command{
+							Run: []string{"chsh -s /bin/bash # was {{ .zsh_path }}"},
+						},
+					},
+					{
+						When: "{{ .not_found }}",
+						Execute: shell.Command{
+							Run: []string{"echo 'Should not be executed'"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func fixBackupRecipe() *recipe.Recipe {
+	return &recipe.Recipe{
+		OS: runtime.GOOS,
+		Metadata: recipe.RecipeMetadata{UnitMetadata: recipe.UnitMetadata{
+			Name: "Backups",
+		}},
+		Templates: true,
+		Stages: []recipe.Stage{
+			{
+				Metadata: recipe.UnitMetadata{
+					Name: "Stage 1",
+				},
+				Steps: []recipe.Step{
+					{
+						Metadata: recipe.UnitMetadata{
+							Name: "Configure Zsh",
+						},
+						Backup: []string{"{{ .dir }}/.zshrc", "{{ .dir }}/.zprofile"},
+						Execute: shell.Command{
+							Run: []string{"echo 'modified' > ~/.zshrc"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 func fixCommand(run []string) shell.Command {
 	return shell.Command{
 		Run: run,
@@ -190,10 +1103,10 @@ func fixCommand(run []string) shell.Command {
 func fixRecipe(os string) *recipe.Recipe {
 	return &recipe.Recipe{
 		OS: os,
-		Metadata: recipe.UnitMetadata{
+		Metadata: recipe.RecipeMetadata{UnitMetadata: recipe.UnitMetadata{
 			Name:        "Recipe",
 			Description: "Recipe Description",
-		},
+		}},
 		Stages: []recipe.Stage{
 			{
 				Metadata: recipe.UnitMetadata{
@@ -0,0 +1,71 @@
+package installer
+
+import (
+	"time"
+
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shared"
+)
+
+// Observer receives events of recipe operations, such as stage and step progress, and command output.
+// Interactive is called before and after an interactive command uses the terminal directly.
+// SetContext is called when an operation on a recipe starts, before anything else can fail.
+// Stage and step indexes are indexes in the order of execution, which is reversed during rollback.
+//go:generate mockery -name=Observer -output=automock -outpkg=automock -case=underscore
+type Observer interface {
+	SetContext(operation shared.Operation, recipeName string, stagesCount int)
+	Recipe(r recipe.UnitMetadata)
+	Stage(stageIndex int, s recipe.Stage)
+	StageFinished(duration time.Duration)
+	Step(stepIndex, steps int, s recipe.UnitMetadata)
+	StepSkipped(condition string)
+	StepFinished(duration time.Duration, err error)
+	Command(cmd string)
+	CommandFinished(duration time.Duration)
+	ExecOutput(output string)
+	ExecError(output string)
+	ExecTrace(trace string)
+	Interactive(active bool)
+}
+
+// NopObserver is an Observer, which ignores all events
+type NopObserver struct{}
+
+// SetContext does nothing
+func (NopObserver) SetContext(shared.Operation, string, int) {}
+
+// Recipe does nothing
+func (NopObserver) Recipe(recipe.UnitMetadata) {}
+
+// Stage does nothing
+func (NopObserver) Stage(int, recipe.Stage) {}
+
+// StageFinished does nothing
+func (NopObserver) StageFinished(time.Duration) {}
+
+// Step does nothing
+func (NopObserver) Step(int, int, recipe.UnitMetadata) {}
+
+// StepSkipped does nothing
+func (NopObserver) StepSkipped(string) {}
+
+// StepFinished does nothing
+func (NopObserver) StepFinished(time.Duration, error) {}
+
+// Command does nothing
+func (NopObserver) Command(string) {}
+
+// CommandFinished does nothing
+func (NopObserver) CommandFinished(time.Duration) {}
+
+// ExecOutput does nothing
+func (NopObserver) ExecOutput(string) {}
+
+// ExecError does nothing
+func (NopObserver) ExecError(string) {}
+
+// ExecTrace does nothing
+func (NopObserver) ExecTrace(string) {}
+
+// Interactive does nothing
+func (NopObserver) Interactive(bool) {}
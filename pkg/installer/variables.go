@@ -0,0 +1,96 @@
+package installer
+
+import (
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/pkg/shell"
+)
+
+// Variables stores values of variables registered by commands during a single recipe operation
+type Variables map[string]string
+
+const conditionTemplateName = "condition"
+
+var falsyConditionResults = []string{"", "false", "no", "0"}
+
+// render executes given text template with variables as data.
+// Missing variables cause an error, unless the template is a condition, for which missing variables are empty.
+func (v Variables) render(name, text string) (string, error) {
+	missingKeyOption := "missingkey=error"
+	if name == conditionTemplateName {
+		missingKeyOption = "missingkey=zero"
+	}
+
+	tmpl, err := template.New(name).Option(missingKeyOption).Parse(text)
+	if err != nil {
+		return "", errors.Wrapf(err, "while parsing %s", name)
+	}
+
+	var builder strings.Builder
+	err = tmpl.Execute(&builder, map[string]string(v))
+	if err != nil {
+		return "", errors.Wrapf(err, "while rendering %s", name)
+	}
+
+	return builder.String(), nil
+}
+
+// usesTemplates checks if commands and backup paths are rendered as templates. They are rendered only if the recipe
+// enables templates, so that other recipes can run commands with literal braces, such as `docker inspect -f '{{.Id}}'`.
+func (installer *Installer) usesTemplates() bool {
+	return installer.r.Templates
+}
+
+func (v Variables) renderCommand(command shell.Command) (shell.Command, error) {
+	run := make([]string, 0, len(command.Run))
+	for _, singleCmd := range command.Run {
+		rendered, err := v.render("command", singleCmd)
+		if err != nil {
+			return shell.Command{}, err
+		}
+
+		run = append(run, rendered)
+	}
+
+	if len(run) > 0 {
+		command.Run = run
+	}
+
+	script, err := v.render("script", command.Script)
+	if err != nil {
+		return shell.Command{}, err
+	}
+	command.Script = script
+
+	return command, nil
+}
+
+func (v Variables) evaluateCondition(condition string) (bool, error) {
+	if condition == "" {
+		return true, nil
+	}
+
+	result, err := v.render(conditionTemplateName, condition)
+	if err != nil {
+		return false, err
+	}
+
+	result = strings.ToLower(strings.TrimSpace(result))
+	for _, falsy := range falsyConditionResults {
+		if result == falsy {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (v Variables) register(command shell.Command, output string) {
+	if command.Register == "" {
+		return
+	}
+
+	v[command.Register] = output
+}
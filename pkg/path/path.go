@@ -1,7 +1,11 @@
 package path
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
+
+	"github.com/pkg/errors"
 )
 
 // IsURL checks if given string is an URL
@@ -18,3 +22,30 @@ func IsURL(path string) bool {
 
 	return false
 }
+
+// ExpandHome replaces a leading tilde in given path with the home directory of the current user
+func ExpandHome(path string) (string, error) {
+	return Resolve(path, "", "")
+}
+
+// Resolve replaces a leading tilde in given path with a given home directory, or with the home directory
+// of the current user if it is empty. Other absolute paths are placed under a given root prefix.
+func Resolve(path, home, prefix string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		if prefix != "" && filepath.IsAbs(path) {
+			return filepath.Join(prefix, path), nil
+		}
+
+		return path, nil
+	}
+
+	if home == "" {
+		var err error
+		home, err = os.UserHomeDir()
+		if err != nil {
+			return "", errors.Wrap(err, "while getting home directory")
+		}
+	}
+
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
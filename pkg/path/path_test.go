@@ -3,6 +3,9 @@ package path_test
 import (
 	"github.com/pkosiec/terminer/pkg/path"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -28,3 +31,46 @@ func TestIsURL(t *testing.T) {
 		assert.Equal(t, tC.expectedResult, result)
 	}
 }
+
+func TestExpandHome(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	cases := []struct {
+		path     string
+		expected string
+	}{
+		{path: "~", expected: home},
+		{path: "~/.zshrc", expected: filepath.Join(home, ".zshrc")},
+		{path: "/etc/hosts", expected: "/etc/hosts"},
+		{path: "./~/foo", expected: "./~/foo"},
+		{path: "~user/foo", expected: "~user/foo"},
+	}
+
+	for _, tC := range cases {
+		result, err := path.ExpandHome(tC.path)
+		require.NoError(t, err)
+		assert.Equal(t, tC.expected, result)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	cases := []struct {
+		path     string
+		home     string
+		prefix   string
+		expected string
+	}{
+		{path: "~/.zshrc", home: "/home/service", expected: "/home/service/.zshrc"},
+		{path: "~/.zshrc", home: "/image/home/service", prefix: "/image", expected: "/image/home/service/.zshrc"},
+		{path: "/etc/zshrc", prefix: "/image", expected: "/image/etc/zshrc"},
+		{path: "/etc/zshrc", home: "/home/service", expected: "/etc/zshrc"},
+		{path: "./zshrc", prefix: "/image", expected: "./zshrc"},
+	}
+
+	for _, tC := range cases {
+		result, err := path.Resolve(tC.path, tC.home, tC.prefix)
+		require.NoError(t, err)
+		assert.Equal(t, tC.expected, result)
+	}
+}
@@ -0,0 +1,188 @@
+package profile
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/state"
+	"sigs.k8s.io/yaml"
+)
+
+// Profile is a declarative list of recipes, which should be installed on a machine
+type Profile struct {
+	Metadata recipe.UnitMetadata `yaml:"metadata" json:"metadata"`
+	Recipes  []Entry             `yaml:"recipes" json:"recipes"`
+}
+
+// Entry describes a single recipe of a Profile. Exactly one of Name, Path and URL has to be set.
+// Version pins a recipe from the official repository to a branch, tag or commit of the repository.
+// Checksum pins content of the recipe to a given SHA-256 checksum.
+// Parameters are initial variables of the recipe installation.
+type Entry struct {
+	Name       string            `yaml:"name" json:"name,omitempty"`
+	Path       string            `yaml:"path" json:"path,omitempty"`
+	URL        string            `yaml:"url" json:"url,omitempty"`
+	Version    string            `yaml:"version" json:"version,omitempty"`
+	Checksum   string            `yaml:"checksum" json:"checksum,omitempty"`
+	Parameters map[string]string `yaml:"parameters" json:"parameters,omitempty"`
+}
+
+// FromPath loads a Profile from given file. Relative recipe paths are resolved against the profile directory.
+func FromPath(path string) (*Profile, error) {
+	bytes, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while reading profile from path `%s`", path)
+	}
+
+	var p *Profile
+	err = yaml.Unmarshal(bytes, &p)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while loading profile from file %s", path)
+	}
+
+	if p == nil {
+		return nil, fmt.Errorf("Profile %s is empty", path)
+	}
+
+	for i, entry := range p.Recipes {
+		if entry.Path != "" && !filepath.IsAbs(entry.Path) {
+			p.Recipes[i].Path = filepath.Join(filepath.Dir(path), entry.Path)
+		}
+	}
+
+	return p, nil
+}
+
+// FromState creates a Profile from states of installed recipes, which can be used to reproduce them on another machine.
+// It returns also names of installed recipes, which states don't contain a source, so they can't be added to the profile.
+func FromState(records []state.Record) (*Profile, []string) {
+	p := &Profile{}
+	var skipped []string
+
+	for _, record := range records {
+		if record.Status != state.StatusInstalled {
+			continue
+		}
+
+		if record.Source == nil {
+			skipped = append(skipped, record.Recipe)
+			continue
+		}
+
+		p.Recipes = append(p.Recipes, Entry{
+			Name:       record.Source.Name,
+			Path:       record.Source.Path,
+			URL:        record.Source.URL,
+			Version:    record.Source.Version,
+			Checksum:   record.Checksum,
+			Parameters: record.Parameters,
+		})
+	}
+
+	return p, skipped
+}
+
+// Marshal encodes the profile in YAML
+func (p *Profile) Marshal() ([]byte, error) {
+	bytes, err := yaml.Marshal(p)
+	if err != nil {
+		return nil, errors.Wrap(err, "while encoding profile")
+	}
+
+	return bytes, nil
+}
+
+// Validate checks if the profile contains recipes, and if all of them have a single source
+func (p *Profile) Validate() error {
+	if len(p.Recipes) == 0 {
+		return errors.New("No recipes defined in profile")
+	}
+
+	for i, entry := range p.Recipes {
+		err := entry.validate()
+		if err != nil {
+			return errors.Wrapf(err, "while validating recipe %d", i+1)
+		}
+	}
+
+	return nil
+}
+
+// Source returns the recipe source, which is saved in the recipe state
+func (e Entry) Source() state.Source {
+	return state.Source{Name: e.Name, Path: e.Path, URL: e.URL, Version: e.Version}
+}
+
+// Load loads the recipe from its source and verifies its checksum, if it is defined
+func (e Entry) Load(httpClient recipe.HTTPClient) (*recipe.Recipe, error) {
+	r, err := e.load(httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.Checksum == "" {
+		return r, nil
+	}
+
+	checksum, err := r.Checksum()
+	if err != nil {
+		return nil, err
+	}
+
+	if checksum != e.Checksum {
+		return nil, fmt.Errorf("Checksum of recipe %s doesn't match. Expected: %s. Actual: %s", e, e.Checksum, checksum)
+	}
+
+	return r, nil
+}
+
+func (e Entry) load(httpClient recipe.HTTPClient) (*recipe.Recipe, error) {
+	switch {
+	case e.Name != "" && e.Version != "":
+		return recipe.FromRepositoryVersion(e.Name, e.Version, httpClient)
+	case e.Name != "":
+		return recipe.FromRepository(e.Name, httpClient)
+	case e.Path != "":
+		return recipe.FromPath(e.Path)
+	}
+
+	r, _, err := recipe.FromURL(e.URL, httpClient)
+	return r, err
+}
+
+// String returns the recipe source
+func (e Entry) String() string {
+	switch {
+	case e.Name != "" && e.Version != "":
+		return fmt.Sprintf("%s@%s", e.Name, e.Version)
+	case e.Name != "":
+		return e.Name
+	case e.Path != "":
+		return e.Path
+	}
+
+	return e.URL
+}
+
+func (e Entry) validate() error {
+	var sources []string
+	for _, source := range []string{e.Name, e.Path, e.URL} {
+		if strings.TrimSpace(source) != "" {
+			sources = append(sources, source)
+		}
+	}
+
+	if len(sources) != 1 {
+		return errors.New("Exactly one of name, path and url has to be defined")
+	}
+
+	if e.Version != "" && e.Name == "" {
+		return errors.New("Version can be defined only for recipes from the official repository")
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package profile_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/pkosiec/terminer/pkg/profile"
+	"github.com/pkosiec/terminer/pkg/state"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromPath(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		p, err := profile.FromPath("./testdata/profile.yaml")
+
+		require.NoError(t, err)
+		assert.Equal(t, "Backend developer", p.Metadata.Name)
+		require.Len(t, p.Recipes, 3)
+		assert.Equal(t, profile.Entry{Name: "homebrew", Version: "v1.0.0"}, p.Recipes[0])
+		assert.Equal(t, filepath.Join("testdata", "recipe.yaml"), p.Recipes[1].Path)
+		assert.Equal(t, map[string]string{"goVersion": "1.16"}, p.Recipes[1].Parameters)
+		assert.Equal(t, "https://example.com/recipe.yaml", p.Recipes[2].URL)
+		assert.NoError(t, p.Validate())
+	})
+
+	t.Run("Invalid path", func(t *testing.T) {
+		_, err := profile.FromPath("./testdata/not-existing.yaml")
+
+		assert.Error(t, err)
+	})
+}
+
+func TestProfile_Validate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		entries     []profile.Entry
+		expectedErr string
+	}{
+		{
+			name:        "No recipes",
+			expectedErr: "No recipes defined",
+		},
+		{
+			name:        "No source",
+			entries:     []profile.Entry{{Version: "v1.0.0"}},
+			expectedErr: "Exactly one of name, path and url",
+		},
+		{
+			name:        "Multiple sources",
+			entries:     []profile.Entry{{Name: "homebrew", URL: "https://example.com/recipe.yaml"}},
+			expectedErr: "Exactly one of name, path and url",
+		},
+		{
+			name:        "Version for path",
+			entries:     []profile.Entry{{Path: "./recipe.yaml", Version: "v1.0.0"}},
+			expectedErr: "Version can be defined only",
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			p := profile.Profile{Recipes: tC.entries}
+
+			err := p.Validate()
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tC.expectedErr)
+		})
+	}
+}
+
+func TestEntry_Load(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		r, err := profile.Entry{Path: "./testdata/recipe.yaml"}.Load(nil)
+
+		require.NoError(t, err)
+		assert.NotEmpty(t, r.Stages)
+	})
+
+	t.Run("Matching checksum", func(t *testing.T) {
+		r, err := profile.Entry{Path: "./testdata/recipe.yaml"}.Load(nil)
+		require.NoError(t, err)
+		checksum, err := r.Checksum()
+		require.NoError(t, err)
+
+		_, err = profile.Entry{Path: "./testdata/recipe.yaml", Checksum: checksum}.Load(nil)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Checksum mismatch", func(t *testing.T) {
+		_, err := profile.Entry{Path: "./testdata/recipe.yaml", Checksum: "invalid"}.Load(nil)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Checksum of recipe")
+	})
+}
+
+func TestFromState(t *testing.T) {
+	records := []state.Record{
+		{
+			Recipe:     "Homebrew",
+			Status:     state.StatusInstalled,
+			Source:     &state.Source{Name: "homebrew", Version: "v1.0.0"},
+			Checksum:   "abc",
+			Parameters: map[string]string{"foo": "bar"},
+		},
+		{Recipe: "Failed", Status: state.StatusFailed, Source: &state.Source{Name: "failed"}},
+		{Recipe: "Legacy", Status: state.StatusInstalled},
+		{Recipe: "Go", Status: state.StatusInstalled, Source: &state.Source{Path: "/recipes/go.yaml"}, Checksum: "def"},
+	}
+
+	p, skipped := profile.FromState(records)
+
+	assert.Equal(t, []string{"Legacy"}, skipped)
+	assert.Equal(t, []profile.Entry{
+		{Name: "homebrew", Version: "v1.0.0", Checksum: "abc", Parameters: map[string]string{"foo": "bar"}},
+		{Path: "/recipes/go.yaml", Checksum: "def"},
+	}, p.Recipes)
+
+	bytes, err := p.Marshal()
+	require.NoError(t, err)
+	assert.Contains(t, string(bytes), "- checksum: def\n  path: /recipes/go.yaml\n")
+}
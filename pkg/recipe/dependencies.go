@@ -0,0 +1,110 @@
+package recipe
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkosiec/terminer/pkg/state"
+)
+
+// DependencyCycleError is returned when recipes depend on each other
+type DependencyCycleError struct {
+	Cycle []string
+}
+
+func (e *DependencyCycleError) Error() string {
+	return fmt.Sprintf("Dependency cycle detected: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// SortByDependencies returns recipes ordered in a way that every recipe follows recipes it depends on.
+// Dependencies, which are not on the list, are ignored. Otherwise, the original order of recipes is preserved.
+func SortByDependencies(recipes []*Recipe) ([]*Recipe, error) {
+	byKey := make(map[string]*Recipe, len(recipes))
+	for _, r := range recipes {
+		byKey[state.Key(r.Metadata.Name)] = r
+	}
+
+	s := &dependencySorter{
+		recipes: byKey,
+		visited: make(map[string]bool, len(recipes)),
+	}
+
+	for _, r := range recipes {
+		err := s.visit(r)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.sorted, nil
+}
+
+// MissingDependencies returns names of dependencies, which are not on the recipe list
+func MissingDependencies(recipes []*Recipe) []string {
+	known := make(map[string]bool, len(recipes))
+	for _, r := range recipes {
+		known[state.Key(r.Metadata.Name)] = true
+	}
+
+	var missing []string
+	for _, r := range recipes {
+		for _, dependency := range r.Metadata.DependsOn {
+			key := state.Key(dependency)
+			if known[key] {
+				continue
+			}
+
+			known[key] = true
+			missing = append(missing, dependency)
+		}
+	}
+
+	return missing
+}
+
+type dependencySorter struct {
+	recipes map[string]*Recipe
+	visited map[string]bool
+	path    []*Recipe
+	sorted  []*Recipe
+}
+
+func (s *dependencySorter) visit(r *Recipe) error {
+	key := state.Key(r.Metadata.Name)
+	if s.visited[key] {
+		return nil
+	}
+
+	for i, previous := range s.path {
+		if previous == r {
+			return &DependencyCycleError{Cycle: cycleNames(append(s.path[i:], r))}
+		}
+	}
+
+	s.path = append(s.path, r)
+	for _, dependency := range r.Metadata.DependsOn {
+		next, ok := s.recipes[state.Key(dependency)]
+		if !ok {
+			continue
+		}
+
+		err := s.visit(next)
+		if err != nil {
+			return err
+		}
+	}
+	s.path = s.path[:len(s.path)-1]
+
+	s.visited[key] = true
+	s.sorted = append(s.sorted, r)
+	return nil
+}
+
+func cycleNames(recipes []*Recipe) []string {
+	names := make([]string, 0, len(recipes))
+	for _, r := range recipes {
+		names = append(names, r.Metadata.Name)
+	}
+
+	return names
+}
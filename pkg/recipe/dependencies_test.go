@@ -0,0 +1,88 @@
+package recipe_test
+
+import (
+	"testing"
+
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortByDependencies(t *testing.T) {
+	t.Run("No dependencies", func(t *testing.T) {
+		recipes := []*recipe.Recipe{fixDependentRecipe("a"), fixDependentRecipe("b"), fixDependentRecipe("c")}
+
+		sorted, err := recipe.SortByDependencies(recipes)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"a", "b", "c"}, recipeNames(sorted))
+	})
+
+	t.Run("Dependencies", func(t *testing.T) {
+		recipes := []*recipe.Recipe{
+			fixDependentRecipe("Zsh Plugins", "zsh", "homebrew"),
+			fixDependentRecipe("zsh", "Homebrew"),
+			fixDependentRecipe("fonts"),
+			fixDependentRecipe("homebrew"),
+		}
+
+		sorted, err := recipe.SortByDependencies(recipes)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"homebrew", "zsh", "Zsh Plugins", "fonts"}, recipeNames(sorted))
+	})
+
+	t.Run("Dependency not on the list", func(t *testing.T) {
+		recipes := []*recipe.Recipe{fixDependentRecipe("zsh", "homebrew"), fixDependentRecipe("fonts")}
+
+		sorted, err := recipe.SortByDependencies(recipes)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"zsh", "fonts"}, recipeNames(sorted))
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		recipes := []*recipe.Recipe{
+			fixDependentRecipe("a", "b"),
+			fixDependentRecipe("b", "c"),
+			fixDependentRecipe("c", "a"),
+		}
+
+		_, err := recipe.SortByDependencies(recipes)
+
+		require.Error(t, err)
+		var cycleErr *recipe.DependencyCycleError
+		require.ErrorAs(t, err, &cycleErr)
+		assert.Equal(t, []string{"a", "b", "c", "a"}, cycleErr.Cycle)
+		assert.Contains(t, err.Error(), "a -> b -> c -> a")
+	})
+}
+
+func TestMissingDependencies(t *testing.T) {
+	recipes := []*recipe.Recipe{
+		fixDependentRecipe("zsh", "homebrew", "git"),
+		fixDependentRecipe("Zsh Plugins", "zsh", "Homebrew"),
+	}
+
+	missing := recipe.MissingDependencies(recipes)
+
+	assert.Equal(t, []string{"homebrew", "git"}, missing)
+}
+
+func fixDependentRecipe(name string, dependsOn ...string) *recipe.Recipe {
+	return &recipe.Recipe{
+		Metadata: recipe.RecipeMetadata{
+			UnitMetadata: recipe.UnitMetadata{Name: name},
+			DependsOn:    dependsOn,
+		},
+	}
+}
+
+func recipeNames(recipes []*recipe.Recipe) []string {
+	var names []string
+	for _, r := range recipes {
+		names = append(names, r.Metadata.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,12 @@
+package recipe
+
+// WithoutSource returns a copy of the recipe without its raw content, so that it can be compared with recipes created in code
+func WithoutSource(r *Recipe) *Recipe {
+	if r == nil {
+		return nil
+	}
+
+	c := *r
+	c.source = nil
+	return &c
+}
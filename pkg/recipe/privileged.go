@@ -0,0 +1,74 @@
+package recipe
+
+import (
+	"github.com/pkosiec/terminer/pkg/shell"
+)
+
+// PrivilegedCommand is a command of a recipe step, which runs with elevated privileges.
+// User is set if Terminer runs the command as root or as another user. Programs lists elevation programs,
+// such as sudo, which the command invokes itself. StageIndex and StepIndex are indexes of the step in the recipe.
+type PrivilegedCommand struct {
+	StageIndex int
+	StepIndex  int
+	Stage      string
+	Step       string
+	Command    string
+	User       string
+	Programs   []string
+}
+
+// PrivilegedCommands returns commands of the recipe, which run with elevated privileges during installation,
+// or during rollback if rollback is true. Commands, which define neither root nor user, run as a default user,
+// if it isn't empty. Every entry of run is a separate command, while a script is a single one.
+func (r *Recipe) PrivilegedCommands(rollback bool, defaultUser string) []PrivilegedCommand {
+	var commands []PrivilegedCommand
+	for stageIndex, stage := range r.Stages {
+		for stepIndex, step := range stage.Steps {
+			command := step.Execute
+			if rollback {
+				command = step.Rollback
+			}
+
+			var user string
+			if shell.NeedsElevation(command, defaultUser) {
+				user = commandUser(command, defaultUser)
+			}
+
+			lines := command.Run
+			if command.Script != "" {
+				lines = []string{command.Script}
+			}
+
+			for _, line := range lines {
+				programs := shell.ElevationPrograms(line)
+				if user == "" && len(programs) == 0 {
+					continue
+				}
+
+				commands = append(commands, PrivilegedCommand{
+					StageIndex: stageIndex,
+					StepIndex:  stepIndex,
+					Stage:      stage.Metadata.Name,
+					Step:       step.Metadata.Name,
+					Command:    line,
+					User:       user,
+					Programs:   programs,
+				})
+			}
+		}
+	}
+
+	return commands
+}
+
+func commandUser(command shell.Command, defaultUser string) string {
+	if command.Root {
+		return "root"
+	}
+
+	if command.User != "" {
+		return command.User
+	}
+
+	return defaultUser
+}
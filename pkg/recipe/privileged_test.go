@@ -0,0 +1,82 @@
+package recipe_test
+
+import (
+	"os/user"
+	"testing"
+
+	"github.com/pkosiec/terminer/pkg/recipe"
+	"github.com/pkosiec/terminer/pkg/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecipe_PrivilegedCommands(t *testing.T) {
+	r := &recipe.Recipe{
+		Stages: []recipe.Stage{
+			{
+				Metadata: recipe.UnitMetadata{Name: "Stage 1"},
+				Steps: []recipe.Step{
+					{
+						Metadata: recipe.UnitMetadata{Name: "Install Zsh"},
+						Execute:  shell.Command{Run: []string{"sudo apt-get update", "sudo apt-get install -y zsh"}},
+						Rollback: shell.Command{Run: []string{"sudo apt-get remove -y zsh"}},
+					},
+					{
+						Metadata: recipe.UnitMetadata{Name: "Configure Zsh"},
+						Execute:  shell.Command{Run: []string{"echo 'Foo' > ~/.zshrc"}},
+						Rollback: shell.Command{Run: []string{"rm ~/.zshrc"}},
+					},
+				},
+			},
+			{
+				Metadata: recipe.UnitMetadata{Name: "Stage 2"},
+				Steps: []recipe.Step{
+					{
+						Metadata: recipe.UnitMetadata{Name: "Set shell"},
+						Execute:  shell.Command{Script: "ZSH=$(command -v zsh)\nchsh -s \"$ZSH\"", Root: true},
+					},
+					{
+						Metadata: recipe.UnitMetadata{Name: "Clone"},
+						Execute:  shell.Command{Run: []string{"git clone https://github.com/ohmyzsh/ohmyzsh.git"}, User: "service"},
+					},
+				},
+			},
+		},
+	}
+
+	t.Run("Install", func(t *testing.T) {
+		commands := r.PrivilegedCommands(false, "")
+
+		assert.Equal(t, []recipe.PrivilegedCommand{
+			{StageIndex: 0, StepIndex: 0, Stage: "Stage 1", Step: "Install Zsh", Command: "sudo apt-get update", Programs: []string{"sudo"}},
+			{StageIndex: 0, StepIndex: 0, Stage: "Stage 1", Step: "Install Zsh", Command: "sudo apt-get install -y zsh", Programs: []string{"sudo"}},
+			{StageIndex: 1, StepIndex: 0, Stage: "Stage 2", Step: "Set shell", Command: "ZSH=$(command -v zsh)\nchsh -s \"$ZSH\"", User: "root"},
+			{StageIndex: 1, StepIndex: 1, Stage: "Stage 2", Step: "Clone", Command: "git clone https://github.com/ohmyzsh/ohmyzsh.git", User: "service"},
+		}, commands)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		commands := r.PrivilegedCommands(true, "")
+
+		assert.Equal(t, []recipe.PrivilegedCommand{
+			{StageIndex: 0, StepIndex: 0, Stage: "Stage 1", Step: "Install Zsh", Command: "sudo apt-get remove -y zsh", Programs: []string{"sudo"}},
+		}, commands)
+	})
+
+	t.Run("Default user", func(t *testing.T) {
+		commands := r.PrivilegedCommands(true, "service")
+
+		require.Len(t, commands, 2)
+		assert.Equal(t, "service", commands[0].User)
+		assert.Equal(t, "rm ~/.zshrc", commands[1].Command)
+		assert.Equal(t, "service", commands[1].User)
+	})
+
+	t.Run("Current user", func(t *testing.T) {
+		current, err := user.Current()
+		require.NoError(t, err)
+
+		commands := r.PrivilegedCommands(true, current.Username)
+		assert.Len(t, commands, 1)
+	})
+}
@@ -1,6 +1,8 @@
 package recipe
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -13,6 +15,7 @@ import (
 	"github.com/pkosiec/terminer/internal/metadata"
 	"github.com/pkosiec/terminer/pkg/path"
 	"github.com/pkosiec/terminer/pkg/shell"
+	"github.com/pkosiec/terminer/pkg/state"
 
 	"github.com/pkg/errors"
 	"sigs.k8s.io/yaml"
@@ -28,11 +31,22 @@ type UnitMetadata struct {
 	URL         string `yaml:"url" json:"url"`
 }
 
-// Recipe stores needed steps to install a gjven piece of functionality
+// RecipeMetadata stores metadata for a Recipe. DependsOn lists names of recipes, which have to be installed first
+type RecipeMetadata struct {
+	UnitMetadata `yaml:",inline"`
+	DependsOn    []string `yaml:"dependsOn" json:"dependsOn"`
+}
+
+// Recipe stores needed steps to install a gjven piece of functionality.
+// Templates enables rendering commands and backup paths as templates with variables. Step conditions are always rendered.
 type Recipe struct {
-	OS       string       `yaml:"os" json:"os"`
-	Metadata UnitMetadata `yaml:"metadata" json:"metadata"`
-	Stages   []Stage      `yaml:"stages" json:"stages"`
+	OS        string         `yaml:"os" json:"os"`
+	Metadata  RecipeMetadata `yaml:"metadata" json:"metadata"`
+	Templates bool           `yaml:"templates" json:"templates,omitempty"`
+	Stages    []Stage        `yaml:"stages" json:"stages"`
+
+	// source is the raw content, from which the recipe was loaded
+	source []byte
 }
 
 // Stage represents a logical part of recipe that consists of steps
@@ -41,9 +55,13 @@ type Stage struct {
 	Steps    []Step       `yaml:"steps" json:"steps"`
 }
 
-// Step contains data about a single shell command, which can be installed or reverted
+// Step contains data about a single shell command, which can be installed or reverted.
+// When is an optional condition template. The step is skipped if it renders to an empty string, "false", "no" or "0".
+// Backup lists files, which are copied before the step is executed, and restored when the step is reverted.
 type Step struct {
 	Metadata UnitMetadata  `yaml:"metadata" json:"metadata"`
+	When     string        `yaml:"when" json:"when"`
+	Backup   []string      `yaml:"backup" json:"backup,omitempty"`
 	Execute  shell.Command `yaml:"execute" json:"execute"`
 	Rollback shell.Command `yaml:"rollback" json:"rollback"`
 }
@@ -114,11 +132,17 @@ func FromURL(url string, httpClient HTTPClient) (*Recipe, int, error) {
 
 // FromRepository downloads a recipe from official recipes repository
 func FromRepository(recipeName string, httpClient HTTPClient) (*Recipe, error) {
+	return FromRepositoryVersion(recipeName, metadata.Repository.BranchName, httpClient)
+}
+
+// FromRepositoryVersion downloads a recipe from given version of official recipes repository.
+// The version is a branch, tag or commit of the repository.
+func FromRepositoryVersion(recipeName, version string, httpClient HTTPClient) (*Recipe, error) {
 	url := fmt.Sprintf(
 		"https://raw.githubusercontent.com/%s/%s/%s/%s/%s/%s.yaml",
 		metadata.Repository.Owner,
 		metadata.Repository.Name,
-		metadata.Repository.BranchName,
+		version,
 		metadata.Repository.RecipeDirectory,
 		recipeName,
 		runtime.GOOS,
@@ -144,6 +168,23 @@ func FromRepository(recipeName string, httpClient HTTPClient) (*Recipe, error) {
 	return r, nil
 }
 
+// Checksum returns a SHA-256 checksum of the raw content, from which the recipe was loaded, so it changes only
+// if the recipe file changes. A recipe created in code has no raw content, so its JSON encoding is used instead,
+// which isn't stable between versions of Terminer.
+func (r *Recipe) Checksum() (string, error) {
+	bytes := r.source
+	if bytes == nil {
+		var err error
+		bytes, err = json.Marshal(r)
+		if err != nil {
+			return "", errors.Wrap(err, "while encoding recipe")
+		}
+	}
+
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:]), nil
+}
+
 // Validate checks if the recipe is valid to run on current OS and whether all stages and steps are not empty
 func (r *Recipe) Validate() error {
 	err := r.validateOS()
@@ -156,6 +197,11 @@ func (r *Recipe) Validate() error {
 		return err
 	}
 
+	err = r.validateDependencies()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -173,13 +219,22 @@ func validateExtension(path string) error {
 func unmarshalRecipe(bytes []byte) (*Recipe, error) {
 	var recipe *Recipe
 
+	var err error
 	if json.Valid(bytes) {
-		err := json.Unmarshal(bytes, &recipe)
-		return recipe, err
+		err = json.Unmarshal(bytes, &recipe)
+	} else {
+		err = yaml.Unmarshal(bytes, &recipe)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	err := yaml.Unmarshal(bytes, &recipe)
-	return recipe, err
+	if recipe == nil {
+		return nil, errors.New("Recipe is empty")
+	}
+
+	recipe.source = bytes
+	return recipe, nil
 }
 
 func (r *Recipe) validateOS() error {
@@ -206,15 +261,45 @@ func (r *Recipe) validateStages() error {
 	return nil
 }
 
+func (r *Recipe) validateDependencies() error {
+	for _, dependency := range r.Metadata.DependsOn {
+		if strings.TrimSpace(dependency) == "" {
+			return errors.New("Empty recipe name in dependencies")
+		}
+
+		if state.Key(dependency) == state.Key(r.Metadata.Name) {
+			return fmt.Errorf("Recipe `%s` cannot depend on itself", dependency)
+		}
+	}
+
+	return nil
+}
+
 func (r *Recipe) validateSteps(stage Stage) error {
 	if len(stage.Steps) == 0 {
 		return errors.New("No steps defined")
 	}
 
 	for stepNo, step := range stage.Steps {
-		if len(step.Execute.Run) == 0 {
+		if step.Execute.IsEmpty() {
 			return fmt.Errorf("No commands defined in step %d (%s)", stepNo+1, step.Metadata.Name)
 		}
+
+		err := step.Execute.Validate()
+		if err != nil {
+			return errors.Wrapf(err, "while validating execute command in step %d (%s)", stepNo+1, step.Metadata.Name)
+		}
+
+		err = step.Rollback.Validate()
+		if err != nil {
+			return errors.Wrapf(err, "while validating rollback command in step %d (%s)", stepNo+1, step.Metadata.Name)
+		}
+
+		for _, path := range step.Backup {
+			if strings.TrimSpace(path) == "" {
+				return fmt.Errorf("Empty backup path in step %d (%s)", stepNo+1, step.Metadata.Name)
+			}
+		}
 	}
 
 	return nil
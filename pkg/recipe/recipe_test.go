@@ -2,6 +2,8 @@ package recipe_test
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/pkg/errors"
@@ -13,10 +15,28 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"runtime"
 	"testing"
 )
 
+func TestShippedRecipes(t *testing.T) {
+	paths, err := filepath.Glob("../../recipes/*/*.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			r, err := recipe.FromPath(path)
+			require.NoError(t, err)
+
+			// Recipes for other operating systems are validated as well
+			r.OS = recipe.AnyOS
+			assert.NoError(t, r.Validate())
+		})
+	}
+}
+
 func TestFromPath(t *testing.T) {
 	t.Run("Success YAML", func(t *testing.T) {
 		expected := fixRecipe("testos")
@@ -24,7 +44,7 @@ func TestFromPath(t *testing.T) {
 		r, err := recipe.FromPath("./testdata/valid-recipe.yaml")
 
 		require.NoError(t, err)
-		assert.Equal(t, expected, r)
+		assert.Equal(t, expected, recipe.WithoutSource(r))
 	})
 
 	t.Run("Success JSON", func(t *testing.T) {
@@ -33,7 +53,7 @@ func TestFromPath(t *testing.T) {
 		r, err := recipe.FromPath("./testdata/valid-recipe.json")
 
 		require.NoError(t, err)
-		assert.Equal(t, expected, r)
+		assert.Equal(t, expected, recipe.WithoutSource(r))
 	})
 
 	t.Run("Invalid Path", func(t *testing.T) {
@@ -62,11 +82,11 @@ func TestFromRepository(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		expected := &recipe.Recipe{
 			OS: "test",
-			Metadata: recipe.UnitMetadata{
+			Metadata: recipe.RecipeMetadata{UnitMetadata: recipe.UnitMetadata{
 				Name:        "Foo",
 				URL:         "foo.bar",
 				Description: "Lorem ipsum",
-			},
+			}},
 			Stages: []recipe.Stage{
 				{
 					Metadata: recipe.UnitMetadata{
@@ -90,7 +110,7 @@ func TestFromRepository(t *testing.T) {
 		r, err := recipe.FromRepository("foo", &httpCli)
 
 		require.NoError(t, err)
-		assert.Equal(t, expected, r)
+		assert.Equal(t, expected, recipe.WithoutSource(r))
 	})
 
 	t.Run("Not found", func(t *testing.T) {
@@ -129,7 +149,7 @@ func TestFromURL(t *testing.T) {
 		r, _, err := recipe.FromURL(server.URL, http.DefaultClient)
 
 		require.NoError(t, err)
-		assert.Equal(t, expected, r)
+		assert.Equal(t, expected, recipe.WithoutSource(r))
 	})
 
 	t.Run("Not existing path", func(t *testing.T) {
@@ -222,9 +242,9 @@ func TestValidate(t *testing.T) {
 	t.Run("No stages", func(t *testing.T) {
 		r := &recipe.Recipe{
 			OS: runtime.GOOS,
-			Metadata: recipe.UnitMetadata{
+			Metadata: recipe.RecipeMetadata{UnitMetadata: recipe.UnitMetadata{
 				Name: "Test",
-			},
+			}},
 		}
 
 		err := r.Validate()
@@ -236,9 +256,9 @@ func TestValidate(t *testing.T) {
 	t.Run("No steps in stage", func(t *testing.T) {
 		r := &recipe.Recipe{
 			OS: runtime.GOOS,
-			Metadata: recipe.UnitMetadata{
+			Metadata: recipe.RecipeMetadata{UnitMetadata: recipe.UnitMetadata{
 				Name: "Test",
-			},
+			}},
 			Stages: []recipe.Stage{
 				{
 					Metadata: recipe.UnitMetadata{
@@ -274,9 +294,9 @@ func TestValidate(t *testing.T) {
 	t.Run("No commands in stage", func(t *testing.T) {
 		r := &recipe.Recipe{
 			OS: runtime.GOOS,
-			Metadata: recipe.UnitMetadata{
+			Metadata: recipe.RecipeMetadata{UnitMetadata: recipe.UnitMetadata{
 				Name: "Test",
-			},
+			}},
 			Stages: []recipe.Stage{
 				{
 					Metadata: recipe.UnitMetadata{
@@ -298,15 +318,133 @@ func TestValidate(t *testing.T) {
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "No command")
 	})
+
+	t.Run("Script", func(t *testing.T) {
+		r := fixRecipe(runtime.GOOS)
+		r.Stages[0].Steps[0].Execute = shell.Command{
+			Script: "cd /tmp\necho \"test\"",
+		}
+
+		err := r.Validate()
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Both run and script", func(t *testing.T) {
+		r := fixRecipe(runtime.GOOS)
+		r.Stages[0].Steps[0].Rollback.Script = "echo \"test\""
+
+		err := r.Validate()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Both run and script")
+	})
+
+	t.Run("Interpreter not available", func(t *testing.T) {
+		r := fixRecipe(runtime.GOOS)
+		r.Stages[1].Steps[1].Rollback.Interpreter = "thisinterpreterdoesnotexist"
+
+		err := r.Validate()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "while validating rollback command in step 2 (Step 2)")
+		assert.Contains(t, err.Error(), "is not available")
+	})
+
+	t.Run("Interpreter installed by previous steps", func(t *testing.T) {
+		r := fixRecipe(runtime.GOOS)
+		r.Stages[1].Steps[1].Rollback.Interpreter = "thisinterpreterdoesnotexist"
+		r.Stages[1].Steps[1].Rollback.SkipInterpreterCheck = true
+
+		err := r.Validate()
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Invalid interpreter", func(t *testing.T) {
+		r := fixRecipe(runtime.GOOS)
+		r.Stages[1].Steps[1].Rollback.Interpreter = "-c"
+
+		err := r.Validate()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "while validating rollback command in step 2 (Step 2)")
+		assert.Contains(t, err.Error(), "Invalid interpreter")
+	})
+
+	t.Run("Self dependency", func(t *testing.T) {
+		r := fixRecipe(runtime.GOOS)
+		r.Metadata.DependsOn = []string{"homebrew", r.Metadata.Name}
+
+		err := r.Validate()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot depend on itself")
+	})
+
+	t.Run("Empty dependency", func(t *testing.T) {
+		r := fixRecipe(runtime.GOOS)
+		r.Metadata.DependsOn = []string{" "}
+
+		err := r.Validate()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Empty recipe name")
+	})
+
+	t.Run("Empty backup path", func(t *testing.T) {
+		r := fixRecipe(runtime.GOOS)
+		r.Stages[0].Steps[1].Backup = []string{"~/.zshrc", ""}
+
+		err := r.Validate()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Empty backup path in step 2")
+	})
+}
+
+func TestRecipe_Checksum(t *testing.T) {
+	t.Run("Unchanged file", func(t *testing.T) {
+		path := "./testdata/valid-recipe.yaml"
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		sum := sha256.Sum256(content)
+
+		for i := 0; i < 2; i++ {
+			r, err := recipe.FromPath(path)
+			require.NoError(t, err)
+
+			checksum, err := r.Checksum()
+			require.NoError(t, err)
+			assert.Equal(t, hex.EncodeToString(sum[:]), checksum)
+		}
+	})
+
+	t.Run("Recipe created in code", func(t *testing.T) {
+		r := fixRecipe(runtime.GOOS)
+
+		checksum, err := r.Checksum()
+		require.NoError(t, err)
+		assert.Len(t, checksum, 64)
+
+		sameChecksum, err := fixRecipe(runtime.GOOS).Checksum()
+		require.NoError(t, err)
+		assert.Equal(t, checksum, sameChecksum)
+
+		r.Stages[0].Steps[0].Execute.Run = []string{"echo \"Changed\""}
+		changedChecksum, err := r.Checksum()
+		require.NoError(t, err)
+		assert.NotEqual(t, checksum, changedChecksum)
+	})
 }
 
 func fixRecipe(os string) *recipe.Recipe {
 	return &recipe.Recipe{
 		OS: os,
-		Metadata: recipe.UnitMetadata{
+		Metadata: recipe.RecipeMetadata{UnitMetadata: recipe.UnitMetadata{
 			Name:        "Recipe",
 			Description: "Recipe Description",
-		},
+		}},
 		Stages: []recipe.Stage{
 			{
 				Metadata: recipe.UnitMetadata{
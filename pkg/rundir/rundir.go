@@ -0,0 +1,163 @@
+// Package rundir keeps entries of recipe runs, such as logs or backups, in a state directory
+package rundir
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/pkosiec/terminer/pkg/state"
+)
+
+// IDLayout is a time layout of run IDs. IDs sort chronologically as strings.
+const IDLayout = "20060102T150405Z"
+
+// Entry is a file or a directory of a single recipe run
+type Entry struct {
+	Recipe string
+	ID     string
+	Path   string
+}
+
+// Dir keeps entries of recipe runs. Entries of every recipe are kept in a separate directory, named after the run ID.
+// Kind is a plural name of entries, such as "logs", and Name is a name of a single entry, such as "Run".
+// Both are used in error messages. If Ext is empty, entries are directories. Otherwise, they are files with the extension.
+type Dir struct {
+	Path string
+	Kind string
+	Name string
+	Ext  string
+}
+
+// Create creates an entry of a new run of a given recipe with a given create function. If another run started
+// within the same second, the ID gets a numeric suffix. The create function has to return an error satisfying
+// os.IsExist if the entry already exists.
+func (d Dir) Create(recipe string, startedAt time.Time, create func(path string) error) (Entry, error) {
+	dir := filepath.Join(d.Path, state.Key(recipe))
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return Entry{}, errors.Wrapf(err, "while creating %s directory %s", d.Kind, dir)
+	}
+
+	base := startedAt.UTC().Format(IDLayout)
+	id := base
+	for i := 2; ; i++ {
+		entry := Entry{Recipe: state.Key(recipe), ID: id, Path: filepath.Join(dir, fmt.Sprintf("%s%s", id, d.Ext))}
+
+		err := create(entry.Path)
+		if err == nil {
+			return entry, nil
+		}
+
+		if !os.IsExist(err) {
+			return Entry{}, errors.Wrapf(err, "while creating %s", entry.Path)
+		}
+
+		// Another run started within the same second
+		id = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// List returns entries of a given recipe, or of all recipes if the recipe is empty.
+// Entries are sorted by recipe and then from the oldest to the newest.
+func (d Dir) List(recipe string) ([]Entry, error) {
+	var recipes []string
+	if recipe != "" {
+		recipes = []string{state.Key(recipe)}
+	} else {
+		dirs, err := ioutil.ReadDir(d.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, errors.Wrapf(err, "while reading %s directory %s", d.Kind, d.Path)
+		}
+
+		for _, dir := range dirs {
+			if dir.IsDir() {
+				recipes = append(recipes, dir.Name())
+			}
+		}
+	}
+
+	var entries []Entry
+	for _, key := range recipes {
+		dir := filepath.Join(d.Path, key)
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return nil, errors.Wrapf(err, "while reading %s directory %s", d.Kind, dir)
+		}
+
+		for _, file := range files {
+			if !d.matches(file) {
+				continue
+			}
+
+			entries = append(entries, Entry{
+				Recipe: key,
+				ID:     strings.TrimSuffix(file.Name(), d.Ext),
+				Path:   filepath.Join(dir, file.Name()),
+			})
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Recipe != entries[j].Recipe {
+			return entries[i].Recipe < entries[j].Recipe
+		}
+
+		return idLess(entries[i].ID, entries[j].ID)
+	})
+
+	return entries, nil
+}
+
+// Get returns an entry of a given recipe. If the ID is empty, it returns the latest entry.
+func (d Dir) Get(recipe, id string) (Entry, error) {
+	entries, err := d.List(recipe)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if len(entries) == 0 {
+		return Entry{}, fmt.Errorf("No %s found for recipe `%s`", d.Kind, recipe)
+	}
+
+	if id == "" {
+		return entries[len(entries)-1], nil
+	}
+
+	for _, entry := range entries {
+		if entry.ID == id {
+			return entry, nil
+		}
+	}
+
+	return Entry{}, fmt.Errorf("%s `%s` of recipe `%s` not found", d.Name, id, recipe)
+}
+
+func (d Dir) matches(file os.FileInfo) bool {
+	if d.Ext == "" {
+		return file.IsDir()
+	}
+
+	return !file.IsDir() && filepath.Ext(file.Name()) == d.Ext
+}
+
+// idLess compares run IDs, taking into account the suffix of runs started within the same second
+func idLess(a, b string) bool {
+	if len(a) != len(b) {
+		aBase, bBase := strings.SplitN(a, "-", 2)[0], strings.SplitN(b, "-", 2)[0]
+		if aBase == bBase {
+			return len(a) < len(b)
+		}
+	}
+
+	return a < b
+}
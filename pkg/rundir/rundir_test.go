@@ -0,0 +1,74 @@
+package rundir_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkosiec/terminer/pkg/rundir"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDir(t *testing.T) {
+	startedAt := time.Date(2021, 7, 1, 10, 0, 0, 0, time.UTC)
+	createFile := func(path string) error {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return err
+		}
+
+		return file.Close()
+	}
+
+	t.Run("Runs within the same second", func(t *testing.T) {
+		dir := rundir.Dir{Path: t.TempDir(), Kind: "logs", Name: "Run", Ext: ".log"}
+
+		for i := 0; i < 10; i++ {
+			_, err := dir.Create("Zsh Starter", startedAt, createFile)
+			require.NoError(t, err)
+		}
+
+		entries, err := dir.List("zsh-starter")
+		require.NoError(t, err)
+		require.Len(t, entries, 10)
+		assert.Equal(t, "20210701T100000Z", entries[0].ID)
+		assert.Equal(t, "20210701T100000Z-2", entries[1].ID)
+		assert.Equal(t, "20210701T100000Z-10", entries[9].ID)
+		assert.Equal(t, filepath.Join(dir.Path, "zsh-starter", "20210701T100000Z-10.log"), entries[9].Path)
+	})
+
+	t.Run("Entries of another kind", func(t *testing.T) {
+		dir := rundir.Dir{Path: t.TempDir(), Kind: "backups", Name: "Backup"}
+
+		entry, err := dir.Create("Zsh Starter", startedAt, func(path string) error {
+			return os.Mkdir(path, 0700)
+		})
+		require.NoError(t, err)
+		err = ioutil.WriteFile(filepath.Join(dir.Path, "zsh-starter", "notes.txt"), nil, 0600)
+		require.NoError(t, err)
+
+		entries, err := dir.List("")
+		require.NoError(t, err)
+		assert.Equal(t, []rundir.Entry{entry}, entries)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		dir := rundir.Dir{Path: t.TempDir(), Kind: "backups", Name: "Backup"}
+
+		_, err := dir.Get("zsh-starter", "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "No backups found for recipe `zsh-starter`")
+
+		_, err = dir.Create("Zsh Starter", startedAt, func(path string) error {
+			return os.Mkdir(path, 0700)
+		})
+		require.NoError(t, err)
+
+		_, err = dir.Get("zsh-starter", "foo")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Backup `foo` of recipe `zsh-starter` not found")
+	})
+}
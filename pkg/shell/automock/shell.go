@@ -10,15 +10,22 @@ type Shell struct {
 }
 
 // Exec provides a mock function with given fields: command, stopOnError
-func (_m *Shell) Exec(command shell.Command, stopOnError bool) error {
+func (_m *Shell) Exec(command shell.Command, stopOnError bool) (string, error) {
 	ret := _m.Called(command, stopOnError)
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(shell.Command, bool) error); ok {
+	var r0 string
+	if rf, ok := ret.Get(0).(func(shell.Command, bool) string); ok {
 		r0 = rf(command, stopOnError)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(string)
 	}
 
-	return r0
+	var r1 error
+	if rf, ok := ret.Get(1).(func(shell.Command, bool) error); ok {
+		r1 = rf(command, stopOnError)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
@@ -0,0 +1,31 @@
+package shell
+
+import "fmt"
+
+// NewDryRun creates a new instance that implements Shell interface, which only prints commands without executing them.
+// For commands with Register property set, Exec returns a placeholder with the variable name.
+func NewDryRun(printCmd PrintFn) Shell {
+	return &dryRunShell{printCmd: printCmd}
+}
+
+type dryRunShell struct {
+	printCmd PrintFn
+}
+
+// Exec prints given command
+func (s *dryRunShell) Exec(command Command, _ bool) (string, error) {
+	lines := command.Run
+	if command.Script != "" {
+		lines = scriptLines(command.Script)
+	}
+
+	for _, line := range lines {
+		s.printCmd(fmt.Sprintf("%s%s", command.cmdPrefix(), line))
+	}
+
+	if command.Register == "" {
+		return "", nil
+	}
+
+	return fmt.Sprintf("<%s>", command.Register), nil
+}
@@ -0,0 +1,43 @@
+package shell_test
+
+import (
+	"testing"
+
+	"github.com/pkosiec/terminer/pkg/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunShell_Exec(t *testing.T) {
+	t.Run("Run", func(t *testing.T) {
+		var commands []string
+		s := shell.NewDryRun(func(cmd string) {
+			commands = append(commands, cmd)
+		})
+
+		output, err := s.Exec(shell.Command{
+			Run:  []string{"exit 1", "echo 'Foo'"},
+			Root: true,
+		}, true)
+
+		require.NoError(t, err)
+		assert.Empty(t, output)
+		assert.Equal(t, []string{"$ exit 1", "$ echo 'Foo'"}, commands)
+	})
+
+	t.Run("Script with register", func(t *testing.T) {
+		var commands []string
+		s := shell.NewDryRun(func(cmd string) {
+			commands = append(commands, cmd)
+		})
+
+		output, err := s.Exec(shell.Command{
+			Script:   "FOO=Bar\n\necho \"$FOO\"\n",
+			Register: "foo",
+		}, true)
+
+		require.NoError(t, err)
+		assert.Equal(t, "<foo>", output)
+		assert.Equal(t, []string{"FOO=Bar", "echo \"$FOO\""}, commands)
+	})
+}
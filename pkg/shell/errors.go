@@ -0,0 +1,42 @@
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExitError is returned when a command exits with a code, which is not one of the command success codes.
+// Stderr contains last lines of the command standard error output.
+type ExitError struct {
+	Command  string
+	ExitCode int
+	Stderr   string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit code %d", e.ExitCode)
+}
+
+// OutputMismatchError is returned when a command output doesn't match the expected output pattern
+type OutputMismatchError struct {
+	Command string
+	Pattern string
+}
+
+func (e *OutputMismatchError) Error() string {
+	return fmt.Sprintf("output doesn't match expected pattern `%s`", e.Pattern)
+}
+
+// MultiError is returned when multiple commands failed during execution, which doesn't stop on error
+type MultiError struct {
+	Errors []error
+}
+
+func (e *MultiError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, ",\n")
+}
@@ -0,0 +1,19 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package shell
+
+import "os"
+
+// OpenPTY opens a new pseudo-terminal, which can be used as a terminal of the user in tests
+func OpenPTY() (*os.File, *os.File, error) {
+	return openPTY()
+}
+
+// NewWithTerminal creates a new Shell, which uses a given terminal as the terminal of the user
+func NewWithTerminal(terminal *os.File, printCmd PrintFn, printOut PrintFn, printErr PrintFn, opts ...Option) Shell {
+	s := New(printCmd, printOut, printErr, opts...).(*shell)
+	s.stdin = terminal
+	s.stdout = terminal
+	return s
+}
@@ -1,9 +0,0 @@
-package shell
-
-func ExposeInternalShell() *shell {
-	return &shell{}
-}
-
-func (s *shell) IsCommandAvailable(cmdName string) bool {
-	return s.isCommandAvailable(cmdName)
-}
@@ -0,0 +1,102 @@
+package shell
+
+import (
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Interpreter describes how to run inline commands and script files with a given program
+type Interpreter struct {
+	Program         string
+	InlineFlag      string
+	ScriptExtension string
+	Preamble        string
+}
+
+// posixPreamble is prepended to scripts executed in POSIX-compatible shells, so they exit on first error or unset variable
+const posixPreamble = "set -eu"
+
+var knownInterpreters = map[string]Interpreter{
+	"sh":      {InlineFlag: "-c", ScriptExtension: ".sh", Preamble: posixPreamble},
+	"bash":    {InlineFlag: "-c", ScriptExtension: ".sh", Preamble: posixPreamble},
+	"zsh":     {InlineFlag: "-c", ScriptExtension: ".sh", Preamble: posixPreamble},
+	"dash":    {InlineFlag: "-c", ScriptExtension: ".sh", Preamble: posixPreamble},
+	"ksh":     {InlineFlag: "-c", ScriptExtension: ".sh", Preamble: posixPreamble},
+	"fish":    {InlineFlag: "-c", ScriptExtension: ".fish"},
+	"python":  {InlineFlag: "-c", ScriptExtension: ".py"},
+	"python2": {InlineFlag: "-c", ScriptExtension: ".py"},
+	"python3": {InlineFlag: "-c", ScriptExtension: ".py"},
+	"perl":    {InlineFlag: "-e", ScriptExtension: ".pl"},
+	"ruby":    {InlineFlag: "-e", ScriptExtension: ".rb"},
+	"node":    {InlineFlag: "-e", ScriptExtension: ".js"},
+}
+
+// NewInterpreter returns an Interpreter for given program name or path.
+// Programs which are not known are treated as shells, which accept inline commands with "-c" flag.
+func NewInterpreter(program string) Interpreter {
+	interpreter, ok := knownInterpreters[filepath.Base(program)]
+	if !ok {
+		interpreter = Interpreter{InlineFlag: "-c"}
+	}
+
+	interpreter.Program = program
+	return interpreter
+}
+
+// InlineArgs returns arguments needed to run a single inline command
+func (i Interpreter) InlineArgs(cmd string) []string {
+	return []string{i.Program, i.InlineFlag, cmd}
+}
+
+// ScriptArgs returns arguments needed to run a script file from given path
+func (i Interpreter) ScriptArgs(path string) []string {
+	return []string{i.Program, path}
+}
+
+// ScriptContent returns content of a script file, including interpreter-specific preamble
+func (i Interpreter) ScriptContent(script string) string {
+	if i.Preamble == "" {
+		return script
+	}
+
+	return strings.Join([]string{i.Preamble, script}, "\n")
+}
+
+// Validate checks if the interpreter program is properly defined and available
+func (i Interpreter) Validate() error {
+	if err := i.validateProgram(); err != nil {
+		return err
+	}
+
+	return i.CheckAvailable()
+}
+
+// validateProgram checks if the interpreter program is properly defined, without checking if it is available
+func (i Interpreter) validateProgram() error {
+	if strings.TrimSpace(i.Program) == "" {
+		return errors.New("Interpreter is empty")
+	}
+
+	if strings.HasPrefix(i.Program, "-") || strings.ContainsAny(i.Program, "\n\r") {
+		return errors.Errorf("Invalid interpreter `%s`. It has to be a name or a path of a program", i.Program)
+	}
+
+	if i.InlineFlag == "" {
+		return errors.Errorf("Unknown inline command flag of interpreter `%s`", i.Program)
+	}
+
+	return nil
+}
+
+// CheckAvailable checks if the interpreter program is available
+func (i Interpreter) CheckAvailable() error {
+	_, err := exec.LookPath(i.Program)
+	if err != nil {
+		return errors.Wrapf(err, "Interpreter `%s` is not available", i.Program)
+	}
+
+	return nil
+}
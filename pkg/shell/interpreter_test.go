@@ -0,0 +1,78 @@
+package shell_test
+
+import (
+	"testing"
+
+	"github.com/pkosiec/terminer/pkg/shell"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInterpreter(t *testing.T) {
+	t.Run("Known interpreter", func(t *testing.T) {
+		i := shell.NewInterpreter("/usr/bin/python3")
+
+		assert.Equal(t, []string{"/usr/bin/python3", "-c", "print(1)"}, i.InlineArgs("print(1)"))
+		assert.Equal(t, []string{"/usr/bin/python3", "/tmp/script.py"}, i.ScriptArgs("/tmp/script.py"))
+		assert.Equal(t, ".py", i.ScriptExtension)
+		assert.Equal(t, "print(1)", i.ScriptContent("print(1)"))
+	})
+
+	t.Run("POSIX shell", func(t *testing.T) {
+		i := shell.NewInterpreter("bash")
+
+		assert.Equal(t, "set -eu\necho 'Foo'", i.ScriptContent("echo 'Foo'"))
+	})
+
+	t.Run("Unknown interpreter", func(t *testing.T) {
+		i := shell.NewInterpreter("elvish")
+
+		assert.Equal(t, []string{"elvish", "-c", "echo Foo"}, i.InlineArgs("echo Foo"))
+		assert.Empty(t, i.Preamble)
+	})
+}
+
+func TestInterpreter_Validate(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		err := shell.NewInterpreter(shell.DefaultShell).Validate()
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Not available", func(t *testing.T) {
+		err := shell.NewInterpreter("thisinterpreterdoesnotexist").Validate()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Interpreter `thisinterpreterdoesnotexist` is not available")
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		err := shell.NewInterpreter(" ").Validate()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Interpreter is empty")
+	})
+
+	t.Run("Flag", func(t *testing.T) {
+		err := shell.NewInterpreter("-l").Validate()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Invalid interpreter `-l`")
+	})
+}
+
+func TestInterpreter_CheckAvailable(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		err := shell.NewInterpreter(shell.DefaultShell).CheckAvailable()
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Not available", func(t *testing.T) {
+		err := shell.NewInterpreter("thisinterpreterdoesnotexist").CheckAvailable()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Interpreter `thisinterpreterdoesnotexist` is not available")
+	})
+}
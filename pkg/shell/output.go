@@ -0,0 +1,348 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// OutputLimits limits output of a single command, which is printed, and therefore displayed and saved in logs.
+// If the output exceeds a limit, only its first and last lines are printed, with a note how much output was omitted.
+// Printed lines longer than half of the byte limit are truncated. Zero disables a limit.
+type OutputLimits struct {
+	Lines int
+	Bytes int
+}
+
+// DefaultOutputLimits are limits of output of a single command, which are used if no other limits are set
+var DefaultOutputLimits = OutputLimits{Lines: 2000, Bytes: 1 << 20}
+
+// WithOutputLimits sets limits of output of every command. Commands can override them with their own limits.
+func WithOutputLimits(limits OutputLimits) Option {
+	return func(s *shell) {
+		s.limits = limits
+	}
+}
+
+// outputLimits returns limits of output of the command
+func (s *shell) outputLimits(c Command) OutputLimits {
+	limits := s.limits
+	if c.MaxOutputLines > 0 {
+		limits.Lines = c.MaxOutputLines
+	}
+	if c.MaxOutputBytes > 0 {
+		limits.Bytes = c.MaxOutputBytes
+	}
+
+	return limits
+}
+
+// headLines returns a maximum number of first lines, which are printed as soon as they are read
+func (l OutputLimits) headLines() int {
+	return l.Lines / 2
+}
+
+// headBytes returns a maximum size of first lines, which are printed as soon as they are read
+func (l OutputLimits) headBytes() int {
+	return l.Bytes / 2
+}
+
+// maxLineBytes returns a maximum size of a single line, or zero if lines aren't limited
+func (l OutputLimits) maxLineBytes() int {
+	return l.Bytes - l.headBytes()
+}
+
+// OutputLimiter decides, which lines of output are kept within limits. First lines are kept as long as they fit
+// in the first half of limits. All following lines are kept as last lines, and the oldest of them are omitted,
+// if they exceed the second half of limits. The last line is always kept, even if it alone exceeds limits.
+type OutputLimiter struct {
+	limits       OutputLimits
+	headLines    int
+	headBytes    int
+	tailing      bool
+	tail         []int
+	tailBytes    int
+	omittedLines int
+	omittedBytes int
+}
+
+// NewOutputLimiter creates a new OutputLimiter for given limits
+func NewOutputLimiter(limits OutputLimits) *OutputLimiter {
+	return &OutputLimiter{limits: limits}
+}
+
+// Add adds a line of output. It returns true if the line is one of first lines. Otherwise, the line is added
+// to last lines, and it returns a number of the oldest last lines, which have to be omitted.
+func (l *OutputLimiter) Add(line string) (bool, int) {
+	size := len(line) + 1
+	if !l.tailing && l.fitsHead(size) {
+		l.headLines++
+		l.headBytes += size
+		return true, 0
+	}
+
+	l.tailing = true
+	l.tail = append(l.tail, size)
+	l.tailBytes += size
+
+	omitted := 0
+	for len(l.tail) > 1 && l.exceedsTail() {
+		l.tailBytes -= l.tail[0]
+		l.omittedLines++
+		l.omittedBytes += l.tail[0]
+		l.tail = l.tail[1:]
+		omitted++
+	}
+
+	return false, omitted
+}
+
+// Omitted returns a number of omitted lines and their total size in bytes
+func (l *OutputLimiter) Omitted() (int, int) {
+	return l.omittedLines, l.omittedBytes
+}
+
+func (l *OutputLimiter) fitsHead(size int) bool {
+	if l.limits.Lines > 0 && l.headLines >= l.limits.headLines() {
+		return false
+	}
+
+	return l.limits.Bytes == 0 || l.headBytes+size <= l.limits.headBytes()
+}
+
+func (l *OutputLimiter) exceedsTail() bool {
+	if l.limits.Lines > 0 && len(l.tail) > l.limits.Lines-l.limits.headLines() {
+		return true
+	}
+
+	return l.limits.Bytes > 0 && l.tailBytes > l.limits.Bytes-l.limits.headBytes()
+}
+
+// outputLine is a line of output, which is printed after the command finishes
+type outputLine struct {
+	text   string
+	stderr bool
+}
+
+// commandOutput prints output of a command line by line. Lines of both output streams are printed one at a time,
+// in the order in which they are read, so printers never receive them concurrently.
+// If the output exceeds limits, first lines are printed as soon as they are read, and only last lines are kept
+// until the command finishes.
+// It captures the whole standard output, if needed, and keeps last lines of the standard error output for error reports.
+type commandOutput struct {
+	mu       sync.Mutex
+	printOut PrintFn
+	printErr PrintFn
+	limits   OutputLimits
+	capture  bool
+	output   strings.Builder
+	errTail  []string
+	limiter  *OutputLimiter
+	tail     []outputLine
+}
+
+func newCommandOutput(printOut, printErr PrintFn, limits OutputLimits, capture bool) *commandOutput {
+	return &commandOutput{printOut: printOut, printErr: printErr, limits: limits, capture: capture, limiter: NewOutputLimiter(limits)}
+}
+
+// Stdout returns a writer of the standard output
+func (o *commandOutput) Stdout() *lineWriter {
+	return o.newWriter(o.stdoutLine, o.capture)
+}
+
+// Stderr returns a writer of the standard error output
+func (o *commandOutput) Stderr() *lineWriter {
+	return o.newWriter(o.stderrLine, false)
+}
+
+// Terminal returns a writer of a pseudo-terminal, which merges both output streams.
+// Its lines are printed as the standard output, but they are also kept for error reports.
+func (o *commandOutput) Terminal() *lineWriter {
+	return o.newWriter(o.terminalLine, o.capture)
+}
+
+// Finish prints last lines of the output, which exceeded limits, preceded by a note how much output was omitted
+func (o *commandOutput) Finish() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if lines, bytes := o.limiter.Omitted(); lines > 0 {
+		o.printOut(fmt.Sprintf("... %d lines (%d bytes) of output omitted ...", lines, bytes))
+	}
+
+	for _, line := range o.tail {
+		o.printLine(line)
+	}
+
+	o.tail = nil
+}
+
+// Output returns the captured standard output
+func (o *commandOutput) Output() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.output.String()
+}
+
+// Tail returns last lines of the standard error output
+func (o *commandOutput) Tail() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return strings.Join(o.errTail, "\n")
+}
+
+func (o *commandOutput) newWriter(handle lineFn, keepFull bool) *lineWriter {
+	return &lineWriter{handle: handle, maxLine: o.limits.maxLineBytes(), keepFull: keepFull}
+}
+
+func (o *commandOutput) stdoutLine(raw, line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.captureLine(raw)
+	o.limitLine(outputLine{text: line})
+}
+
+func (o *commandOutput) stderrLine(_, line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.tailLine(line)
+	o.limitLine(outputLine{text: line, stderr: true})
+}
+
+func (o *commandOutput) terminalLine(raw, line string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.captureLine(raw)
+	o.tailLine(line)
+	o.limitLine(outputLine{text: line})
+}
+
+func (o *commandOutput) captureLine(line string) {
+	if !o.capture {
+		return
+	}
+
+	o.output.WriteString(line)
+	o.output.WriteString("\n")
+}
+
+func (o *commandOutput) tailLine(line string) {
+	o.errTail = append(o.errTail, line)
+	if len(o.errTail) > stderrTailLines {
+		o.errTail = o.errTail[1:]
+	}
+}
+
+// limitLine prints the line, if it fits in the first half of limits. Otherwise, it keeps the line
+// among last lines and drops the oldest ones, which exceed the second half of limits.
+func (o *commandOutput) limitLine(line outputLine) {
+	head, omitted := o.limiter.Add(line.text)
+	if head {
+		o.printLine(line)
+		return
+	}
+
+	o.tail = append(o.tail, line)[omitted:]
+}
+
+func (o *commandOutput) printLine(line outputLine) {
+	if line.stderr {
+		o.printErr(line.text)
+		return
+	}
+
+	o.printOut(line.text)
+}
+
+// lineFn receives a complete line of output, both as it was written and truncated for printing
+type lineFn func(raw, line string)
+
+// lineWriter splits written output into lines. A line overwritten with carriage returns, such as a progress bar,
+// is handled once, as it is finally displayed. Lines longer than maxLine bytes are truncated, unless maxLine is zero.
+// The raw line is kept whole only if keepFull is set, so that long lines don't use memory if they aren't captured.
+type lineWriter struct {
+	handle    lineFn
+	maxLine   int
+	keepFull  bool
+	line      []byte
+	truncated int
+	overwrite bool
+}
+
+// Write handles all complete lines and keeps the last, incomplete one
+func (w *lineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexAny(p, "\r\n")
+		if i < 0 {
+			w.append(p)
+			break
+		}
+
+		w.append(p[:i])
+		if p[i] == '\n' {
+			w.handleLine()
+		} else {
+			// The line is overwritten, unless the carriage return ends it
+			w.overwrite = true
+		}
+
+		p = p[i+1:]
+	}
+
+	return n, nil
+}
+
+// Flush handles the last line, if it isn't terminated
+func (w *lineWriter) Flush() {
+	if len(w.line) > 0 || w.truncated > 0 {
+		w.handleLine()
+	}
+	w.overwrite = false
+}
+
+func (w *lineWriter) append(p []byte) {
+	if len(p) == 0 {
+		return
+	}
+
+	if w.overwrite {
+		w.line = w.line[:0]
+		w.truncated = 0
+		w.overwrite = false
+	}
+
+	if !w.keepFull && w.maxLine > 0 && len(w.line)+len(p) > w.maxLine {
+		free := w.maxLine - len(w.line)
+		if free < 0 {
+			free = 0
+		}
+		w.truncated += len(p) - free
+		p = p[:free]
+	}
+
+	w.line = append(w.line, p...)
+}
+
+func (w *lineWriter) handleLine() {
+	raw := string(w.line)
+	text, truncated := raw, w.truncated
+	if w.maxLine > 0 && len(text) > w.maxLine {
+		truncated += len(text) - w.maxLine
+		text = text[:w.maxLine]
+	}
+	if truncated > 0 {
+		text = fmt.Sprintf("%s... (%d bytes truncated)", text, truncated)
+	}
+
+	w.handle(raw, text)
+	w.line = w.line[:0]
+	w.truncated = 0
+	w.overwrite = false
+}
@@ -0,0 +1,57 @@
+package shell_test
+
+import (
+	"testing"
+
+	"github.com/pkosiec/terminer/pkg/shell"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputLimiter(t *testing.T) {
+	t.Run("Lines", func(t *testing.T) {
+		l := shell.NewOutputLimiter(shell.OutputLimits{Lines: 4})
+
+		var head, omitted []bool
+		for _, line := range []string{"1", "2", "3", "4", "5", "6"} {
+			isHead, n := l.Add(line)
+			head = append(head, isHead)
+			omitted = append(omitted, n > 0)
+		}
+
+		assert.Equal(t, []bool{true, true, false, false, false, false}, head)
+		assert.Equal(t, []bool{false, false, false, false, true, true}, omitted)
+		lines, bytes := l.Omitted()
+		assert.Equal(t, 2, lines)
+		assert.Equal(t, 4, bytes)
+	})
+
+	t.Run("Bytes", func(t *testing.T) {
+		l := shell.NewOutputLimiter(shell.OutputLimits{Bytes: 8})
+
+		isHead, _ := l.Add("123")
+		assert.True(t, isHead)
+		isHead, _ = l.Add("456")
+		assert.False(t, isHead)
+		_, n := l.Add("789")
+		assert.Equal(t, 1, n)
+	})
+
+	t.Run("Last line exceeding limits", func(t *testing.T) {
+		l := shell.NewOutputLimiter(shell.OutputLimits{Bytes: 4})
+
+		isHead, n := l.Add("0123456789")
+		assert.False(t, isHead)
+		assert.Equal(t, 0, n)
+		lines, _ := l.Omitted()
+		assert.Equal(t, 0, lines)
+	})
+
+	t.Run("No limits", func(t *testing.T) {
+		l := shell.NewOutputLimiter(shell.OutputLimits{})
+
+		for i := 0; i < 1000; i++ {
+			isHead, _ := l.Add("line")
+			assert.True(t, isHead)
+		}
+	})
+}
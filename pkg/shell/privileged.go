@@ -0,0 +1,25 @@
+package shell
+
+import "regexp"
+
+// elevationProgramRegex matches invocations of elevation programs at the beginning of a shell command,
+// also after command separators, pipes, and in subshells and command substitutions
+var elevationProgramRegex = regexp.MustCompile("(?m)(?:^|[;&|(`{]|\\bthen|\\bdo|\\belse)\\s*(?:exec\\s+|command\\s+)?(sudo|doas|pkexec|su)(?:\\s|$)")
+
+// ElevationPrograms returns elevation programs, such as sudo or su, invoked by a shell command itself,
+// in the order of their first occurrence
+func ElevationPrograms(command string) []string {
+	var programs []string
+	seen := map[string]bool{}
+	for _, match := range elevationProgramRegex.FindAllStringSubmatch(command, -1) {
+		program := match[1]
+		if seen[program] {
+			continue
+		}
+
+		seen[program] = true
+		programs = append(programs, program)
+	}
+
+	return programs
+}
@@ -0,0 +1,31 @@
+package shell_test
+
+import (
+	"testing"
+
+	"github.com/pkosiec/terminer/pkg/shell"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElevationPrograms(t *testing.T) {
+	testCases := []struct {
+		Command  string
+		Expected []string
+	}{
+		{Command: "sudo apt-get install -y zsh", Expected: []string{"sudo"}},
+		{Command: "apt-get update && sudo apt-get install -y zsh", Expected: []string{"sudo"}},
+		{Command: "echo 'foo' | sudo tee /etc/shells; su -c 'chsh'", Expected: []string{"sudo", "su"}},
+		{Command: "ZSH=$(command -v zsh)\nif [ -n \"$ZSH\" ]; then doas chsh -s \"$ZSH\"; fi", Expected: []string{"doas"}},
+		{Command: "echo $(pkexec id -u)", Expected: []string{"pkexec"}},
+		{Command: "sudo true; sudo false", Expected: []string{"sudo"}},
+		{Command: "echo 'Install pseudo files'"},
+		{Command: "brew install sudo-prompt"},
+		{Command: "cat ~/.sudo_as_admin_successful"},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.Command, func(t *testing.T) {
+			assert.Equal(t, tC.Expected, shell.ElevationPrograms(tC.Command))
+		})
+	}
+}
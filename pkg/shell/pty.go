@@ -0,0 +1,123 @@
+package shell
+
+import (
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/pkg/errors"
+)
+
+// errPTYUnsupported is returned if pseudo-terminals aren't supported on the current platform
+var errPTYUnsupported = errors.New("Pseudo-terminals aren't supported on this platform")
+
+// InteractiveFn is notified before and after an interactive command uses the terminal directly
+type InteractiveFn func(active bool)
+
+// WithInteractive sets a function, which is notified before and after an interactive command uses the terminal,
+// so that nothing else is printed meanwhile
+func WithInteractive(notify InteractiveFn) Option {
+	return func(s *shell) {
+		s.notifyInteractive = notify
+	}
+}
+
+// runTTY runs the command in a pseudo-terminal, so that it behaves as if it ran in a terminal.
+// The terminal merges standard output and standard error, so the whole output is printed as standard output.
+// If pseudo-terminals aren't supported, the command runs with pipes.
+func (s *shell) runTTY(cmd *exec.Cmd, out *commandOutput) error {
+	master, err := startPTY(cmd, nil)
+	if err == errPTYUnsupported {
+		return s.runCmd(cmd, out)
+	}
+	if err != nil {
+		return errors.Wrap(err, "while starting command in pseudo-terminal")
+	}
+	defer master.Close()
+
+	term := out.Terminal()
+	copyTerminal(term, master)
+	term.Flush()
+
+	return cmd.Wait()
+}
+
+// runInteractive runs the command in a pseudo-terminal connected to the terminal of the user.
+// Input of the user is forwarded to the command and its output is written directly to the terminal.
+// Lines of the output are printed as well, so that they are saved in logs.
+func (s *shell) runInteractive(cmd *exec.Cmd, out *commandOutput) error {
+	if !isTerminal(s.stdin) || !isTerminal(s.stdout) {
+		return errors.New("Interactive commands require a terminal, but the standard input or output isn't a terminal")
+	}
+
+	if s.notifyInteractive != nil {
+		s.notifyInteractive(true)
+		defer s.notifyInteractive(false)
+	}
+
+	master, err := startPTY(cmd, s.stdout)
+	if err == errPTYUnsupported {
+		return errors.New("Interactive commands aren't supported on this platform")
+	}
+	if err != nil {
+		return errors.Wrap(err, "while starting command in pseudo-terminal")
+	}
+	defer master.Close()
+
+	restore, err := makeRaw(s.stdin)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return errors.Wrap(err, "while switching terminal to raw mode")
+	}
+	defer restore()
+
+	stopWatching := watchSize(s.stdout, master)
+	defer stopWatching()
+
+	stopForwarding := forwardInput(s.stdin, master)
+	defer stopForwarding()
+
+	term := out.Terminal()
+	copyTerminal(io.MultiWriter(s.stdout, term), master)
+	term.Flush()
+
+	return cmd.Wait()
+}
+
+// copyTerminal copies the output of a pseudo-terminal until the command exits.
+// Reading from the master end fails once the command closes the terminal, so the error is ignored.
+func copyTerminal(dst io.Writer, master *os.File) {
+	_, _ = io.Copy(dst, master)
+}
+
+// forwardInput copies input of the user to the pseudo-terminal. It returns a function, which stops forwarding.
+// The input is read from a non-blocking duplicate, so that reading can be interrupted after the command exits.
+func forwardInput(stdin, master *os.File) func() {
+	in := nonBlockingDup(stdin)
+	if in == nil {
+		go func() {
+			_, _ = io.Copy(master, stdin)
+		}()
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = io.Copy(master, in)
+	}()
+
+	return func() {
+		_ = in.SetReadDeadline(time.Now())
+		<-done
+		setBlocking(stdin)
+		in.Close()
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	return f != nil && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd()))
+}
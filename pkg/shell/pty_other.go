@@ -0,0 +1,32 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package shell
+
+import (
+	"os"
+	"os/exec"
+)
+
+// startPTY isn't supported on this platform
+func startPTY(_ *exec.Cmd, _ *os.File) (*os.File, error) {
+	return nil, errPTYUnsupported
+}
+
+// makeRaw isn't supported on this platform
+func makeRaw(_ *os.File) (func(), error) {
+	return nil, errPTYUnsupported
+}
+
+// watchSize does nothing on this platform
+func watchSize(_, _ *os.File) func() {
+	return func() {}
+}
+
+// nonBlockingDup isn't supported on this platform
+func nonBlockingDup(_ *os.File) *os.File {
+	return nil
+}
+
+// setBlocking does nothing on this platform
+func setBlocking(_ *os.File) {}
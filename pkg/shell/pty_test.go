@@ -0,0 +1,117 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package shell_test
+
+import (
+	"bufio"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkosiec/terminer/pkg/shell"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShell_Exec_TTY(t *testing.T) {
+	t.Run("Terminal", func(t *testing.T) {
+		var lines []string
+		s := shell.New(func(string) {}, func(line string) { lines = append(lines, line) }, func(string) {})
+
+		output, err := s.Exec(shell.Command{
+			Run:      []string{`[ -t 0 ] && [ -t 1 ] && echo "terminal"`},
+			TTY:      true,
+			Register: "foo",
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, "terminal", output)
+		assert.Equal(t, []string{"terminal"}, lines)
+	})
+
+	t.Run("Progress and standard error", func(t *testing.T) {
+		var lines []string
+		s := shell.New(func(string) {}, func(line string) { lines = append(lines, line) }, func(string) {})
+
+		_, err := s.Exec(shell.Command{
+			Script: "printf 'Downloading 10%%\\rDownloading 50%%\\rDownloading 100%%\\n'\n>&2 echo 'Error'\nprintf 'Done'",
+			TTY:    true,
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Downloading 100%", "Error", "Done"}, lines)
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		s := shell.New(func(string) {}, func(string) {}, func(string) {})
+
+		_, err := s.Exec(shell.Command{
+			Run: []string{">&2 echo 'Not found'; exit 3"},
+			TTY: true,
+		}, true)
+		require.Error(t, err)
+
+		var exitErr *shell.ExitError
+		require.ErrorAs(t, err, &exitErr)
+		assert.Equal(t, 3, exitErr.ExitCode)
+		assert.Equal(t, "Not found", exitErr.Stderr)
+	})
+}
+
+func TestShell_Exec_Interactive(t *testing.T) {
+	t.Run("Terminal of the user", func(t *testing.T) {
+		master, terminal, err := shell.OpenPTY()
+		require.NoError(t, err)
+		defer master.Close()
+		defer terminal.Close()
+
+		// Everything written to the terminal of the user is displayed on the other end
+		var displayed strings.Builder
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scanner := bufio.NewScanner(master)
+			for scanner.Scan() {
+				displayed.WriteString(scanner.Text())
+				displayed.WriteString("\n")
+				if strings.Contains(scanner.Text(), "Answer: yes") {
+					return
+				}
+			}
+		}()
+
+		var notifications []bool
+		var lines []string
+		s := shell.NewWithTerminal(terminal, func(string) {}, func(line string) { lines = append(lines, line) }, func(string) {},
+			shell.WithInteractive(func(active bool) { notifications = append(notifications, active) }))
+
+		_, err = master.Write([]byte("yes\r"))
+		require.NoError(t, err)
+
+		output, err := s.Exec(shell.Command{
+			Script:      "printf 'Continue? '\nread answer\necho \"Answer: $answer\"",
+			Interactive: true,
+			Register:    "foo",
+		}, true)
+		require.NoError(t, err)
+		wg.Wait()
+
+		assert.Contains(t, output, "Answer: yes")
+		assert.Contains(t, strings.Join(lines, "\n"), "Answer: yes")
+		assert.Contains(t, displayed.String(), "Continue? ")
+		assert.Equal(t, []bool{true, false}, notifications)
+	})
+
+	t.Run("No terminal", func(t *testing.T) {
+		file, err := ioutil.TempFile(t.TempDir(), "stdin")
+		require.NoError(t, err)
+		defer file.Close()
+
+		s := shell.NewWithTerminal(file, func(string) {}, func(string) {}, func(string) {})
+
+		_, err = s.Exec(shell.Command{Run: []string{"read answer"}, Interactive: true}, true)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Interactive commands require a terminal")
+	})
+}
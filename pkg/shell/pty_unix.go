@@ -0,0 +1,128 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package shell
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// startPTY starts the command with a new pseudo-terminal as its controlling terminal and returns the master end.
+// If the size terminal is given, the pseudo-terminal gets its size.
+func startPTY(cmd *exec.Cmd, size *os.File) (*os.File, error) {
+	master, slave, err := openPTY()
+	if err != nil {
+		return nil, err
+	}
+	defer slave.Close()
+
+	if size != nil {
+		copySize(size, master)
+	}
+
+	cmd.Stdin = slave
+	cmd.Stdout = slave
+	cmd.Stderr = slave
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setsid = true
+	cmd.SysProcAttr.Setctty = true
+	cmd.SysProcAttr.Ctty = 0
+
+	err = cmd.Start()
+	if err != nil {
+		master.Close()
+		return nil, err
+	}
+
+	return master, nil
+}
+
+// makeRaw puts a terminal into raw mode, so that every key is passed to the command as it is typed.
+// It returns a function, which restores the previous mode.
+func makeRaw(f *os.File) (func(), error) {
+	fd := int(f.Fd())
+	termios, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := *termios
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+
+	err = unix.IoctlSetTermios(fd, ioctlSetTermios, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		_ = unix.IoctlSetTermios(fd, ioctlSetTermios, termios)
+	}, nil
+}
+
+// watchSize copies the size of a terminal to the pseudo-terminal whenever the terminal is resized.
+// It returns a function, which stops watching.
+func watchSize(from, to *os.File) func() {
+	resized := make(chan os.Signal, 1)
+	signal.Notify(resized, syscall.SIGWINCH)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-resized:
+				copySize(from, to)
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(resized)
+		close(done)
+	}
+}
+
+// nonBlockingDup returns a non-blocking duplicate of a file, which supports read deadlines.
+// The duplicate shares the file status, so the file has to be switched back to blocking mode after use.
+func nonBlockingDup(f *os.File) *os.File {
+	fd, err := unix.Dup(int(f.Fd()))
+	if err != nil {
+		return nil
+	}
+
+	err = unix.SetNonblock(fd, true)
+	if err != nil {
+		unix.Close(fd)
+		return nil
+	}
+
+	return os.NewFile(uintptr(fd), f.Name())
+}
+
+// setBlocking switches a file back to blocking mode
+func setBlocking(f *os.File) {
+	_ = unix.SetNonblock(int(f.Fd()), false)
+}
+
+func copySize(from, to *os.File) {
+	ws, err := unix.IoctlGetWinsize(int(from.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return
+	}
+
+	_ = unix.IoctlSetWinsize(int(to.Fd()), unix.TIOCSWINSZ, ws)
+}
@@ -1,134 +1,494 @@
 package shell
 
 import (
-	"bufio"
 	"fmt"
 	"github.com/pkg/errors"
-	"io"
+	"github.com/pkosiec/terminer/pkg/elevation"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"regexp"
 	"strings"
+	"time"
 )
 
 // PrintFn prints command output
 type PrintFn func(string)
 
-// Command represents command to execute in given shell
+// DurationFn receives wall-clock duration of a finished command
+type DurationFn func(time.Duration)
+
+// Command represents command to execute in given shell or interpreter.
+// Every entry of Run is executed in a separate process, while Script is executed once as a whole in a single session.
+// Shell is kept for compatibility and it is an equivalent of Interpreter.
+// Root runs the command as root, and User runs it as a given user, with the home directory of the user.
+// TTY runs the command in a pseudo-terminal, so that it behaves as in a terminal. Interactive additionally
+// connects the command to the terminal of the user, so that the user can answer its prompts.
+// SuccessCodes lists exit codes treated as success (by default, only 0), and ExpectOutput is a regular expression,
+// which standard output of every command has to match. MaxOutputLines and MaxOutputBytes override limits of printed output.
+// SkipInterpreterCheck skips checking if the interpreter is available during validation, for interpreters installed
+// by previous steps of a recipe.
 type Command struct {
-	Run   []string `yaml:"run" json:"run"`
-	Shell string   `yaml:"shell" json:"shell"`
-	Root  bool     `yaml:"root" json:"root"`
+	Run                  []string `yaml:"run" json:"run"`
+	Script               string   `yaml:"script" json:"script"`
+	Shell                string   `yaml:"shell" json:"shell"`
+	Interpreter          string   `yaml:"interpreter" json:"interpreter"`
+	Root                 bool     `yaml:"root" json:"root"`
+	User                 string   `yaml:"user" json:"user,omitempty"`
+	TTY                  bool     `yaml:"tty" json:"tty,omitempty"`
+	Interactive          bool     `yaml:"interactive" json:"interactive,omitempty"`
+	Register             string   `yaml:"register" json:"register"`
+	SuccessCodes         []int    `yaml:"successCodes" json:"successCodes"`
+	ExpectOutput         string   `yaml:"expectOutput" json:"expectOutput"`
+	MaxOutputLines       int      `yaml:"maxOutputLines" json:"maxOutputLines,omitempty"`
+	MaxOutputBytes       int      `yaml:"maxOutputBytes" json:"maxOutputBytes,omitempty"`
+	SkipInterpreterCheck bool     `yaml:"skipInterpreterCheck" json:"skipInterpreterCheck,omitempty"`
+}
+
+// IsEmpty checks if there is nothing to execute for the command
+func (c Command) IsEmpty() bool {
+	return len(c.Run) == 0 && c.Script == ""
+}
+
+// Validate checks if the command is properly defined and its interpreter is available
+func (c Command) Validate() error {
+	if len(c.Run) > 0 && c.Script != "" {
+		return errors.New("Both run and script defined. Use only one of them")
+	}
+
+	if c.Shell != "" && c.Interpreter != "" {
+		return errors.New("Both shell and interpreter defined. Use only one of them")
+	}
+
+	if c.Root && c.User != "" && c.User != rootUser {
+		return errors.New("Both root and user defined. Use only one of them")
+	}
+
+	if c.MaxOutputLines < 0 || c.MaxOutputBytes < 0 {
+		return errors.New("Output limits can't be negative")
+	}
+
+	if c.Register != "" && !variableNameRegex.MatchString(c.Register) {
+		return fmt.Errorf("Invalid register name `%s`. It has to start with a letter or underscore and contain only letters, digits and underscores", c.Register)
+	}
+
+	if _, err := c.expectedOutputRegex(); err != nil {
+		return err
+	}
+
+	if c.IsEmpty() {
+		return nil
+	}
+
+	if c.SkipInterpreterCheck {
+		return c.interpreter().validateProgram()
+	}
+
+	return c.interpreter().Validate()
+}
+
+func (c Command) expectedOutputRegex() (*regexp.Regexp, error) {
+	if c.ExpectOutput == "" {
+		return nil, nil
+	}
+
+	re, err := regexp.Compile(c.ExpectOutput)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while parsing expected output pattern `%s`", c.ExpectOutput)
+	}
+
+	return re, nil
+}
+
+func (c Command) isSuccessCode(exitCode int) bool {
+	if len(c.SuccessCodes) == 0 {
+		return exitCode == 0
+	}
+
+	for _, code := range c.SuccessCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c Command) interpreter() Interpreter {
+	if c.Interpreter != "" {
+		return NewInterpreter(c.Interpreter)
+	}
+
+	if c.Shell != "" {
+		return NewInterpreter(c.Shell)
+	}
+
+	return NewInterpreter(DefaultShell)
 }
 
-// Shell gives an ability to run shell commands
+var variableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Shell gives an ability to run shell commands.
+// Exec returns trimmed standard output of the command, if the command has Register property set.
 //go:generate mockery -name=Shell -output=automock -outpkg=automock -case=underscore
 type Shell interface {
-	Exec(command Command, stopOnError bool) error
+	Exec(command Command, stopOnError bool) (string, error)
+}
+
+// EnvFn returns additional environment variables of started processes, in the KEY=value form
+type EnvFn func() []string
+
+// Option configures a Shell
+type Option func(s *shell)
+
+// WithTrace sets a function, which prints a full command line of every started process,
+// including the interpreter and elevation wrapper
+func WithTrace(printTrace PrintFn) Option {
+	return func(s *shell) {
+		s.printTrace = printTrace
+	}
+}
+
+// WithTiming sets a function, which receives duration of every executed command, or a whole script
+func WithTiming(printDuration DurationFn) Option {
+	return func(s *shell) {
+		s.printDuration = printDuration
+	}
+}
+
+// WithEnv sets a function, which returns additional environment variables of every started process.
+// Commands executed as root receive them with the env command, as sudo doesn't preserve the environment.
+func WithEnv(env EnvFn) Option {
+	return func(s *shell) {
+		s.env = env
+	}
+}
+
+// WithUser sets a user, who runs commands, which define neither root nor user
+func WithUser(username string) Option {
+	return func(s *shell) {
+		s.user = username
+	}
+}
+
+// WithElevator sets an elevator, which runs commands as root or as another user.
+// By default, the elevation method is detected automatically.
+func WithElevator(elevator *elevation.Elevator) Option {
+	return func(s *shell) {
+		s.elevator = elevator
+	}
 }
 
 // New creates a new instance that implements Shell interface
-func New(printCmd PrintFn, printOut PrintFn, printErr PrintFn) Shell {
-	return &shell{printCmd: printCmd, printOut: printOut, printErr: printErr}
+func New(printCmd PrintFn, printOut PrintFn, printErr PrintFn, opts ...Option) Shell {
+	s := &shell{
+		printCmd: printCmd,
+		printOut: printOut,
+		printErr: printErr,
+		limits:   DefaultOutputLimits,
+		stdin:    os.Stdin,
+		stdout:   os.Stdout,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.elevator == nil {
+		s.elevator = elevation.New(elevation.MethodAuto)
+	}
+
+	return s
 }
 
 // DefaultShell defines in which shell all commands should be executed by default
 const DefaultShell = "/bin/sh"
 
+// stderrTailLines is a number of last standard error lines, which are kept for error reports
+const stderrTailLines = 20
+
 type shell struct {
-	printCmd PrintFn
-	printOut PrintFn
-	printErr PrintFn
+	printCmd      PrintFn
+	printOut      PrintFn
+	printErr      PrintFn
+	printTrace    PrintFn
+	printDuration DurationFn
+	env           EnvFn
+	user          string
+	elevator      *elevation.Elevator
+	limits        OutputLimits
+
+	notifyInteractive InteractiveFn
+	stdin             *os.File
+	stdout            *os.File
 }
 
-// Exec executes given command in specified shell
-func (s *shell) Exec(command Command, stopOnError bool) error {
-	if command.Shell == "" {
-		command.Shell = DefaultShell
+// Exec executes given command in specified shell or interpreter
+func (s *shell) Exec(command Command, stopOnError bool) (string, error) {
+	expectedOutput, err := command.expectedOutputRegex()
+	if err != nil {
+		return "", err
 	}
 
-	var errMessages []string
-
-	for _, singleCmd := range command.Run {
-		var prefix string
-		if command.Root {
-			prefix = "$ "
+	e := &execution{
+		command:        command,
+		interpreter:    command.interpreter(),
+		expectedOutput: expectedOutput,
+	}
+	if !command.IsEmpty() {
+		err = e.interpreter.CheckAvailable()
+		if err != nil {
+			return "", err
 		}
+	}
 
-		s.printCmd(fmt.Sprintf("%s%s", prefix, singleCmd))
+	if command.Register != "" {
+		e.output = &strings.Builder{}
+	}
 
-		var cmd *exec.Cmd
-		if command.Root {
-			cmd = s.rootCommand(command.Shell, singleCmd)
-		} else {
-			cmd = exec.Command(command.Shell, "-c", singleCmd)
-		}
+	if command.Script != "" {
+		err = s.execScript(e)
+	} else {
+		err = s.execRun(e, stopOnError)
+	}
+
+	if e.output == nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(e.output.String()), err
+}
+
+// execution holds details of a single Exec call
+type execution struct {
+	command        Command
+	interpreter    Interpreter
+	expectedOutput *regexp.Regexp
+	output         *strings.Builder
+}
+
+func (s *shell) execRun(e *execution, stopOnError bool) error {
+	var errs []error
+
+	for _, singleCmd := range e.command.Run {
+		s.printCmd(fmt.Sprintf("%s%s", e.command.cmdPrefix(), singleCmd))
 
-		err := s.runCmd(cmd)
+		err := s.runCommand(e, singleCmd, e.interpreter.InlineArgs(singleCmd)...)
 		if err != nil {
 			wrappedErr := errors.Wrapf(err, "while executing %s", singleCmd)
 			if stopOnError {
 				return wrappedErr
 			}
 
-			errMessages = append(errMessages, wrappedErr.Error())
+			errs = append(errs, wrappedErr)
 		}
 	}
 
-	if len(errMessages) > 0 {
-		return errors.New(strings.Join(errMessages, ",\n"))
+	if len(errs) > 0 {
+		return &MultiError{Errors: errs}
 	}
 
 	return nil
 }
 
-func (s *shell) runCmd(cmd *exec.Cmd) error {
-	stdOut, err := cmd.StdoutPipe()
+func (s *shell) execScript(e *execution) error {
+	for _, line := range scriptLines(e.command.Script) {
+		s.printCmd(fmt.Sprintf("%s%s", e.command.cmdPrefix(), line))
+	}
+
+	scriptPath, err := writeScript(e.interpreter, e.command.Script)
 	if err != nil {
-		return err
+		return errors.Wrap(err, "while writing script to temporary file")
+	}
+	defer func() {
+		_ = os.Remove(scriptPath)
+	}()
+
+	if s.commandUser(e.command) != "" {
+		// The script is written by the current user, so it has to be readable by the other one
+		err = os.Chmod(scriptPath, 0644)
+		if err != nil {
+			return errors.Wrap(err, "while changing permissions of script")
+		}
 	}
 
-	stdErr, err := cmd.StderrPipe()
+	err = s.runCommand(e, "script", e.interpreter.ScriptArgs(scriptPath)...)
 	if err != nil {
-		return err
+		return errors.Wrap(err, "while executing script")
 	}
 
-	err = cmd.Start()
+	return nil
+}
+
+// runCommand runs the command as root or another user, if needed, and checks its result
+func (s *shell) runCommand(e *execution, name string, args ...string) error {
+	cmd, err := s.command(e.command, args...)
 	if err != nil {
 		return err
 	}
 
-	s.readAndPrint(stdOut, s.printOut)
-	s.readAndPrint(stdErr, s.printErr)
+	return s.runAndCheck(e, name, cmd)
+}
 
-	err = cmd.Wait()
-	return err
+// runAndCheck runs the command and checks its exit code and output against the command expectations
+func (s *shell) runAndCheck(e *execution, name string, cmd *exec.Cmd) error {
+	captureOutput := e.output != nil || e.expectedOutput != nil
+
+	if s.printTrace != nil {
+		s.printTrace(formatArgs(cmd.Args))
+	}
+
+	start := time.Now()
+	run := s.runCmd
+	switch {
+	case e.command.Interactive:
+		run = s.runInteractive
+	case e.command.TTY:
+		run = s.runTTY
+	}
+
+	out := newCommandOutput(s.printOut, s.printErr, s.outputLimits(e.command), captureOutput)
+	err := run(cmd, out)
+	out.Finish()
+	if s.printDuration != nil {
+		s.printDuration(time.Since(start))
+	}
+
+	exitCode := 0
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return err
+		}
+
+		exitCode = exitErr.ExitCode()
+	}
+
+	if !e.command.isSuccessCode(exitCode) {
+		return &ExitError{Command: name, ExitCode: exitCode, Stderr: out.Tail()}
+	}
+
+	stdOut := out.Output()
+	if e.expectedOutput != nil && !e.expectedOutput.MatchString(stdOut) {
+		return &OutputMismatchError{Command: name, Pattern: e.command.ExpectOutput}
+	}
+
+	if e.output != nil {
+		e.output.WriteString(stdOut)
+	}
+
+	return nil
 }
 
-func (s *shell) readAndPrint(pipe io.ReadCloser, printer PrintFn) {
-	scanner := bufio.NewScanner(pipe)
-	scanner.Split(bufio.ScanLines)
+func (c Command) cmdPrefix() string {
+	if c.Root {
+		return "$ "
+	}
+
+	if c.User != "" {
+		return fmt.Sprintf("%s$ ", c.User)
+	}
 
-	go func() {
-		for scanner.Scan() {
-			text := scanner.Text()
-			printer(text)
+	return ""
+}
+
+// scriptLines returns non-empty lines of a script
+func scriptLines(script string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(script), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
 		}
-	}()
+
+		lines = append(lines, line)
+	}
+
+	return lines
 }
 
-// TODO: Test it
-func (s *shell) rootCommand(shell, cmd string) *exec.Cmd {
-	if !s.isCommandAvailable("sudo") {
-		return exec.Command("su", "-s", shell, "-c", cmd)
+func writeScript(interpreter Interpreter, script string) (string, error) {
+	file, err := ioutil.TempFile("", fmt.Sprintf("terminer-*%s", interpreter.ScriptExtension))
+	if err != nil {
+		return "", err
+	}
+
+	_, err = file.WriteString(interpreter.ScriptContent(script))
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return "", err
+	}
+
+	err = file.Close()
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", err
 	}
 
-	return exec.Command("sudo", shell, "-c", cmd)
+	return file.Name(), nil
 }
 
-func (s *shell) isCommandAvailable(cmdName string) bool {
-	cmd := exec.Command("/bin/sh", "-c", fmt.Sprintf("command -v %s", cmdName))
-	if err := cmd.Run(); err != nil {
-		return false
+func (s *shell) command(c Command, args ...string) (*exec.Cmd, error) {
+	var env []string
+	if s.env != nil {
+		env = s.env()
 	}
 
-	return true
+	if c.Root {
+		return s.elevator.Command(env, args...)
+	}
+
+	if username := s.commandUser(c); username != "" {
+		return s.userCommand(username, env, args...)
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	return cmd, nil
+}
+
+// commandUser returns a user, who runs the command, or an empty string for the current user or root
+func (s *shell) commandUser(c Command) string {
+	if c.Root {
+		return ""
+	}
+
+	if c.User != "" {
+		return c.User
+	}
+
+	return s.user
 }
+
+// runCmd runs the command with output streams connected to pipes
+func (s *shell) runCmd(cmd *exec.Cmd, out *commandOutput) error {
+	stdOut, stdErr := out.Stdout(), out.Stderr()
+	cmd.Stdout = stdOut
+	cmd.Stderr = stdErr
+
+	// Run returns after the whole output is written, so no line is printed after the command finishes
+	err := cmd.Run()
+	stdOut.Flush()
+	stdErr.Flush()
+
+	return err
+}
+
+// formatArgs formats command arguments as a shell command line, quoting only arguments that need it
+func formatArgs(args []string) string {
+	formatted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg != "" && safeArgRegex.MatchString(arg) {
+			formatted = append(formatted, arg)
+			continue
+		}
+
+		formatted = append(formatted, elevation.QuoteArgs([]string{arg}))
+	}
+
+	return strings.Join(formatted, " ")
+}
+
+var safeArgRegex = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
@@ -2,7 +2,13 @@ package shell_test
 
 import (
 	"fmt"
+	"os"
+	"os/user"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/pkg/errors"
 
 	"github.com/pkosiec/terminer/pkg/shell"
 
@@ -24,7 +30,7 @@ func TestShell_Exec(t *testing.T) {
 
 		s := shell.New(cmdPrinter, outPrinter, errPrinter)
 
-		err := s.Exec(shell.Command{
+		_, err := s.Exec(shell.Command{
 			Run: []string{
 				"echo 'Foo'",
 			},
@@ -45,7 +51,7 @@ func TestShell_Exec(t *testing.T) {
 		}
 
 		s := shell.New(cmdPrinter, outPrinter, errPrinter)
-		err := s.Exec(shell.Command{
+		_, err := s.Exec(shell.Command{
 			Run: []string{
 				"echo 'Foo'",
 			},
@@ -54,6 +60,49 @@ func TestShell_Exec(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("Trace", func(t *testing.T) {
+		noopPrinter := func(s string) {}
+		var traces []string
+		tracePrinter := func(s string) {
+			traces = append(traces, s)
+		}
+
+		s := shell.New(noopPrinter, noopPrinter, noopPrinter, shell.WithTrace(tracePrinter))
+		_, err := s.Exec(shell.Command{
+			Run: []string{
+				"echo 'Foo'",
+				"true",
+			},
+			Shell: "bash",
+		}, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{
+			`bash -c 'echo '\''Foo'\'''`,
+			"bash -c true",
+		}, traces)
+	})
+
+	t.Run("Timing", func(t *testing.T) {
+		noopPrinter := func(s string) {}
+		var durations []time.Duration
+		durationPrinter := func(d time.Duration) {
+			durations = append(durations, d)
+		}
+
+		s := shell.New(noopPrinter, noopPrinter, noopPrinter, shell.WithTiming(durationPrinter))
+		_, err := s.Exec(shell.Command{
+			Run: []string{
+				"sleep 0.1",
+				"true",
+			},
+		}, true)
+		require.NoError(t, err)
+
+		require.Len(t, durations, 2)
+		assert.GreaterOrEqual(t, int64(durations[0]), int64(100*time.Millisecond))
+	})
+
 	t.Run("Print errors", func(t *testing.T) {
 		cmdPrinter := func(s string) {
 			assert.Equal(t, ">&2 echo 'error!'", s)
@@ -67,7 +116,7 @@ func TestShell_Exec(t *testing.T) {
 
 		s := shell.New(cmdPrinter, outPrinter, errPrinter)
 
-		err := s.Exec(shell.Command{
+		_, err := s.Exec(shell.Command{
 			Run: []string{
 				">&2 echo 'error!'",
 			},
@@ -112,7 +161,7 @@ func TestShell_Exec(t *testing.T) {
 
 		s := shell.New(cmdPrinter, outPrinter, errPrinter)
 
-		err := s.Exec(shell.Command{
+		_, err := s.Exec(shell.Command{
 			Run: []string{
 				"echo 'Foo'",
 				"echo 'Bar'",
@@ -150,7 +199,7 @@ func TestShell_Exec(t *testing.T) {
 
 		s := shell.New(cmdPrinter, outPrinter, errPrinter)
 
-		err := s.Exec(shell.Command{
+		_, err := s.Exec(shell.Command{
 			Run: []string{
 				"echo 'Foo'",
 				"exit 1",
@@ -191,7 +240,7 @@ func TestShell_Exec(t *testing.T) {
 
 		s := shell.New(cmdPrinter, outPrinter, errPrinter)
 
-		err := s.Exec(shell.Command{
+		_, err := s.Exec(shell.Command{
 			Run: []string{
 				"echo 'Foo'",
 				"exit 1",
@@ -201,28 +250,611 @@ func TestShell_Exec(t *testing.T) {
 		}, false)
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "while executing exit 1")
+
+		var multiErr *shell.MultiError
+		require.True(t, errors.As(err, &multiErr))
+		assert.Len(t, multiErr.Errors, 1)
+	})
+}
+
+func TestShell_Exec_Env(t *testing.T) {
+	env := func() []string {
+		return []string{"HOME=/home/service", "TERMINER_PREFIX=/image"}
+	}
+	s := shell.New(func(string) {}, func(string) {}, func(string) {}, shell.WithEnv(env))
+
+	output, err := s.Exec(shell.Command{
+		Run:      []string{`echo "$HOME $TERMINER_PREFIX"`},
+		Register: "foo",
+	}, true)
+	require.NoError(t, err)
+	assert.Equal(t, "/home/service /image", output)
+
+	output, err = s.Exec(shell.Command{
+		Script:   `echo "$HOME"`,
+		Register: "foo",
+	}, true)
+	require.NoError(t, err)
+	assert.Equal(t, "/home/service", output)
+}
+
+func TestShell_Exec_Output(t *testing.T) {
+	// Both printers append to the same slice, so the race detector reports lines printed concurrently
+	newShell := func(opts ...shell.Option) (shell.Shell, *[]string) {
+		var lines []string
+		outPrinter := func(s string) {
+			lines = append(lines, s)
+		}
+		errPrinter := func(s string) {
+			lines = append(lines, "err: "+s)
+		}
+
+		opts = append([]shell.Option{shell.WithOutputLimits(shell.OutputLimits{})}, opts...)
+		return shell.New(func(string) {}, outPrinter, errPrinter, opts...), &lines
+	}
+
+	t.Run("Complete output", func(t *testing.T) {
+		s, lines := newShell()
+
+		_, err := s.Exec(shell.Command{Run: []string{"seq 1 10000"}}, true)
+		require.NoError(t, err)
+
+		require.Len(t, *lines, 10000)
+		for i, line := range *lines {
+			assert.Equal(t, fmt.Sprint(i+1), line)
+		}
+	})
+
+	t.Run("Order of streams", func(t *testing.T) {
+		s, lines := newShell()
+
+		_, err := s.Exec(shell.Command{
+			Run: []string{"echo foo; sleep 0.1; >&2 echo bar; sleep 0.1; echo baz"},
+		}, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"foo", "err: bar", "baz"}, *lines)
+	})
+
+	t.Run("Concurrent streams", func(t *testing.T) {
+		s, lines := newShell()
+
+		_, err := s.Exec(shell.Command{
+			Run: []string{"for i in $(seq 1 1000); do echo \"out $i\"; >&2 echo \"$i\"; done"},
+		}, true)
+		require.NoError(t, err)
+
+		var outLines, errLines int
+		for _, line := range *lines {
+			if strings.HasPrefix(line, "err: ") {
+				errLines++
+				continue
+			}
+			outLines++
+		}
+		assert.Equal(t, 1000, outLines)
+		assert.Equal(t, 1000, errLines)
+	})
+
+	t.Run("Long lines", func(t *testing.T) {
+		s, lines := newShell()
+
+		output, err := s.Exec(shell.Command{
+			Run:      []string{"head -c 200000 /dev/zero | tr '\\0' 'a'; echo; echo foo"},
+			Register: "foo",
+		}, true)
+		require.NoError(t, err)
+
+		require.Len(t, *lines, 2)
+		assert.Equal(t, strings.Repeat("a", 200000), (*lines)[0])
+		assert.Equal(t, "foo", (*lines)[1])
+		assert.Equal(t, strings.Repeat("a", 200000)+"\nfoo", output)
+	})
+
+	t.Run("Carriage returns", func(t *testing.T) {
+		s, lines := newShell()
+
+		_, err := s.Exec(shell.Command{
+			Run: []string{"printf 'Downloading 10%%\\rDownloading 50%%\\rDownloading 100%%\\nDone\\r\\n'"},
+		}, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"Downloading 100%", "Done"}, *lines)
+	})
+
+	t.Run("Unterminated line", func(t *testing.T) {
+		s, lines := newShell()
+
+		_, err := s.Exec(shell.Command{
+			Run: []string{"printf foo; >&2 printf bar"},
+		}, true)
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []string{"foo", "err: bar"}, *lines)
+	})
+}
+
+func TestShell_Exec_OutputLimits(t *testing.T) {
+	newShell := func(limits shell.OutputLimits) (shell.Shell, *[]string) {
+		var lines []string
+		outPrinter := func(s string) {
+			lines = append(lines, s)
+		}
+		errPrinter := func(s string) {
+			lines = append(lines, "err: "+s)
+		}
+
+		return shell.New(func(string) {}, outPrinter, errPrinter, shell.WithOutputLimits(limits)), &lines
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		s := shell.New(func(string) {}, func(string) {}, func(string) {})
+
+		output, err := s.Exec(shell.Command{Run: []string{"seq 1 10000"}, Register: "foo"}, true)
+		require.NoError(t, err)
+
+		assert.Len(t, strings.Split(output, "\n"), 10000)
+	})
+
+	t.Run("Lines", func(t *testing.T) {
+		s, lines := newShell(shell.OutputLimits{Lines: 4})
+
+		output, err := s.Exec(shell.Command{Run: []string{"seq 1 10"}, Register: "foo"}, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"1", "2", "... 6 lines (12 bytes) of output omitted ...", "9", "10"}, *lines)
+		assert.Equal(t, "1\n2\n3\n4\n5\n6\n7\n8\n9\n10", output)
+	})
+
+	t.Run("Lines within limit", func(t *testing.T) {
+		s, lines := newShell(shell.OutputLimits{Lines: 4})
+
+		_, err := s.Exec(shell.Command{Run: []string{"seq 1 4"}}, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"1", "2", "3", "4"}, *lines)
+	})
+
+	t.Run("Bytes", func(t *testing.T) {
+		s, lines := newShell(shell.OutputLimits{Bytes: 20})
+
+		_, err := s.Exec(shell.Command{Run: []string{"seq 101 110"}}, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"101", "102", "... 6 lines (24 bytes) of output omitted ...", "109", "110"}, *lines)
+	})
+
+	t.Run("Long line", func(t *testing.T) {
+		s, lines := newShell(shell.OutputLimits{Bytes: 20})
+
+		_, err := s.Exec(shell.Command{Run: []string{"echo 0123456789abcdef"}}, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"0123456789... (6 bytes truncated)"}, *lines)
+	})
+
+	t.Run("Registered long line", func(t *testing.T) {
+		s, lines := newShell(shell.OutputLimits{Bytes: 20})
+
+		output, err := s.Exec(shell.Command{Run: []string{"printf 0123456789; sleep 0.1; echo abcdef"}, Register: "foo"}, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"0123456789... (6 bytes truncated)"}, *lines)
+		assert.Equal(t, "0123456789abcdef", output)
+	})
+
+	t.Run("Both streams", func(t *testing.T) {
+		s, lines := newShell(shell.OutputLimits{Lines: 4})
+
+		_, err := s.Exec(shell.Command{
+			Run: []string{"echo 1; sleep 0.1; >&2 echo 2; sleep 0.1; echo 3; sleep 0.1; >&2 echo 4; sleep 0.1; echo 5"},
+		}, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"1", "err: 2", "... 1 lines (2 bytes) of output omitted ...", "err: 4", "5"}, *lines)
+	})
+
+	t.Run("Command limits", func(t *testing.T) {
+		s, lines := newShell(shell.OutputLimits{Lines: 2})
+
+		_, err := s.Exec(shell.Command{Run: []string{"seq 1 6"}, MaxOutputLines: 4}, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"1", "2", "... 2 lines (4 bytes) of output omitted ...", "5", "6"}, *lines)
+	})
+
+	t.Run("Standard error in exit error", func(t *testing.T) {
+		s, lines := newShell(shell.OutputLimits{Lines: 2})
+
+		_, err := s.Exec(shell.Command{Run: []string{">&2 seq 1 5; exit 1"}}, true)
+		require.Error(t, err)
+
+		var exitErr *shell.ExitError
+		require.True(t, errors.As(err, &exitErr))
+		assert.Equal(t, "1\n2\n3\n4\n5", exitErr.Stderr)
+		assert.Equal(t, []string{"err: 1", "... 3 lines (6 bytes) of output omitted ...", "err: 5"}, *lines)
 	})
 }
 
-func TestShell_IsCommandAvailable(t *testing.T) {
-	t.Run("Success", func(t *testing.T) {
-		for _, testCase := range []string{"ls", "echo", "sh", "cd", "mkdir"} {
-			t.Run(fmt.Sprintf("Test case %s", testCase), func(t *testing.T) {
-				s := shell.ExposeInternalShell()
-				result := s.IsCommandAvailable(testCase)
-				assert.True(t, result)
-			})
+func TestShell_Exec_User(t *testing.T) {
+	s := shell.New(func(string) {}, func(string) {}, func(string) {})
+
+	t.Run("Current user", func(t *testing.T) {
+		current, err := user.Current()
+		require.NoError(t, err)
+
+		output, err := s.Exec(shell.Command{
+			Run:      []string{"id -u"},
+			User:     current.Username,
+			Register: "foo",
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, current.Uid, output)
+	})
+
+	t.Run("Another user", func(t *testing.T) {
+		if os.Geteuid() != 0 {
+			t.Skip("Switching users without a password requires root")
 		}
+
+		nobody, err := user.Lookup("nobody")
+		if err != nil {
+			t.Skip("User nobody doesn't exist")
+		}
+
+		output, err := s.Exec(shell.Command{
+			Script:   `echo "$(id -u) $HOME"`,
+			User:     nobody.Username,
+			Register: "foo",
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%s %s", nobody.Uid, nobody.HomeDir), output)
 	})
 
-	t.Run("Error", func(t *testing.T) {
-		s := shell.ExposeInternalShell()
-		result := s.IsCommandAvailable("thiscommanddoesnotexist")
+	t.Run("Nonexistent user", func(t *testing.T) {
+		s := shell.New(func(string) {}, func(string) {}, func(string) {}, shell.WithUser("thisuserdoesnotexist"))
+
+		_, err := s.Exec(shell.Command{Run: []string{"echo 'Foo'"}}, true)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "User `thisuserdoesnotexist` doesn't exist")
 
-		require.False(t, result)
+		_, err = s.Exec(shell.Command{Run: []string{"echo 'Foo'"}, User: "thisuserdoesnotexist"}, true)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "User `thisuserdoesnotexist` doesn't exist")
 	})
 }
 
+func TestShell_Exec_Register(t *testing.T) {
+	t.Run("Run", func(t *testing.T) {
+		outPrinter := printerAssertFn(t, func(i int) string {
+			switch i {
+			case 1:
+				return "Foo"
+			case 2:
+				return "Bar"
+			}
+
+			return ""
+		})
+
+		s := shell.New(func(string) {}, outPrinter, func(string) {})
+
+		output, err := s.Exec(shell.Command{
+			Run: []string{
+				"echo ''",
+				"echo 'Foo'",
+				">&2 echo 'Error'",
+				"echo 'Bar'",
+			},
+			Register: "foo",
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, "Foo\nBar", output)
+	})
+
+	t.Run("Script", func(t *testing.T) {
+		s := shell.New(func(string) {}, func(string) {}, func(string) {})
+
+		output, err := s.Exec(shell.Command{
+			Script:   "FOO=Bar\necho \"$FOO\"",
+			Register: "foo",
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, "Bar", output)
+	})
+
+	t.Run("No register", func(t *testing.T) {
+		s := shell.New(func(string) {}, func(string) {}, func(string) {})
+
+		output, err := s.Exec(shell.Command{
+			Run: []string{"echo 'Foo'"},
+		}, true)
+		require.NoError(t, err)
+		assert.Empty(t, output)
+	})
+}
+
+func TestShell_Exec_Expectations(t *testing.T) {
+	t.Run("Exit code", func(t *testing.T) {
+		s := shell.New(func(string) {}, func(string) {}, func(string) {})
+
+		_, err := s.Exec(shell.Command{
+			Run: []string{"exit 3"},
+		}, true)
+		require.Error(t, err)
+
+		var exitErr *shell.ExitError
+		require.True(t, errors.As(err, &exitErr))
+		assert.Equal(t, 3, exitErr.ExitCode)
+		assert.Equal(t, "exit 3", exitErr.Command)
+	})
+
+	t.Run("Standard error in exit error", func(t *testing.T) {
+		s := shell.New(func(string) {}, func(string) {}, func(string) {})
+
+		_, err := s.Exec(shell.Command{
+			Run: []string{"for i in $(seq 1 30); do >&2 echo \"Line $i\"; done; exit 1"},
+		}, true)
+		require.Error(t, err)
+
+		var exitErr *shell.ExitError
+		require.True(t, errors.As(err, &exitErr))
+		lines := strings.Split(exitErr.Stderr, "\n")
+		require.Len(t, lines, 20)
+		assert.Equal(t, "Line 11", lines[0])
+		assert.Equal(t, "Line 30", lines[19])
+	})
+
+	t.Run("Success codes", func(t *testing.T) {
+		s := shell.New(func(string) {}, func(string) {}, func(string) {})
+
+		_, err := s.Exec(shell.Command{
+			Run:          []string{"exit 1", "exit 0"},
+			SuccessCodes: []int{0, 1},
+		}, true)
+		require.NoError(t, err)
+	})
+
+	t.Run("Zero exit code not listed", func(t *testing.T) {
+		s := shell.New(func(string) {}, func(string) {}, func(string) {})
+
+		_, err := s.Exec(shell.Command{
+			Script:       "exit 0",
+			SuccessCodes: []int{1},
+		}, true)
+		require.Error(t, err)
+
+		var exitErr *shell.ExitError
+		require.True(t, errors.As(err, &exitErr))
+		assert.Equal(t, 0, exitErr.ExitCode)
+		assert.Equal(t, "script", exitErr.Command)
+	})
+
+	t.Run("Expected output", func(t *testing.T) {
+		s := shell.New(func(string) {}, func(string) {}, func(string) {})
+
+		output, err := s.Exec(shell.Command{
+			Run:          []string{"echo 'Already exists'; exit 1"},
+			SuccessCodes: []int{0, 1},
+			ExpectOutput: "(?i)already exists",
+			Register:     "result",
+		}, true)
+		require.NoError(t, err)
+		assert.Equal(t, "Already exists", output)
+	})
+
+	t.Run("Unexpected output", func(t *testing.T) {
+		s := shell.New(func(string) {}, func(string) {}, func(string) {})
+
+		_, err := s.Exec(shell.Command{
+			Run:          []string{"echo 'Foo'"},
+			ExpectOutput: "^Bar$",
+		}, true)
+		require.Error(t, err)
+
+		var mismatchErr *shell.OutputMismatchError
+		require.True(t, errors.As(err, &mismatchErr))
+		assert.Equal(t, "^Bar$", mismatchErr.Pattern)
+		assert.Contains(t, err.Error(), "while executing echo 'Foo'")
+	})
+}
+
+func TestShell_Exec_Script(t *testing.T) {
+	t.Run("Single session", func(t *testing.T) {
+		cmdPrinter := printerAssertFn(t, func(i int) string {
+			switch i {
+			case 0:
+				return "cd /"
+			case 1:
+				return "FOO=bar"
+			case 2:
+				return "echo \"$(pwd) $FOO\""
+			}
+
+			return ""
+		})
+		outPrinter := func(s string) {
+			assert.Equal(t, "/ bar", s)
+		}
+		errPrinter := func(s string) {
+			assert.Fail(t, "Should not be called")
+		}
+
+		s := shell.New(cmdPrinter, outPrinter, errPrinter)
+
+		_, err := s.Exec(shell.Command{
+			Script: "cd /\nFOO=bar\n\necho \"$(pwd) $FOO\"\n",
+		}, true)
+		require.NoError(t, err)
+	})
+
+	t.Run("Exit on first error", func(t *testing.T) {
+		cmdPrinter := func(s string) {}
+		outPrinter := func(s string) {
+			assert.Fail(t, "Should not be called")
+		}
+		errPrinter := func(s string) {}
+
+		s := shell.New(cmdPrinter, outPrinter, errPrinter)
+
+		_, err := s.Exec(shell.Command{
+			Script: "false\necho 'Foo'",
+		}, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "while executing script")
+	})
+
+	t.Run("Unset variable", func(t *testing.T) {
+		cmdPrinter := func(s string) {}
+		outPrinter := func(s string) {
+			assert.Fail(t, "Should not be called")
+		}
+		errPrinter := func(s string) {}
+
+		s := shell.New(cmdPrinter, outPrinter, errPrinter)
+
+		_, err := s.Exec(shell.Command{
+			Script: "echo \"$THIS_VARIABLE_IS_NOT_SET\"",
+			Shell:  "bash",
+		}, true)
+		require.Error(t, err)
+	})
+}
+
+func TestShell_Exec_Interpreter(t *testing.T) {
+	t.Run("Not available", func(t *testing.T) {
+		s := shell.New(func(string) {}, func(string) {}, func(string) {})
+
+		_, err := s.Exec(shell.Command{
+			Run:         []string{"echo 'Foo'"},
+			Interpreter: "thisinterpreterdoesnotexist",
+		}, true)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Interpreter `thisinterpreterdoesnotexist` is not available")
+	})
+
+	t.Run("Inline commands", func(t *testing.T) {
+		cmdPrinter := func(s string) {
+			assert.Equal(t, "print('Foo')", s)
+		}
+		outPrinter := func(s string) {
+			assert.Equal(t, "Foo", s)
+		}
+		errPrinter := func(s string) {
+			assert.Fail(t, "Should not be called")
+		}
+
+		s := shell.New(cmdPrinter, outPrinter, errPrinter)
+
+		_, err := s.Exec(shell.Command{
+			Run:         []string{"print('Foo')"},
+			Interpreter: "python3",
+		}, true)
+		require.NoError(t, err)
+	})
+
+	t.Run("Script", func(t *testing.T) {
+		cmdPrinter := func(s string) {}
+		outPrinter := func(s string) {
+			assert.Equal(t, "Foo Bar", s)
+		}
+		errPrinter := func(s string) {
+			assert.Fail(t, "Should not be called")
+		}
+
+		s := shell.New(cmdPrinter, outPrinter, errPrinter)
+
+		_, err := s.Exec(shell.Command{
+			Script:      "words = ['Foo', 'Bar']\nprint(' '.join(words))",
+			Interpreter: "python3",
+		}, true)
+		require.NoError(t, err)
+	})
+}
+
+func TestCommand_Validate(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Command       shell.Command
+		ExpectedError string
+	}{
+		{
+			Name:    "Default shell",
+			Command: shell.Command{Run: []string{"echo 'Foo'"}},
+		},
+		{
+			Name:    "Empty command with unavailable interpreter",
+			Command: shell.Command{Interpreter: "thisinterpreterdoesnotexist"},
+		},
+		{
+			Name:          "Unavailable interpreter",
+			Command:       shell.Command{Run: []string{"echo 'Foo'"}, Interpreter: "thisinterpreterdoesnotexist"},
+			ExpectedError: "is not available",
+		},
+		{
+			Name:    "Unavailable interpreter with skipped check",
+			Command: shell.Command{Run: []string{"echo 'Foo'"}, Interpreter: "thisinterpreterdoesnotexist", SkipInterpreterCheck: true},
+		},
+		{
+			Name:          "Invalid interpreter with skipped check",
+			Command:       shell.Command{Run: []string{"echo 'Foo'"}, Interpreter: "-c", SkipInterpreterCheck: true},
+			ExpectedError: "Invalid interpreter",
+		},
+		{
+			Name:          "Invalid interpreter",
+			Command:       shell.Command{Run: []string{"echo 'Foo'"}, Interpreter: "-c"},
+			ExpectedError: "Invalid interpreter",
+		},
+		{
+			Name:          "Both run and script",
+			Command:       shell.Command{Run: []string{"echo 'Foo'"}, Script: "echo 'Bar'"},
+			ExpectedError: "Both run and script",
+		},
+		{
+			Name:          "Invalid register name",
+			Command:       shell.Command{Run: []string{"echo 'Foo'"}, Register: "zsh-path"},
+			ExpectedError: "Invalid register name",
+		},
+		{
+			Name:    "Valid register name",
+			Command: shell.Command{Run: []string{"echo 'Foo'"}, Register: "zsh_path"},
+		},
+		{
+			Name:          "Invalid expected output",
+			Command:       shell.Command{Run: []string{"echo 'Foo'"}, ExpectOutput: "(Foo"},
+			ExpectedError: "while parsing expected output pattern",
+		},
+		{
+			Name:          "Both shell and interpreter",
+			Command:       shell.Command{Run: []string{"echo 'Foo'"}, Shell: "sh", Interpreter: "sh"},
+			ExpectedError: "Both shell and interpreter",
+		},
+		{
+			Name:          "Both root and user",
+			Command:       shell.Command{Run: []string{"echo 'Foo'"}, Root: true, User: "service"},
+			ExpectedError: "Both root and user",
+		},
+		{
+			Name:    "Root user",
+			Command: shell.Command{Run: []string{"echo 'Foo'"}, Root: true, User: "root"},
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.Name, func(t *testing.T) {
+			err := tC.Command.Validate()
+
+			if tC.ExpectedError == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tC.ExpectedError)
+		})
+	}
+}
+
 func printerAssertFn(t *testing.T, expectedStringFn func(i int) string) func(s string) {
 	var i int
 
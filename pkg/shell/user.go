@@ -0,0 +1,80 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+
+	"github.com/pkg/errors"
+)
+
+const rootUser = "root"
+
+// userCommand returns a command, which runs given arguments as a given user, with the home directory of the user.
+// Additional environment variables override the default ones.
+func (s *shell) userCommand(username string, env []string, args ...string) (*exec.Cmd, error) {
+	u, err := lookupUser(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if current, err := user.Current(); err == nil && current.Uid == u.Uid {
+		cmd := exec.Command(args[0], args[1:]...)
+		if len(env) > 0 {
+			cmd.Env = append(os.Environ(), env...)
+		}
+
+		return cmd, nil
+	}
+
+	env = append([]string{"HOME=" + u.HomeDir, "USER=" + u.Username, "LOGNAME=" + u.Username}, env...)
+	return s.elevator.UserCommand(u, env, args...)
+}
+
+func lookupUser(username string) (*user.User, error) {
+	u, err := user.Lookup(username)
+	if err == nil {
+		return u, nil
+	}
+
+	var unknownErr user.UnknownUserError
+	if errors.As(err, &unknownErr) {
+		return nil, fmt.Errorf("User `%s` doesn't exist", username)
+	}
+
+	return nil, errors.Wrapf(err, "while looking up user `%s`", username)
+}
+
+// NeedsElevation checks if a command runs as root or as another user than the current one.
+// The default user runs commands, which define neither root nor user.
+func NeedsElevation(c Command, defaultUser string) bool {
+	if c.IsEmpty() {
+		return false
+	}
+
+	if c.Root {
+		return true
+	}
+
+	username := c.User
+	if username == "" {
+		username = defaultUser
+	}
+	if username == "" {
+		return false
+	}
+
+	current, err := user.Current()
+	return err != nil || current.Username != username
+}
+
+// HomeDir returns the home directory of a given user
+func HomeDir(username string) (string, error) {
+	u, err := lookupUser(username)
+	if err != nil {
+		return "", err
+	}
+
+	return u.HomeDir, nil
+}
@@ -0,0 +1,275 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// DirEnv is a name of environment variable which overrides default state directory
+const DirEnv = "TERMINER_STATE_DIR"
+
+const defaultDirName = ".terminer"
+const recipesDirName = "recipes"
+
+// Status describes result of the last operation on a recipe
+type Status string
+
+const (
+	// StatusInstalled means that all recipe steps were executed successfully
+	StatusInstalled Status = "installed"
+
+	// StatusFailed means that recipe installation stopped on a failed step
+	StatusFailed Status = "failed"
+)
+
+// StepStatus describes result of a single step during the last operation on a recipe
+type StepStatus string
+
+const (
+	// StepApplied means that the step was executed successfully
+	StepApplied StepStatus = "applied"
+
+	// StepSkipped means that the step was skipped as its condition wasn't met
+	StepSkipped StepStatus = "skipped"
+
+	// StepFailed means that the step failed
+	StepFailed StepStatus = "failed"
+)
+
+// Record stores details of a recipe installed on the machine.
+// Duration and Steps describe the last operation on the recipe.
+// Definition is the installed recipe encoded in JSON, and Checksum is the SHA-256 checksum of the recipe file.
+type Record struct {
+	Recipe     string            `json:"recipe"`
+	Status     Status            `json:"status"`
+	Source     *Source           `json:"source,omitempty"`
+	Target     *Target           `json:"target,omitempty"`
+	Checksum   string            `json:"checksum,omitempty"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+	Variables  map[string]string `json:"variables,omitempty"`
+	Duration   Duration          `json:"duration,omitempty"`
+	Steps      []StepTiming      `json:"steps,omitempty"`
+	Backups    []Backup          `json:"backups,omitempty"`
+	Changes    []FileChange      `json:"changes,omitempty"`
+	Definition json.RawMessage   `json:"definition,omitempty"`
+	UpdatedAt  time.Time         `json:"updatedAt"`
+}
+
+// Source describes where a recipe was loaded from: the official repository, a file or a URL.
+// Version is a branch, tag or commit of the official repository.
+type Source struct {
+	Name    string `json:"name,omitempty"`
+	Path    string `json:"path,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// Target describes where a recipe is installed. Home is a home directory, Prefix is a root prefix,
+// under which absolute paths are placed, and User is a user, who runs commands.
+// Empty values mean the current user and the root directory.
+type Target struct {
+	Home   string `json:"home,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	User   string `json:"user,omitempty"`
+}
+
+// Backup describes a copy of a file made before a step changed it.
+// Stage and Step are indexes of the step in the recipe. File is a path of the copy,
+// which is empty if the original file didn't exist.
+type Backup struct {
+	Stage int         `json:"stage"`
+	Step  int         `json:"step"`
+	Path  string      `json:"path"`
+	File  string      `json:"file,omitempty"`
+	Mode  os.FileMode `json:"mode,omitempty"`
+}
+
+// ChangeType describes how a step changed a file
+type ChangeType string
+
+const (
+	// ChangeCreated means that the file didn't exist before the step
+	ChangeCreated ChangeType = "created"
+
+	// ChangeModified means that content or metadata of the file changed during the step
+	ChangeModified ChangeType = "modified"
+
+	// ChangeDeleted means that the file was removed during the step
+	ChangeDeleted ChangeType = "deleted"
+)
+
+// FileChange describes a file changed by a step. Stage and Step are indexes of the step in the recipe.
+type FileChange struct {
+	Stage int        `json:"stage"`
+	Step  int        `json:"step"`
+	Path  string     `json:"path"`
+	Type  ChangeType `json:"type"`
+	Dir   bool       `json:"dir,omitempty"`
+}
+
+// StepTiming stores status and wall-clock duration of a step
+type StepTiming struct {
+	Stage    string     `json:"stage"`
+	Step     string     `json:"step"`
+	Status   StepStatus `json:"status"`
+	Duration Duration   `json:"duration"`
+}
+
+// Duration is a time.Duration, which is encoded to JSON as a number of seconds, the same way as in summaries
+// and JSON output
+type Duration time.Duration
+
+// MarshalJSON encodes duration as a number of seconds
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).Seconds())
+}
+
+// UnmarshalJSON decodes duration from a number of seconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	err := json.Unmarshal(data, &seconds)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(seconds * float64(time.Second))
+	return nil
+}
+
+// Store persists installation state of recipes
+type Store interface {
+	Get(recipe string) (*Record, error)
+	Save(record Record) error
+	Delete(recipe string) error
+	List() ([]Record, error)
+}
+
+// Dir returns a directory where Terminer keeps its state
+func Dir() (string, error) {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", errors.Wrap(err, "while getting user home directory")
+	}
+
+	return filepath.Join(home, defaultDirName), nil
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Key returns a normalized recipe name, which is used to identify recipe state
+func Key(recipeName string) string {
+	key := nonAlphanumeric.ReplaceAllString(strings.ToLower(recipeName), "-")
+	return strings.Trim(key, "-")
+}
+
+type fileStore struct {
+	dir string
+}
+
+// NewFileStore creates a new Store, which keeps every recipe state as a separate JSON file in given state directory
+func NewFileStore(stateDir string) Store {
+	return &fileStore{dir: filepath.Join(stateDir, recipesDirName)}
+}
+
+// Get returns state of a given recipe. If the recipe state doesn't exist, it returns nil.
+func (s *fileStore) Get(recipe string) (*Record, error) {
+	bytes, err := ioutil.ReadFile(s.path(recipe))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrapf(err, "while reading state of recipe `%s`", recipe)
+	}
+
+	var record Record
+	err = json.Unmarshal(bytes, &record)
+	if err != nil {
+		return nil, errors.Wrapf(err, "while loading state of recipe `%s`", recipe)
+	}
+
+	return &record, nil
+}
+
+// Save stores state of a given recipe
+func (s *fileStore) Save(record Record) error {
+	if record.UpdatedAt.IsZero() {
+		record.UpdatedAt = time.Now()
+	}
+
+	bytes, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return errors.Wrapf(err, "while encoding state of recipe `%s`", record.Recipe)
+	}
+
+	err = os.MkdirAll(s.dir, 0700)
+	if err != nil {
+		return errors.Wrapf(err, "while creating state directory %s", s.dir)
+	}
+
+	err = ioutil.WriteFile(s.path(record.Recipe), bytes, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "while writing state of recipe `%s`", record.Recipe)
+	}
+
+	return nil
+}
+
+// Delete removes state of a given recipe
+func (s *fileStore) Delete(recipe string) error {
+	err := os.Remove(s.path(recipe))
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "while removing state of recipe `%s`", recipe)
+	}
+
+	return nil
+}
+
+// List returns states of all recipes sorted by recipe name
+func (s *fileStore) List() ([]Record, error) {
+	files, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, errors.Wrapf(err, "while reading state directory %s", s.dir)
+	}
+
+	var records []Record
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
+			continue
+		}
+
+		record, err := s.Get(strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, *record)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Recipe < records[j].Recipe
+	})
+
+	return records, nil
+}
+
+func (s *fileStore) path(recipe string) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s.json", Key(recipe)))
+}
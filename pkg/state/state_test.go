@@ -0,0 +1,131 @@
+package state_test
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkosiec/terminer/pkg/state"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDir(t *testing.T) {
+	t.Run("From environment variable", func(t *testing.T) {
+		dirBak := os.Getenv(state.DirEnv)
+		defer os.Setenv(state.DirEnv, dirBak)
+
+		err := os.Setenv(state.DirEnv, "/tmp/terminer-state")
+		require.NoError(t, err)
+
+		dir, err := state.Dir()
+
+		require.NoError(t, err)
+		assert.Equal(t, "/tmp/terminer-state", dir)
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		dirBak := os.Getenv(state.DirEnv)
+		defer os.Setenv(state.DirEnv, dirBak)
+
+		err := os.Unsetenv(state.DirEnv)
+		require.NoError(t, err)
+
+		dir, err := state.Dir()
+
+		require.NoError(t, err)
+		assert.Contains(t, dir, ".terminer")
+	})
+}
+
+func TestKey(t *testing.T) {
+	testCases := map[string]string{
+		"Zsh Starter":      "zsh-starter",
+		"zsh-starter":      "zsh-starter",
+		" Fish / Starter ": "fish-starter",
+	}
+
+	for name, expected := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, expected, state.Key(name))
+		})
+	}
+}
+
+func TestDuration(t *testing.T) {
+	timing := state.StepTiming{Stage: "Install", Step: "Install zsh", Duration: state.Duration(1500 * time.Millisecond)}
+
+	bytes, err := json.Marshal(timing)
+	require.NoError(t, err)
+	assert.Contains(t, string(bytes), `"duration":1.5`)
+
+	var actual state.StepTiming
+	err = json.Unmarshal(bytes, &actual)
+	require.NoError(t, err)
+	assert.Equal(t, timing, actual)
+}
+
+func TestFileStore(t *testing.T) {
+	t.Run("Save, get and delete", func(t *testing.T) {
+		s := state.NewFileStore(t.TempDir())
+		record := state.Record{
+			Recipe:    "Zsh Starter",
+			Status:    state.StatusInstalled,
+			Variables: map[string]string{"zsh_path": "/bin/zsh"},
+			Duration:  state.Duration(3 * time.Second),
+			Steps: []state.StepTiming{
+				{Stage: "Install", Step: "Install zsh", Status: state.StepApplied, Duration: state.Duration(2 * time.Second)},
+				{Stage: "Install", Step: "Set default shell", Status: state.StepSkipped},
+			},
+			UpdatedAt: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+
+		err := s.Save(record)
+		require.NoError(t, err)
+
+		actual, err := s.Get("zsh-starter")
+		require.NoError(t, err)
+		require.NotNil(t, actual)
+		assert.Equal(t, record, *actual)
+
+		err = s.Delete("Zsh Starter")
+		require.NoError(t, err)
+
+		actual, err = s.Get("Zsh Starter")
+		require.NoError(t, err)
+		assert.Nil(t, actual)
+	})
+
+	t.Run("Not existing", func(t *testing.T) {
+		s := state.NewFileStore(t.TempDir())
+
+		record, err := s.Get("foo")
+		require.NoError(t, err)
+		assert.Nil(t, record)
+
+		err = s.Delete("foo")
+		assert.NoError(t, err)
+
+		records, err := s.List()
+		require.NoError(t, err)
+		assert.Empty(t, records)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		s := state.NewFileStore(t.TempDir())
+
+		for _, name := range []string{"Zsh Starter", "Fish Starter"} {
+			err := s.Save(state.Record{Recipe: name, Status: state.StatusInstalled})
+			require.NoError(t, err)
+		}
+
+		records, err := s.List()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, "Fish Starter", records[0].Recipe)
+		assert.Equal(t, "Zsh Starter", records[1].Recipe)
+		assert.False(t, records[0].UpdatedAt.IsZero())
+	})
+}